@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"net/http"
+	"openbridge/internal/provider"
 	"os"
 	"sync"
 
@@ -20,10 +21,12 @@ type AdminConfig struct {
 }
 
 type ProviderConfig struct {
-	Type             string   `json:"type" yaml:"type"`
-	BaseURL          string   `json:"base_url" yaml:"base_url"`
-	APIKeys          []string `json:"api_keys" yaml:"api_keys"`
-	RotationStrategy string   `json:"rotation_strategy" yaml:"rotation_strategy"`
+	Type             string                 `json:"type" yaml:"type"`
+	BaseURL          string                 `json:"base_url" yaml:"base_url"`
+	APIKeys          []string               `json:"api_keys" yaml:"api_keys"`
+	RotationStrategy string                 `json:"rotation_strategy" yaml:"rotation_strategy"`
+	Options          map[string]interface{} `json:"options,omitempty" yaml:"options,omitempty"`
+	Cassette         map[string]interface{} `json:"cassette,omitempty" yaml:"cassette,omitempty"`
 }
 
 var (
@@ -104,6 +107,8 @@ func getConfig(c *gin.Context) {
 			BaseURL:          p.BaseURL,
 			APIKeys:          maskedKeys,
 			RotationStrategy: p.RotationStrategy,
+			Options:          p.Options,
+			Cassette:         p.Cassette,
 		}
 	}
 
@@ -112,11 +117,12 @@ func getConfig(c *gin.Context) {
 
 func addProvider(c *gin.Context) {
 	var req struct {
-		Name             string   `json:"name"`
-		Type             string   `json:"type"`
-		BaseURL          string   `json:"base_url"`
-		APIKeys          []string `json:"api_keys"`
-		RotationStrategy string   `json:"rotation_strategy"`
+		Name             string                 `json:"name"`
+		Type             string                 `json:"type"`
+		BaseURL          string                 `json:"base_url"`
+		APIKeys          []string               `json:"api_keys"`
+		RotationStrategy string                 `json:"rotation_strategy"`
+		Options          map[string]interface{} `json:"options"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -124,12 +130,21 @@ func addProvider(c *gin.Context) {
 		return
 	}
 
+	if !provider.IsSupportedType(req.Type) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":           "Unknown provider type: " + req.Type,
+			"supported_types": provider.SupportedTypes(),
+		})
+		return
+	}
+
 	adminConfig.mu.Lock()
 	adminConfig.Providers[req.Name] = ProviderConfig{
 		Type:             req.Type,
 		BaseURL:          req.BaseURL,
 		APIKeys:          req.APIKeys,
 		RotationStrategy: req.RotationStrategy,
+		Options:          req.Options,
 	}
 	adminConfig.mu.Unlock()
 
@@ -0,0 +1,119 @@
+// Package blob 临时保存生成的文件（如图片），通过不可猜测的 ID 以 URL 形式提供下载
+package blob
+
+import (
+	"container/list"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Options 临时文件存储配置
+type Options struct {
+	MaxBytes int64         // 所有文件的总大小上限，默认 256MB，超出时删除最早的文件
+	TTL      time.Duration // 保存时间，默认 1h
+}
+
+// Blob 保存的文件
+type Blob struct {
+	Data        []byte
+	ContentType string
+}
+
+// Store 内存中的临时文件存储，进程重启后丢失
+type Store struct {
+	mu      sync.Mutex
+	opts    Options
+	size    int64
+	order   *list.List // 按保存时间排列，最早的在最前
+	entries map[string]*list.Element
+}
+
+type entry struct {
+	id      string
+	blob    Blob
+	expires time.Time
+}
+
+// New 创建临时文件存储
+func New(opts Options) *Store {
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = 256 << 20
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = time.Hour
+	}
+	return &Store{
+		opts:    opts,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// TTL 返回文件保存时间
+func (s *Store) TTL() time.Duration {
+	return s.opts.TTL
+}
+
+// Put 保存文件，返回 ID；contentType 为空时按内容检测
+func (s *Store) Put(data []byte, contentType string) string {
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	id := strings.ReplaceAll(uuid.New().String(), "-", "")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.removeExpired()
+	s.entries[id] = s.order.PushBack(&entry{
+		id:      id,
+		blob:    Blob{Data: data, ContentType: contentType},
+		expires: time.Now().Add(s.opts.TTL),
+	})
+	s.size += int64(len(data))
+
+	// 超出总大小时删除最早的文件（至少保留刚保存的文件）
+	for s.size > s.opts.MaxBytes && s.order.Len() > 1 {
+		s.remove(s.order.Front())
+	}
+	return id
+}
+
+// Get 返回未过期的文件
+func (s *Store) Get(id string) (Blob, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.entries[id]
+	if !ok {
+		return Blob{}, false
+	}
+	e := el.Value.(*entry)
+	if time.Now().After(e.expires) {
+		s.remove(el)
+		return Blob{}, false
+	}
+	return e.blob, true
+}
+
+// removeExpired 删除过期的文件；按保存时间排列，遇到未过期的即可停止
+func (s *Store) removeExpired() {
+	now := time.Now()
+	for el := s.order.Front(); el != nil; el = s.order.Front() {
+		if now.Before(el.Value.(*entry).expires) {
+			return
+		}
+		s.remove(el)
+	}
+}
+
+func (s *Store) remove(el *list.Element) {
+	e := el.Value.(*entry)
+	s.order.Remove(el)
+	delete(s.entries, e.id)
+	s.size -= int64(len(e.blob.Data))
+}
@@ -0,0 +1,299 @@
+// Package cassette 录制并回放上游 HTTP 交互，用于离线回归测试各 Provider 的转换逻辑
+package cassette
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// 模式
+const (
+	ModeRecord = "record"
+	ModeReplay = "replay"
+)
+
+// redacted 替换敏感信息的占位符
+const redacted = "[REDACTED]"
+
+// sensitiveHeaders 录制时需要脱敏的请求/响应头（小写）
+var sensitiveHeaders = map[string]bool{
+	"authorization":  true,
+	"x-api-key":      true,
+	"x-goog-api-key": true,
+	"api-key":        true,
+	"cookie":         true,
+	"set-cookie":     true,
+}
+
+// sensitiveParams 录制时需要脱敏的 query 参数
+var sensitiveParams = []string{"key", "api_key", "access_token"}
+
+// Interaction 一次录制的 HTTP 交互
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type RecordedRequest struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+type RecordedResponse struct {
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body"` // 原始字节，SSE 流原样保存
+}
+
+// Transport 录制或回放 HTTP 交互的 http.RoundTripper
+type Transport struct {
+	mode        string
+	dir         string
+	scrubFields map[string]bool
+	inner       http.RoundTripper
+}
+
+// NewTransport 创建 Transport，inner 为 nil 时使用 http.DefaultTransport
+// scrubFields 为录制时需要脱敏的请求体 JSON 字段名（任意层级），如 user、user_id
+func NewTransport(mode, dir string, scrubFields []string, inner http.RoundTripper) (*Transport, error) {
+	if mode != ModeRecord && mode != ModeReplay {
+		return nil, fmt.Errorf("unknown cassette mode %q (supported: record, replay)", mode)
+	}
+	if dir == "" {
+		return nil, fmt.Errorf("cassette dir is required")
+	}
+	if mode == ModeRecord {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create cassette dir: %w", err)
+		}
+	}
+	if inner == nil {
+		inner = http.DefaultTransport
+	}
+
+	fields := make(map[string]bool, len(scrubFields))
+	for _, f := range scrubFields {
+		fields[f] = true
+	}
+
+	return &Transport{
+		mode:        mode,
+		dir:         dir,
+		scrubFields: fields,
+		inner:       inner,
+	}, nil
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("cassette: failed to read request body: %w", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	path := filepath.Join(t.dir, matchKey(req, body)+".json")
+
+	if t.mode == ModeReplay {
+		return t.replay(req, path)
+	}
+	return t.record(req, body, path)
+}
+
+// replay 从 cassette 文件返回响应
+func (t *Transport) replay(req *http.Request, path string) (*http.Response, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cassette: no recorded interaction for %s %s (%s)", req.Method, req.URL.Path, filepath.Base(path))
+	}
+
+	var interaction Interaction
+	if err := json.Unmarshal(data, &interaction); err != nil {
+		return nil, fmt.Errorf("cassette: invalid cassette %s: %w", path, err)
+	}
+
+	resp := &http.Response{
+		Status:        fmt.Sprintf("%d %s", interaction.Response.Status, http.StatusText(interaction.Response.Status)),
+		StatusCode:    interaction.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        interaction.Response.Headers,
+		Body:          io.NopCloser(strings.NewReader(interaction.Response.Body)),
+		ContentLength: int64(len(interaction.Response.Body)),
+		Request:       req,
+	}
+	if resp.Header == nil {
+		resp.Header = make(http.Header)
+	}
+	return resp, nil
+}
+
+// record 发送真实请求，并在响应体读取完毕后写入 cassette
+func (t *Transport) record(req *http.Request, body []byte, path string) (*http.Response, error) {
+	resp, err := t.inner.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	interaction := &Interaction{
+		Request: RecordedRequest{
+			Method:  req.Method,
+			URL:     scrubURL(req.URL),
+			Headers: scrubHeaders(req.Header),
+			Body:    string(scrubBody(body, t.scrubFields)),
+		},
+		Response: RecordedResponse{
+			Status:  resp.StatusCode,
+			Headers: scrubHeaders(resp.Header),
+		},
+	}
+
+	// 包装响应体：流式响应边读边记录，读完或关闭时落盘
+	resp.Body = &recordingBody{
+		ReadCloser: resp.Body,
+		onDone: func(data []byte) {
+			interaction.Response.Body = string(data)
+			if err := save(path, interaction); err != nil {
+				log.Printf("⚠️ Failed to save cassette %s: %v", path, err)
+			}
+		},
+	}
+	return resp, nil
+}
+
+// recordingBody 在读取响应体的同时缓存原始字节
+type recordingBody struct {
+	io.ReadCloser
+	buf    bytes.Buffer
+	once   sync.Once
+	onDone func([]byte)
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	if err == io.EOF {
+		b.once.Do(func() { b.onDone(b.buf.Bytes()) })
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	b.once.Do(func() { b.onDone(b.buf.Bytes()) })
+	return b.ReadCloser.Close()
+}
+
+func save(path string, interaction *Interaction) error {
+	data, err := json.MarshalIndent(interaction, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// matchKey 根据方法、路径和请求体生成匹配键
+// 不包含 query 参数（Gemini 的 API Key 在 query 中），JSON 请求体会先规范化
+func matchKey(req *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(req.Method + " " + req.URL.Path + "\n"))
+	h.Write(canonicalBody(body))
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// canonicalBody 将 JSON 重新序列化以忽略字段顺序和空白差异
+func canonicalBody(body []byte) []byte {
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return body
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return data
+}
+
+func scrubHeaders(header http.Header) http.Header {
+	scrubbed := make(http.Header, len(header))
+	for k, v := range header {
+		if sensitiveHeaders[strings.ToLower(k)] {
+			scrubbed[k] = []string{redacted}
+			continue
+		}
+		scrubbed[k] = append([]string(nil), v...)
+	}
+	return scrubbed
+}
+
+// scrubBody 替换请求体中指定字段的值；匹配键基于原始请求体计算，不受脱敏影响
+// 没有需要脱敏的字段时请求体原样保存，否则保存重新序列化后的 JSON
+func scrubBody(body []byte, fields map[string]bool) []byte {
+	if len(fields) == 0 {
+		return body
+	}
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return body
+	}
+	if !scrubValue(v, fields) {
+		return body
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return data
+}
+
+// scrubValue 递归替换指定字段的值，返回是否有替换
+func scrubValue(v interface{}, fields map[string]bool) bool {
+	scrubbed := false
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			if fields[k] {
+				v[k] = redacted
+				scrubbed = true
+				continue
+			}
+			scrubbed = scrubValue(child, fields) || scrubbed
+		}
+	case []interface{}:
+		for _, child := range v {
+			scrubbed = scrubValue(child, fields) || scrubbed
+		}
+	}
+	return scrubbed
+}
+
+func scrubURL(u *url.URL) string {
+	copied := *u
+	query := copied.Query()
+	for _, param := range sensitiveParams {
+		if query.Has(param) {
+			query.Set(param, redacted)
+		}
+	}
+	copied.RawQuery = query.Encode()
+	copied.User = nil
+	return copied.String()
+}
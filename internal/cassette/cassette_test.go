@@ -0,0 +1,139 @@
+package cassette
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// sseBody 模拟上游的 SSE 流，包含 \r\n 和空行，回放时必须逐字节一致
+const sseBody = "event: message_start\r\ndata: {\"type\":\"message_start\"}\r\n\r\n" +
+	"data: {\"delta\":\"hel\"}\n\n" +
+	"data: {\"delta\":\"lo\"}\n\n" +
+	"data: [DONE]\n\n"
+
+// failingTransport 回放时不应访问上游
+type failingTransport struct{}
+
+func (failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("unexpected upstream request during replay")
+}
+
+func doRequest(t *testing.T, rt http.RoundTripper, url string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest("POST", url+"/v1/stream?key=secret-key&alt=sse",
+		strings.NewReader(`{"model":"m","metadata":{"user_id":"alice"},"messages":[{"role":"user","content":"hi"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer secret-token")
+	req.Header.Set("x-api-key", "secret-key")
+
+	resp, err := (&http.Client{Transport: rt}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestRecordReplayRoundTrip(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Set-Cookie", "session=secret-cookie")
+		w.Header().Set("X-Request-Id", "req_123")
+		w.WriteHeader(http.StatusOK)
+		// 分多次写出，确认录制拼接的是完整的原始字节
+		for _, part := range strings.SplitAfter(sseBody, "\n\n") {
+			io.WriteString(w, part)
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer upstream.Close()
+
+	dir := t.TempDir()
+
+	// 录制
+	recorder, err := NewTransport(ModeRecord, dir, []string{"user_id"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := doRequest(t, recorder, upstream.URL)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != sseBody {
+		t.Fatalf("recorded passthrough body = %q, want %q", body, sseBody)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("cassette files = %v (%v), want exactly one", files, err)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"secret-key", "secret-token", "secret-cookie", "alice"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("cassette contains %q:\n%s", secret, data)
+		}
+	}
+
+	var interaction Interaction
+	if err := json.Unmarshal(data, &interaction); err != nil {
+		t.Fatal(err)
+	}
+	if got := interaction.Request.Headers.Get("Authorization"); got != redacted {
+		t.Errorf("Authorization = %q, want %q", got, redacted)
+	}
+	if got := interaction.Response.Headers.Get("Set-Cookie"); got != redacted {
+		t.Errorf("Set-Cookie = %q, want %q", got, redacted)
+	}
+	if !strings.Contains(interaction.Request.URL, "alt=sse") || !strings.Contains(interaction.Request.URL, "key=%5BREDACTED%5D") {
+		t.Errorf("URL = %q, want key redacted and other params kept", interaction.Request.URL)
+	}
+	if !strings.Contains(interaction.Request.Body, `"user_id":"[REDACTED]"`) || !strings.Contains(interaction.Request.Body, `"content":"hi"`) {
+		t.Errorf("request body = %s, want user_id redacted and other fields kept", interaction.Request.Body)
+	}
+
+	// 回放：不访问上游，返回逐字节相同的响应
+	player, err := NewTransport(ModeReplay, dir, nil, failingTransport{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp = doRequest(t, player, "http://upstream.invalid")
+	replayed, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("replayed status = %d, want 200", resp.StatusCode)
+	}
+	if string(replayed) != sseBody {
+		t.Errorf("replayed body = %q, want %q", replayed, sseBody)
+	}
+	if got := resp.Header.Get("X-Request-Id"); got != "req_123" {
+		t.Errorf("replayed X-Request-Id = %q, want req_123", got)
+	}
+}
+
+func TestReplayMissingInteraction(t *testing.T) {
+	player, err := NewTransport(ModeReplay, t.TempDir(), nil, failingTransport{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("POST", "http://upstream.invalid/v1/messages", strings.NewReader(`{}`))
+	if _, err := player.RoundTrip(req); err == nil || !strings.Contains(err.Error(), "no recorded interaction") {
+		t.Errorf("RoundTrip error = %v, want no recorded interaction", err)
+	}
+}
@@ -0,0 +1,191 @@
+// Package completions 通过 Chat Completions 模拟旧版 /v1/completions，
+// 供 Claude、Gemini 等没有原生文本补全接口的 Provider 使用
+package completions
+
+import (
+	"openbridge/internal/models"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// instruction 让聊天模型续写 prompt，而不是回答它
+const instruction = "Continue the text provided by the user. Output only the continuation, without repeating or commenting on the text."
+
+// maxLogprobs 旧版 API 的 logprobs 上限
+const maxLogprobs = 5
+
+// Validate 检查模拟实现不支持的参数，返回拆分后的 prompt
+func Validate(req *models.CompletionRequest) ([]string, error) {
+	if req.Suffix != "" {
+		return nil, models.NewRequestError("suffix", "suffix is only supported by OpenAI-compatible upstreams")
+	}
+	if req.BestOf > 1 {
+		return nil, models.NewRequestError("best_of", "best_of is only supported by OpenAI-compatible upstreams")
+	}
+	if req.Logprobs != nil && (*req.Logprobs < 0 || *req.Logprobs > maxLogprobs) {
+		return nil, models.NewRequestError("logprobs", "logprobs must be between 0 and %d", maxLogprobs)
+	}
+	return req.Prompts()
+}
+
+// NewID 生成 text_completion 的 ID
+func NewID() string {
+	return "cmpl-" + uuid.New().String()
+}
+
+// ToChatRequest 将单个 prompt 转换为 Chat Completions 请求
+func ToChatRequest(req *models.CompletionRequest, prompt string) *models.ChatCompletionRequest {
+	chatReq := &models.ChatCompletionRequest{
+		Model: req.Model,
+		Messages: []models.Message{
+			{Role: "system", Content: instruction},
+			{Role: "user", Content: prompt},
+		},
+		MaxTokens:        req.MaxTokens,
+		Temperature:      req.Temperature,
+		TopP:             req.TopP,
+		PresencePenalty:  req.PresencePenalty,
+		FrequencyPenalty: req.FrequencyPenalty,
+		Stream:           req.Stream,
+		StreamOptions:    req.StreamOptions,
+		N:                req.N,
+		Stop:             req.Stop,
+		Seed:             req.Seed,
+		User:             req.User,
+		LogitBias:        req.LogitBias,
+	}
+	if req.Logprobs != nil {
+		chatReq.Logprobs = true
+		chatReq.TopLogprobs = *req.Logprobs
+	}
+	return chatReq
+}
+
+// ChoicesPerPrompt 每个 prompt 生成的 choice 数，第 i 个 prompt 的 choice 的 index 从 i*n 开始
+func ChoicesPerPrompt(req *models.CompletionRequest) int {
+	if req.N > 1 {
+		return req.N
+	}
+	return 1
+}
+
+// NewResponse 创建空的 text_completion 响应
+func NewResponse(id, model string) *models.CompletionResponse {
+	return &models.CompletionResponse{
+		ID:      id,
+		Object:  "text_completion",
+		Created: time.Now().Unix(),
+		Model:   model,
+		Choices: []models.CompletionChoice{},
+	}
+}
+
+// ConvertChoices 将一个 prompt 的 Chat Completions 响应转换为 text_completion 的 choices，index 加上 offset
+// echo 时 text 以 prompt 开头
+func ConvertChoices(chatResp *models.ChatCompletionResponse, prompt string, echo bool, offset int) []models.CompletionChoice {
+	choices := make([]models.CompletionChoice, 0, len(chatResp.Choices))
+	for _, choice := range chatResp.Choices {
+		text := choice.Message.Content
+		start := 0
+		if echo {
+			text = prompt + text
+			start = utf8.RuneCountInString(prompt)
+		}
+		finishReason := choice.FinishReason
+		choices = append(choices, models.CompletionChoice{
+			Text:         text,
+			Index:        offset + choice.Index,
+			Logprobs:     convertLogprobs(choice.Logprobs, start),
+			FinishReason: &finishReason,
+		})
+	}
+	return choices
+}
+
+// convertLogprobs 转换为旧版 logprobs 格式，text_offset 从 start 开始累计
+// echo 的 prompt 部分没有 logprobs
+func convertLogprobs(logprobs *models.Logprobs, start int) *models.CompletionLogprobs {
+	if logprobs == nil {
+		return nil
+	}
+
+	result := &models.CompletionLogprobs{
+		Tokens:        make([]string, 0, len(logprobs.Content)),
+		TokenLogprobs: make([]float64, 0, len(logprobs.Content)),
+		TopLogprobs:   make([]map[string]float64, 0, len(logprobs.Content)),
+		TextOffset:    make([]int, 0, len(logprobs.Content)),
+	}
+	offset := start
+	for _, token := range logprobs.Content {
+		top := make(map[string]float64, len(token.TopLogprobs))
+		for _, t := range token.TopLogprobs {
+			top[t.Token] = t.Logprob
+		}
+		result.Tokens = append(result.Tokens, token.Token)
+		result.TokenLogprobs = append(result.TokenLogprobs, token.Logprob)
+		result.TopLogprobs = append(result.TopLogprobs, top)
+		result.TextOffset = append(result.TextOffset, offset)
+		offset += utf8.RuneCountInString(token.Token)
+	}
+	return result
+}
+
+// StreamConverter 将一个 prompt 的 Chat Completions 流式块转换为 text_completion chunk
+type StreamConverter struct {
+	id     string
+	model  string
+	prompt string
+	echo   bool
+	offset int
+
+	started map[int]bool // 已输出过的 choice（echo 时第一次输出 prompt）
+	chars   map[int]int  // 每个 choice 已输出的字符数，用于 text_offset
+}
+
+// NewStreamConverter 创建流式转换器，多个 prompt 的 chunk 使用相同的 id，choice 的 index 加上 offset
+func NewStreamConverter(id, model, prompt string, echo bool, offset int) *StreamConverter {
+	return &StreamConverter{
+		id:      id,
+		model:   model,
+		prompt:  prompt,
+		echo:    echo,
+		offset:  offset,
+		started: make(map[int]bool),
+		chars:   make(map[int]int),
+	}
+}
+
+// Convert 转换一个流式块，没有可输出的内容时返回 nil（usage 由调用方累计）
+func (c *StreamConverter) Convert(chunk *models.ChatCompletionChunk) *models.CompletionResponse {
+	var choices []models.CompletionChoice
+	for _, choice := range chunk.Choices {
+		text := choice.Delta.Content
+		if c.echo && !c.started[choice.Index] {
+			text = c.prompt + text
+			c.chars[choice.Index] = utf8.RuneCountInString(c.prompt)
+		}
+		if text == "" && choice.FinishReason == nil && choice.Logprobs == nil {
+			continue
+		}
+		c.started[choice.Index] = true
+
+		logprobs := convertLogprobs(choice.Logprobs, c.chars[choice.Index])
+		c.chars[choice.Index] += utf8.RuneCountInString(choice.Delta.Content)
+
+		choices = append(choices, models.CompletionChoice{
+			Text:         text,
+			Index:        c.offset + choice.Index,
+			Logprobs:     logprobs,
+			FinishReason: choice.FinishReason,
+		})
+	}
+	if len(choices) == 0 {
+		return nil
+	}
+
+	resp := NewResponse(c.id, c.model)
+	resp.Choices = choices
+	return resp
+}
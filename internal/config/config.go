@@ -1,7 +1,9 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -13,6 +15,38 @@ type Config struct {
 	Providers     map[string]ProviderConfig `yaml:"providers"`
 	Routes        map[string]string         `yaml:"routes"`
 	Logging       LoggingConfig             `yaml:"logging"`
+	Media         MediaConfig               `yaml:"media"`
+	Responses     ResponsesConfig           `yaml:"responses"`
+	Images        ImagesConfig              `yaml:"images"`
+	Tokenizer     TokenizerConfig           `yaml:"tokenizer"`
+}
+
+// TokenizerConfig 本地分词器配置，用于计算 OpenAI 模型的 token 数（内置 o200k_base 和 cl100k_base 词表）
+type TokenizerConfig struct {
+	VocabDir string `yaml:"vocab_dir"` // 可选，覆盖内置词表的目录（o200k_base.tiktoken、cl100k_base.tiktoken）
+}
+
+// ImagesConfig /v1/images/generations 配置，Imagen 等只返回 base64 的上游生成的图片临时保存在内存中以 URL 返回
+type ImagesConfig struct {
+	PublicURL    string        `yaml:"public_url"`     // 图片 URL 使用的外部地址，如 https://gateway.example.com，默认使用请求的 Host
+	BlobTTL      time.Duration `yaml:"blob_ttl"`       // 图片保存时间，默认 1h
+	BlobMaxBytes int64         `yaml:"blob_max_bytes"` // 所有图片的总大小上限，默认 256MB
+}
+
+// ResponsesConfig /v1/responses 的响应存储配置（内存存储，重启后丢失）
+type ResponsesConfig struct {
+	StoreSize int           `yaml:"store_size"` // 最多保存的响应数，默认 1000，负数关闭存储
+	StoreTTL  time.Duration `yaml:"store_ttl"`  // 保存时间，默认 1h
+}
+
+// MediaConfig 远程媒体（image_url 等）下载配置，供不支持远程 URL 的上游使用
+type MediaConfig struct {
+	MaxBytes     int64         `yaml:"max_bytes"`     // 单个文件大小上限，默认 20MB
+	Timeout      time.Duration `yaml:"timeout"`       // 下载超时，默认 10s
+	AllowedHosts []string      `yaml:"allowed_hosts"` // 允许下载的域名，为空时允许所有公网地址
+	AllowPrivate bool          `yaml:"allow_private"` // 允许访问内网地址（默认禁止）
+	CacheSize    int           `yaml:"cache_size"`    // 缓存条目数，默认 64，负数关闭缓存
+	CacheTTL     time.Duration `yaml:"cache_ttl"`     // 缓存有效期，默认 10m
 }
 
 type AdminConfig struct {
@@ -26,10 +60,35 @@ type ServerConfig struct {
 }
 
 type ProviderConfig struct {
-	Type             string   `yaml:"type"`     // openai, anthropic, google
-	BaseURL          string   `yaml:"base_url"`
-	APIKeys          []string `yaml:"api_keys"`
-	RotationStrategy string   `yaml:"rotation_strategy"` // round_robin, random, least_used
+	Type             string                 `yaml:"type"`     // openai, anthropic, google, cohere
+	BaseURL          string                 `yaml:"base_url"`
+	APIKeys          []string               `yaml:"api_keys"`
+	RotationStrategy string                 `yaml:"rotation_strategy"` // round_robin, random, least_used
+	Options          map[string]interface{} `yaml:"options,omitempty"` // 各 Provider 类型专属选项
+	Cassette         *CassetteConfig        `yaml:"cassette,omitempty"` // 录制/回放上游流量
+}
+
+// CassetteConfig 上游流量录制/回放配置
+type CassetteConfig struct {
+	Mode        string   `yaml:"mode"`         // record 或 replay
+	Dir         string   `yaml:"dir"`          // cassette 文件目录
+	ScrubFields []string `yaml:"scrub_fields"` // 录制时需要脱敏的请求体 JSON 字段名（任意层级）
+}
+
+// DecodeOptions 将 options 解码到具体 Provider 定义的结构体中（使用 yaml 标签）
+func (p ProviderConfig) DecodeOptions(out interface{}) error {
+	if len(p.Options) == 0 {
+		return nil
+	}
+
+	data, err := yaml.Marshal(p.Options)
+	if err != nil {
+		return fmt.Errorf("invalid options: %w", err)
+	}
+	if err := yaml.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("invalid options: %w", err)
+	}
+	return nil
 }
 
 type LoggingConfig struct {
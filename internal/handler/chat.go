@@ -2,11 +2,14 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"openbridge/internal/config"
 	"openbridge/internal/models"
 	"openbridge/internal/provider"
+	"openbridge/internal/schema"
 	"openbridge/internal/service"
 
 	"github.com/gin-gonic/gin"
@@ -43,39 +46,87 @@ func (h *ChatHandler) CreateChatCompletion(c *gin.Context) {
 		log.Printf("📥 Client Request:\n%s", string(reqJSON))
 	}
 
+	p, actualModel, apiKey, ok := h.selectProvider(c, req.Model)
+	if !ok {
+		return
+	}
+
+	// 使用实际的模型 ID（不带前缀）发送请求
+	originalModel := req.Model
+	req.Model = actualModel
+
+	// 处理流式请求
+	if req.Stream {
+		h.handleStreamRequest(c, p, &req, apiKey, originalModel)
+		return
+	}
+
+	// 非流式请求
+	resp, err := p.ChatCompletion(&req, apiKey)
+	if err != nil {
+		log.Printf("❌ Provider error: %v", err)
+		h.handleProviderError(c, err)
+		return
+	}
+
+	// strict json_schema: 校验模型输出是否符合 schema（Claude/Gemini 等为模拟实现，无法保证）
+	if err := validateStructuredOutput(&req, resp); err != nil {
+		log.Printf("❌ Structured output validation failed: %v", err)
+		c.JSON(http.StatusBadGateway, models.NewErrorResponse(
+			err.Error(),
+			models.ErrorTypeAPIError,
+			models.ErrorCodeSchemaMismatch,
+		))
+		return
+	}
+
+	// 恢复原始模型名称（带前缀）
+	resp.Model = originalModel
+
+	// Log response
+	if h.config.Logging.LogResponses {
+		respJSON, _ := json.MarshalIndent(resp, "", "  ")
+		log.Printf("📤 Response:\n%s", string(respJSON))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// selectProvider 根据 model 选择 Provider 和 API Key，失败时已写入错误响应
+func (h *ChatHandler) selectProvider(c *gin.Context, model string) (p provider.Provider, actualModel, apiKey string, ok bool) {
 	// 根据 model 路由到对应的 Provider
-	providerName, actualModel, err := h.registry.RouteModel(req.Model)
+	providerName, actualModel, err := h.registry.RouteModel(model)
 	if err != nil {
-		log.Printf("❌ No provider for model %s: %v", req.Model, err)
+		log.Printf("❌ No provider for model %s: %v", model, err)
 		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
-			"Model not found: "+req.Model,
+			"Model not found: "+model,
 			models.ErrorTypeNotFound,
 			models.ErrorCodeModelNotFound,
 		))
-		return
+		return nil, "", "", false
 	}
 
-	p, ok := h.registry.GetProvider(providerName)
+	p, ok = h.registry.GetProvider(providerName)
 	if !ok {
 		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
 			"Provider not found: "+providerName,
 			models.ErrorTypeServerError,
 			models.ErrorCodeServerError,
 		))
-		return
+		return nil, "", "", false
 	}
 
-	log.Printf("🔀 Routing model %s to provider: %s (%s)", req.Model, p.Name(), p.Type())
+	log.Printf("🔀 Routing model %s to provider: %s (%s)", model, p.Name(), p.Type())
 
 	// 获取 API Key
-	apiKey := h.keyManagers.GetKey(providerName)
+	apiKey = h.keyManagers.GetKey(providerName)
 	if apiKey == "" {
 		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
 			"No API keys configured for provider: "+providerName,
 			models.ErrorTypeServerError,
 			models.ErrorCodeServerError,
 		))
-		return
+		return nil, "", "", false
 	}
 
 	// Mask API key for logging
@@ -85,34 +136,7 @@ func (h *ChatHandler) CreateChatCompletion(c *gin.Context) {
 	}
 	log.Printf("🔑 Using API Key: %s", maskedKey)
 
-	// 使用实际的模型 ID（不带前缀）发送请求
-	originalModel := req.Model
-	req.Model = actualModel
-
-	// 处理流式请求
-	if req.Stream {
-		h.handleStreamRequest(c, p, &req, apiKey, originalModel)
-		return
-	}
-
-	// 非流式请求
-	resp, err := p.ChatCompletion(&req, apiKey)
-	if err != nil {
-		log.Printf("❌ Provider error: %v", err)
-		h.handleProviderError(c, err)
-		return
-	}
-
-	// 恢复原始模型名称（带前缀）
-	resp.Model = originalModel
-
-	// Log response
-	if h.config.Logging.LogResponses {
-		respJSON, _ := json.MarshalIndent(resp, "", "  ")
-		log.Printf("📤 Response:\n%s", string(respJSON))
-	}
-
-	c.JSON(http.StatusOK, resp)
+	return p, actualModel, apiKey, true
 }
 
 func (h *ChatHandler) handleStreamRequest(c *gin.Context, p provider.Provider, req *models.ChatCompletionRequest, apiKey string, originalModel string) {
@@ -139,6 +163,13 @@ func (h *ChatHandler) handleStreamRequest(c *gin.Context, p provider.Provider, r
 		select {
 		case chunk, ok := <-chunkChan:
 			if !ok {
+				// Provider 先关闭 errChan 再关闭 chunkChan，错误可能还未被读取
+				if errChan != nil {
+					if err := <-errChan; err != nil {
+						h.writeStreamError(c, flusher, err)
+						return
+					}
+				}
 				// Channel closed, send [DONE]
 				c.Writer.Write([]byte("data: [DONE]\n\n"))
 				flusher.Flush()
@@ -160,39 +191,120 @@ func (h *ChatHandler) handleStreamRequest(c *gin.Context, p provider.Provider, r
 			c.Writer.Write([]byte("\n\n"))
 			flusher.Flush()
 
-		case err := <-errChan:
+		case err, ok := <-errChan:
+			if !ok {
+				// errChan 已关闭且没有错误，继续发送剩余的 chunk
+				errChan = nil
+				continue
+			}
 			if err != nil {
-				log.Printf("❌ Stream error: %v", err)
-				// 尝试发送错误信息
-				errResp := models.NewErrorResponse(
-					err.Error(),
-					models.ErrorTypeServerError,
-					models.ErrorCodeServerError,
-				)
-				data, _ := json.Marshal(errResp)
-				c.Writer.Write([]byte("data: "))
-				c.Writer.Write(data)
-				c.Writer.Write([]byte("\n\n"))
-				flusher.Flush()
+				h.writeStreamError(c, flusher, err)
+				return
 			}
-			return
 		}
 	}
 }
 
+// writeStreamError 输出流式错误
+// 尚未输出任何内容时（如请求转换失败、上游直接拒绝）按普通错误返回正确的状态码，否则以 SSE 事件发送
+func (h *ChatHandler) writeStreamError(c *gin.Context, flusher http.Flusher, err error) {
+	log.Printf("❌ Stream error: %v", err)
+
+	if !c.Writer.Written() {
+		c.Writer.Header().Del("Content-Type")
+		h.handleProviderError(c, err)
+		return
+	}
+
+	errResp := models.NewErrorResponse(
+		err.Error(),
+		models.ErrorTypeServerError,
+		models.ErrorCodeServerError,
+	)
+	data, _ := json.Marshal(errResp)
+	c.Writer.Write([]byte("data: "))
+	c.Writer.Write(data)
+	c.Writer.Write([]byte("\n\n"))
+	flusher.Flush()
+}
+
+// validateStructuredOutput 当 response_format 为 strict json_schema 时校验每个 choice 的内容
+func validateStructuredOutput(req *models.ChatCompletionRequest, resp *models.ChatCompletionResponse) error {
+	format := req.ResponseFormat
+	if format == nil || format.Type != "json_schema" || format.JSONSchema == nil {
+		return nil
+	}
+	if format.JSONSchema.Strict == nil || !*format.JSONSchema.Strict || len(format.JSONSchema.Schema) == 0 {
+		return nil
+	}
+
+	for _, choice := range resp.Choices {
+		// 工具调用时没有结构化内容；被截断或过滤的输出与 OpenAI 一致，原样返回并由 finish_reason 说明
+		switch choice.FinishReason {
+		case "tool_calls", "length", "content_filter":
+			continue
+		}
+		if err := schema.ValidateJSON(format.JSONSchema.Schema, choice.Message.Content); err != nil {
+			return fmt.Errorf("response does not match json_schema %q: %w", format.JSONSchema.Name, err)
+		}
+	}
+	return nil
+}
+
 func (h *ChatHandler) handleProviderError(c *gin.Context, err error) {
+	// 请求无法被目标 Provider 接受
+	var reqErr *models.RequestError
+	if errors.As(err, &reqErr) {
+		code := models.ErrorCodeInvalidRequest
+		if reqErr.Code != "" {
+			code = reqErr.Code
+		}
+		errResp := models.NewErrorResponse(
+			reqErr.Message,
+			models.ErrorTypeInvalidRequest,
+			code,
+		)
+		errResp.Error.Param = reqErr.Param
+		c.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
 	// 尝试解析 API 错误
 	statusCode := http.StatusInternalServerError
 	message := err.Error()
 
-	// 检查是否是 API 错误类型
-	if apiErr, ok := err.(interface{ StatusCode() int }); ok {
-		statusCode = apiErr.StatusCode()
+	// 检查是否是带 HTTP 状态码的上游错误
+	var statusErr interface{ HTTPStatus() int }
+	if errors.As(err, &statusErr) && statusErr.HTTPStatus() != 0 {
+		statusCode = statusErr.HTTPStatus()
 	}
 
+	errorType, errorCode := errorTypeForStatus(statusCode)
 	c.JSON(statusCode, models.NewErrorResponse(
 		message,
-		models.ErrorTypeAPIError,
-		models.ErrorCodeServerError,
+		errorType,
+		errorCode,
 	))
 }
+
+// errorTypeForStatus 根据 HTTP 状态码选择 OpenAI 错误类型和错误码
+func errorTypeForStatus(statusCode int) (string, string) {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return models.ErrorTypeInvalidRequest, models.ErrorCodeInvalidRequest
+	case http.StatusUnauthorized:
+		return models.ErrorTypeAuthentication, models.ErrorCodeInvalidAPIKey
+	case http.StatusForbidden:
+		return models.ErrorTypePermission, models.ErrorCodeServerError
+	case http.StatusNotFound:
+		return models.ErrorTypeNotFound, models.ErrorCodeModelNotFound
+	case http.StatusTooManyRequests:
+		return models.ErrorTypeRateLimit, models.ErrorCodeRateLimitExceeded
+	case http.StatusServiceUnavailable:
+		return models.ErrorTypeServiceUnavailable, models.ErrorCodeServerError
+	case http.StatusGatewayTimeout:
+		return models.ErrorTypeTimeout, models.ErrorCodeServerError
+	default:
+		return models.ErrorTypeAPIError, models.ErrorCodeServerError
+	}
+}
@@ -0,0 +1,245 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"openbridge/internal/config"
+	"openbridge/internal/models"
+	"openbridge/internal/provider"
+	"openbridge/internal/provider/mock"
+	"openbridge/internal/service"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testFixtures = `
+- match: weather
+  tool_calls:
+    - name: get_weather
+      arguments: {city: Paris}
+- content: fallback reply
+`
+
+// newTestServer 使用 mock Provider 创建 ChatHandler：
+// mock/echo 回显输入，mock/limited 总是返回 429，mock/fixtures 按脚本回复
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	fixturesFile := filepath.Join(t.TempDir(), "fixtures.yaml")
+	if err := os.WriteFile(fixturesFile, []byte(testFixtures), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := provider.NewRegistry()
+	keyManagers := service.NewProviderKeyManagers()
+	for name, opts := range map[string]mock.Options{
+		"echo":     {ChunkSize: 4},
+		"limited":  {RateLimitRate: 1},
+		"fixtures": {Mode: "fixtures", FixturesFile: fixturesFile},
+	} {
+		p, err := mock.New(name, opts)
+		if err != nil {
+			t.Fatalf("mock.New(%s): %v", name, err)
+		}
+		registry.Register(name, p)
+		registry.CacheModel("mock/"+name, name, "mock-1")
+		keyManagers.Register(name, []string{"test-key"}, "round_robin")
+	}
+
+	r := gin.New()
+	r.POST("/v1/chat/completions", NewChatHandler(&config.Config{}, registry, keyManagers).CreateChatCompletion)
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func postChat(t *testing.T, server *httptest.Server, body string) *http.Response {
+	t.Helper()
+	resp, err := http.Post(server.URL+"/v1/chat/completions", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestChatCompletion(t *testing.T) {
+	server := newTestServer(t)
+
+	resp := postChat(t, server, `{"model":"mock/echo","messages":[{"role":"user","content":"hello mock world"}]}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+
+	var chatResp models.ChatCompletionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		t.Fatal(err)
+	}
+	if chatResp.Model != "mock/echo" {
+		t.Errorf("model = %q, want the prefixed model from the request", chatResp.Model)
+	}
+	if len(chatResp.Choices) != 1 || chatResp.Choices[0].Message.Content != "hello mock world" {
+		t.Fatalf("choices = %+v, want the echoed prompt", chatResp.Choices)
+	}
+	if chatResp.Choices[0].FinishReason != "stop" {
+		t.Errorf("finish_reason = %q, want stop", chatResp.Choices[0].FinishReason)
+	}
+	if chatResp.Usage.PromptTokens != 3 || chatResp.Usage.CompletionTokens != 3 || chatResp.Usage.TotalTokens != 6 {
+		t.Errorf("usage = %+v, want 3 + 3", chatResp.Usage)
+	}
+}
+
+func TestChatCompletionStreamIncludeUsage(t *testing.T) {
+	server := newTestServer(t)
+
+	resp := postChat(t, server, `{"model":"mock/echo","stream":true,"stream_options":{"include_usage":true},
+		"messages":[{"role":"user","content":"hello mock world"}]}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+		t.Errorf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	var (
+		content      strings.Builder
+		finishReason string
+		usage        *models.Usage
+		done         bool
+	)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			done = true
+			break
+		}
+		var chunk models.ChatCompletionChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatalf("invalid chunk %q: %v", data, err)
+		}
+		if chunk.Model != "mock/echo" {
+			t.Errorf("chunk model = %q, want mock/echo", chunk.Model)
+		}
+		for _, choice := range chunk.Choices {
+			content.WriteString(choice.Delta.Content)
+			if choice.FinishReason != nil {
+				finishReason = *choice.FinishReason
+			}
+		}
+		if chunk.Usage != nil {
+			if len(chunk.Choices) != 0 {
+				t.Errorf("usage chunk has %d choices, want 0", len(chunk.Choices))
+			}
+			usage = chunk.Usage
+		}
+	}
+
+	if !done {
+		t.Error("stream did not end with [DONE]")
+	}
+	if content.String() != "hello mock world" {
+		t.Errorf("content = %q, want the echoed prompt", content.String())
+	}
+	if finishReason != "stop" {
+		t.Errorf("finish_reason = %q, want stop", finishReason)
+	}
+	if usage == nil || usage.TotalTokens != 6 {
+		t.Errorf("usage = %+v, want a final usage chunk with 6 tokens", usage)
+	}
+}
+
+func TestChatCompletionRateLimit(t *testing.T) {
+	server := newTestServer(t)
+
+	for _, stream := range []string{"false", "true"} {
+		resp := postChat(t, server, `{"model":"mock/limited","stream":`+stream+`,"messages":[{"role":"user","content":"hi"}]}`)
+		if resp.StatusCode != http.StatusTooManyRequests {
+			t.Fatalf("stream=%s: status = %d, want 429", stream, resp.StatusCode)
+		}
+
+		var errResp models.ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil {
+			t.Fatal(err)
+		}
+		if errResp.Error.Type != models.ErrorTypeRateLimit || errResp.Error.Code != models.ErrorCodeRateLimitExceeded {
+			t.Errorf("stream=%s: error = %+v, want rate_limit_error / rate_limit_exceeded", stream, errResp.Error)
+		}
+	}
+}
+
+func TestChatCompletionFixtureToolCalls(t *testing.T) {
+	server := newTestServer(t)
+
+	resp := postChat(t, server, `{"model":"mock/fixtures","messages":[{"role":"user","content":"What's the weather in Paris?"}],
+		"tools":[{"type":"function","function":{"name":"get_weather","parameters":{"type":"object"}}}]}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+
+	var chatResp models.ChatCompletionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		t.Fatal(err)
+	}
+	choice := chatResp.Choices[0]
+	if choice.FinishReason != "tool_calls" {
+		t.Errorf("finish_reason = %q, want tool_calls", choice.FinishReason)
+	}
+	if len(choice.Message.ToolCalls) != 1 {
+		t.Fatalf("tool_calls = %+v, want 1 call", choice.Message.ToolCalls)
+	}
+	call := choice.Message.ToolCalls[0]
+	if call.ID != "call_mock_0" || call.Type != "function" || call.Function.Name != "get_weather" {
+		t.Errorf("tool call = %+v, want call_mock_0 get_weather", call)
+	}
+	if call.Function.Arguments != `{"city":"Paris"}` {
+		t.Errorf("arguments = %s, want {\"city\":\"Paris\"}", call.Function.Arguments)
+	}
+
+	// 不匹配的 prompt 命中兜底 fixture
+	resp = postChat(t, server, `{"model":"mock/fixtures","messages":[{"role":"user","content":"something else"}]}`)
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		t.Fatal(err)
+	}
+	if got := chatResp.Choices[0].Message.Content; got != "fallback reply" {
+		t.Errorf("content = %q, want fallback reply", got)
+	}
+}
+
+func TestValidateStructuredOutputSkipsTruncated(t *testing.T) {
+	strict := true
+	req := &models.ChatCompletionRequest{ResponseFormat: &models.ResponseFormat{
+		Type: "json_schema",
+		JSONSchema: &models.JSONSchema{Name: "answer", Strict: &strict, Schema: map[string]any{
+			"type": "object", "required": []any{"ok"}, "properties": map[string]any{"ok": map[string]any{"type": "boolean"}},
+		}},
+	}}
+
+	for _, tt := range []struct {
+		finishReason string
+		wantErr      bool
+	}{
+		{"stop", true},
+		{"length", false},
+		{"content_filter", false},
+		{"tool_calls", false},
+	} {
+		resp := &models.ChatCompletionResponse{Choices: []models.Choice{{
+			Message:      models.ResponseMessage{Role: "assistant", Content: `{"ok":`},
+			FinishReason: tt.finishReason,
+		}}}
+		if err := validateStructuredOutput(req, resp); (err != nil) != tt.wantErr {
+			t.Errorf("finish_reason %s: err = %v, want error %v", tt.finishReason, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"openbridge/internal/completions"
+	"openbridge/internal/models"
+	"openbridge/internal/provider"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CompletionsHandler 旧版 /v1/completions
+// 原生支持的 Provider（OpenAI 兼容上游）直接透传，其他 Provider 通过 Chat Completions 模拟
+type CompletionsHandler struct {
+	chat *ChatHandler
+}
+
+func NewCompletionsHandler(chat *ChatHandler) *CompletionsHandler {
+	return &CompletionsHandler{chat: chat}
+}
+
+func (h *CompletionsHandler) CreateCompletion(c *gin.Context) {
+	var req models.CompletionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			err.Error(),
+			models.ErrorTypeInvalidRequest,
+			models.ErrorCodeInvalidRequest,
+		))
+		return
+	}
+
+	if h.chat.config.Logging.LogRequests {
+		reqJSON, _ := json.MarshalIndent(req, "", "  ")
+		log.Printf("📥 Client Request (completions):\n%s", string(reqJSON))
+	}
+
+	p, actualModel, apiKey, ok := h.chat.selectProvider(c, req.Model)
+	if !ok {
+		return
+	}
+
+	originalModel := req.Model
+	req.Model = actualModel
+
+	if native, ok := p.(provider.CompletionProvider); ok {
+		if req.Stream {
+			h.handleNativeStream(c, native, &req, apiKey, originalModel)
+			return
+		}
+
+		resp, err := native.Completion(&req, apiKey)
+		if err != nil {
+			log.Printf("❌ Provider error: %v", err)
+			h.chat.handleProviderError(c, err)
+			return
+		}
+		resp.Model = originalModel
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	prompts, err := completions.Validate(&req)
+	if err != nil {
+		h.chat.handleProviderError(c, err)
+		return
+	}
+
+	if req.Stream {
+		h.handleEmulatedStream(c, p, &req, prompts, apiKey, originalModel)
+		return
+	}
+
+	// 每个 prompt 单独请求，choices 按 prompt 顺序排列
+	resp := completions.NewResponse(completions.NewID(), originalModel)
+	n := completions.ChoicesPerPrompt(&req)
+	var usage models.Usage
+	for i, prompt := range prompts {
+		chatResp, err := p.ChatCompletion(completions.ToChatRequest(&req, prompt), apiKey)
+		if err != nil {
+			log.Printf("❌ Provider error: %v", err)
+			h.chat.handleProviderError(c, err)
+			return
+		}
+		resp.Choices = append(resp.Choices, completions.ConvertChoices(chatResp, prompt, req.Echo, i*n)...)
+		usage.Add(chatResp.Usage)
+	}
+	resp.Usage = &usage
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleNativeStream 透传上游的 text_completion chunk
+func (h *CompletionsHandler) handleNativeStream(c *gin.Context, p provider.CompletionProvider, req *models.CompletionRequest, apiKey string, originalModel string) {
+	flusher, ok := startSSE(c)
+	if !ok {
+		return
+	}
+
+	chunkChan, errChan := p.CompletionStream(req, apiKey)
+	err := receiveStream(chunkChan, errChan, func(chunk *models.CompletionResponse) {
+		chunk.Model = originalModel
+		writeSSEData(c, flusher, chunk)
+	})
+	if err != nil {
+		h.chat.writeStreamError(c, flusher, err)
+		return
+	}
+
+	c.Writer.Write([]byte("data: [DONE]\n\n"))
+	flusher.Flush()
+	log.Printf("✅ Stream completed")
+}
+
+// handleEmulatedStream 依次流式请求每个 prompt，转换为 text_completion chunk
+func (h *CompletionsHandler) handleEmulatedStream(c *gin.Context, p provider.Provider, req *models.CompletionRequest, prompts []string, apiKey string, originalModel string) {
+	flusher, ok := startSSE(c)
+	if !ok {
+		return
+	}
+
+	id := completions.NewID()
+	n := completions.ChoicesPerPrompt(req)
+	var usage models.Usage
+	for i, prompt := range prompts {
+		converter := completions.NewStreamConverter(id, originalModel, prompt, req.Echo, i*n)
+		chunkChan, errChan := p.ChatCompletionStream(completions.ToChatRequest(req, prompt), apiKey)
+		err := receiveStream(chunkChan, errChan, func(chunk *models.ChatCompletionChunk) {
+			if chunk.Usage != nil {
+				usage.Add(*chunk.Usage)
+			}
+			if out := converter.Convert(chunk); out != nil {
+				writeSSEData(c, flusher, out)
+			}
+		})
+		if err != nil {
+			h.chat.writeStreamError(c, flusher, err)
+			return
+		}
+	}
+
+	// 所有 prompt 的 usage 合并为最后一个 chunk
+	if req.IncludeUsage() {
+		chunk := completions.NewResponse(id, originalModel)
+		chunk.Usage = &usage
+		writeSSEData(c, flusher, chunk)
+	}
+
+	c.Writer.Write([]byte("data: [DONE]\n\n"))
+	flusher.Flush()
+	log.Printf("✅ Stream completed")
+}
+
+// startSSE 设置 SSE 响应头
+func startSSE(c *gin.Context) (http.Flusher, bool) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		log.Printf("❌ Streaming not supported")
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			"Streaming not supported",
+			models.ErrorTypeServerError,
+			models.ErrorCodeServerError,
+		))
+	}
+	return flusher, ok
+}
+
+// writeSSEData 输出一个 data: 事件
+func writeSSEData(c *gin.Context, flusher http.Flusher, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("❌ Error marshaling chunk: %v", err)
+		return
+	}
+	c.Writer.Write([]byte("data: "))
+	c.Writer.Write(data)
+	c.Writer.Write([]byte("\n\n"))
+	flusher.Flush()
+}
+
+// receiveStream 读取 Provider 的流直到结束，返回流中的错误
+func receiveStream[T any](chunkChan <-chan T, errChan <-chan error, onChunk func(T)) error {
+	for {
+		select {
+		case chunk, ok := <-chunkChan:
+			if !ok {
+				// Provider 先关闭 errChan 再关闭 chunkChan，错误可能还未被读取
+				if errChan != nil {
+					if err := <-errChan; err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+			onChunk(chunk)
+
+		case err, ok := <-errChan:
+			if !ok {
+				errChan = nil
+				continue
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
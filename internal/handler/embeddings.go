@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"openbridge/internal/models"
+	"openbridge/internal/provider"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EmbeddingsHandler /v1/embeddings，复用 ChatHandler 的路由和错误处理
+type EmbeddingsHandler struct {
+	chat *ChatHandler
+}
+
+func NewEmbeddingsHandler(chat *ChatHandler) *EmbeddingsHandler {
+	return &EmbeddingsHandler{chat: chat}
+}
+
+func (h *EmbeddingsHandler) CreateEmbeddings(c *gin.Context) {
+	var req models.EmbeddingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			err.Error(),
+			models.ErrorTypeInvalidRequest,
+			models.ErrorCodeInvalidRequest,
+		))
+		return
+	}
+
+	inputs, err := req.Inputs()
+	if err != nil {
+		h.chat.handleProviderError(c, err)
+		return
+	}
+
+	p, actualModel, apiKey, ok := h.chat.selectProvider(c, req.Model)
+	if !ok {
+		return
+	}
+
+	embedder, ok := p.(provider.EmbeddingProvider)
+	if !ok {
+		errResp := models.NewErrorResponse(
+			fmt.Sprintf("Provider %s (%s) does not support embeddings", p.Name(), p.Type()),
+			models.ErrorTypeInvalidRequest,
+			models.ErrorCodeInvalidRequest,
+		)
+		errResp.Error.Param = "model"
+		c.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	originalModel := req.Model
+	req.Model = actualModel
+
+	// 输入数超过上游限制时分批请求，按顺序合并结果并累计 usage
+	resp := &models.EmbeddingResponse{
+		Object: "list",
+		Data:   make([]models.Embedding, 0, len(inputs)),
+	}
+	batchSize := embedder.MaxEmbeddingInputs()
+	batches := 0
+	for start := 0; start < len(inputs); start += batchSize {
+		end := start + batchSize
+		if end > len(inputs) {
+			end = len(inputs)
+		}
+		batch := &req
+		if len(inputs) > batchSize {
+			batch = req.WithInputs(inputs[start:end])
+		}
+
+		batchResp, err := embedder.Embeddings(batch, apiKey)
+		if err != nil {
+			log.Printf("❌ Provider error: %v", err)
+			h.chat.handleProviderError(c, err)
+			return
+		}
+		for _, e := range batchResp.Data {
+			e.Index += start
+			resp.Data = append(resp.Data, e)
+		}
+		resp.Usage.PromptTokens += batchResp.Usage.PromptTokens
+		resp.Usage.TotalTokens += batchResp.Usage.TotalTokens
+		resp.Usage.Estimated = resp.Usage.Estimated || batchResp.Usage.Estimated
+		batches++
+	}
+
+	// 恢复原始模型名称（带前缀）
+	resp.Model = originalModel
+	log.Printf("✅ Embeddings: %d inputs in %d batches, %d tokens", len(inputs), batches, resp.Usage.TotalTokens)
+
+	c.JSON(http.StatusOK, resp)
+}
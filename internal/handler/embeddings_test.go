@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"openbridge/internal/config"
+	"openbridge/internal/models"
+	"openbridge/internal/provider"
+	"openbridge/internal/provider/mock"
+	"openbridge/internal/service"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// batchEmbedder 每批最多 2 个输入，向量为输入文本的长度，记录每批的输入
+type batchEmbedder struct {
+	*mock.Provider
+	batches [][]json.RawMessage
+}
+
+func (p *batchEmbedder) MaxEmbeddingInputs() int {
+	return 2
+}
+
+func (p *batchEmbedder) Embeddings(req *models.EmbeddingRequest, apiKey string) (*models.EmbeddingResponse, error) {
+	inputs, err := req.Inputs()
+	if err != nil {
+		return nil, err
+	}
+	p.batches = append(p.batches, inputs)
+
+	resp := &models.EmbeddingResponse{Object: "list", Model: req.Model}
+	for i, input := range inputs {
+		var text string
+		json.Unmarshal(input, &text)
+		resp.Data = append(resp.Data, models.NewEmbedding(i, []float64{float64(len(text))}, req.EncodingFormat))
+	}
+	resp.Usage.PromptTokens = len(inputs)
+	resp.Usage.TotalTokens = len(inputs)
+	resp.Usage.Estimated = true
+	return resp, nil
+}
+
+func TestEmbeddingsBatching(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	base, err := mock.New("embed", mock.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	embedder := &batchEmbedder{Provider: base}
+	registry := provider.NewRegistry()
+	registry.Register("embed", embedder)
+	registry.CacheModel("mock/embed", "embed", "embed-1")
+	keyManagers := service.NewProviderKeyManagers()
+	keyManagers.Register("embed", []string{"test-key"}, "round_robin")
+
+	r := gin.New()
+	r.POST("/v1/embeddings", NewEmbeddingsHandler(NewChatHandler(&config.Config{}, registry, keyManagers)).CreateEmbeddings)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/v1/embeddings", "application/json",
+		strings.NewReader(`{"model":"mock/embed","input":["a","bb","ccc","dddd","eeeee"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+
+	var embResp models.EmbeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&embResp); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(embedder.batches) != 3 || len(embedder.batches[0]) != 2 || len(embedder.batches[2]) != 1 {
+		t.Fatalf("batches = %s, want 2 + 2 + 1 inputs", embedder.batches)
+	}
+	if embResp.Model != "mock/embed" {
+		t.Errorf("model = %q, want mock/embed", embResp.Model)
+	}
+	if len(embResp.Data) != 5 {
+		t.Fatalf("data = %+v, want 5 embeddings", embResp.Data)
+	}
+	// index 按批次偏移，向量仍对应原位置的输入
+	for i, e := range embResp.Data {
+		var values []float64
+		json.Unmarshal(e.Embedding, &values)
+		if e.Index != i || len(values) != 1 || values[0] != float64(i+1) {
+			t.Errorf("data[%d] = index %d embedding %s, want index %d embedding [%d]", i, e.Index, e.Embedding, i, i+1)
+		}
+	}
+	if embResp.Usage.PromptTokens != 5 || embResp.Usage.TotalTokens != 5 || !embResp.Usage.Estimated {
+		t.Errorf("usage = %+v, want 5 estimated tokens", embResp.Usage)
+	}
+}
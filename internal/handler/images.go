@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net/http"
+	"openbridge/internal/blob"
+	"openbridge/internal/models"
+	"openbridge/internal/provider"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImagesHandler /v1/images/generations，复用 ChatHandler 的路由和错误处理
+// 只能返回 b64_json 的 Provider（Imagen）在 response_format 为 url 时把图片保存到临时存储，返回 /blobs/{id} 的 URL
+type ImagesHandler struct {
+	chat      *ChatHandler
+	blobs     *blob.Store
+	publicURL string // 生成 URL 使用的外部地址，为空时使用请求的 Host
+}
+
+func NewImagesHandler(chat *ChatHandler, blobs *blob.Store, publicURL string) *ImagesHandler {
+	return &ImagesHandler{
+		chat:      chat,
+		blobs:     blobs,
+		publicURL: strings.TrimSuffix(publicURL, "/"),
+	}
+}
+
+func (h *ImagesHandler) CreateImage(c *gin.Context) {
+	var req models.ImageGenerationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			err.Error(),
+			models.ErrorTypeInvalidRequest,
+			models.ErrorCodeInvalidRequest,
+		))
+		return
+	}
+
+	if req.Prompt == "" {
+		h.chat.handleProviderError(c, models.NewRequestError("prompt", "prompt is required"))
+		return
+	}
+	if req.ResponseFormat != "" && req.ResponseFormat != "url" && req.ResponseFormat != "b64_json" {
+		h.chat.handleProviderError(c, models.NewRequestError("response_format", "response_format must be url or b64_json"))
+		return
+	}
+
+	p, actualModel, apiKey, ok := h.chat.selectProvider(c, req.Model)
+	if !ok {
+		return
+	}
+
+	generator, ok := p.(provider.ImageProvider)
+	if !ok {
+		errResp := models.NewErrorResponse(
+			fmt.Sprintf("Provider %s (%s) does not support image generation", p.Name(), p.Type()),
+			models.ErrorTypeInvalidRequest,
+			models.ErrorCodeInvalidRequest,
+		)
+		errResp.Error.Param = "model"
+		c.JSON(http.StatusBadRequest, errResp)
+		return
+	}
+
+	req.Model = actualModel
+	resp, err := generator.GenerateImages(&req, apiKey)
+	if err != nil {
+		log.Printf("❌ Provider error: %v", err)
+		h.chat.handleProviderError(c, err)
+		return
+	}
+
+	// OpenAI 兼容上游自己处理 response_format（gpt-image 只返回 b64_json），其他 Provider 按 OpenAI 的默认值 url 返回
+	if p.Type() != "openai" && req.ResponseFormat != "b64_json" {
+		if err := h.storeImages(c, resp); err != nil {
+			log.Printf("❌ Failed to store images: %v", err)
+			h.chat.handleProviderError(c, err)
+			return
+		}
+	}
+
+	log.Printf("✅ Generated %d images", len(resp.Data))
+	c.JSON(http.StatusOK, resp)
+}
+
+// storeImages 把 b64_json 图片保存到临时存储并替换为 URL
+func (h *ImagesHandler) storeImages(c *gin.Context, resp *models.ImageResponse) error {
+	for i := range resp.Data {
+		image := &resp.Data[i]
+		if image.B64JSON == "" || image.URL != "" {
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(image.B64JSON)
+		if err != nil {
+			return fmt.Errorf("failed to decode image: %w", err)
+		}
+		image.URL = h.baseURL(c) + "/blobs/" + h.blobs.Put(data, "")
+		image.B64JSON = ""
+	}
+	return nil
+}
+
+// baseURL 返回生成 URL 使用的外部地址
+func (h *ImagesHandler) baseURL(c *gin.Context) string {
+	if h.publicURL != "" {
+		return h.publicURL
+	}
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+// GetBlob 下载临时保存的文件（无需认证，ID 不可猜测）
+func (h *ImagesHandler) GetBlob(c *gin.Context) {
+	b, ok := h.blobs.Get(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, models.NewErrorResponse(
+			"File not found or expired",
+			models.ErrorTypeNotFound,
+			models.ErrorCodeInvalidRequest,
+		))
+		return
+	}
+	c.Header("Cache-Control", fmt.Sprintf("private, max-age=%d", int(h.blobs.TTL().Seconds())))
+	c.Data(http.StatusOK, b.ContentType, b.Data)
+}
@@ -0,0 +1,241 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"openbridge/internal/models"
+	"openbridge/internal/provider"
+	"openbridge/internal/responses"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ResponsesHandler OpenAI Responses API，请求转换为 Chat Completions 后复用 ChatHandler 的路由和错误处理
+type ResponsesHandler struct {
+	chat  *ChatHandler
+	store *responses.Store
+}
+
+func NewResponsesHandler(chat *ChatHandler, store *responses.Store) *ResponsesHandler {
+	return &ResponsesHandler{
+		chat:  chat,
+		store: store,
+	}
+}
+
+func (h *ResponsesHandler) CreateResponse(c *gin.Context) {
+	var req models.ResponsesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			err.Error(),
+			models.ErrorTypeInvalidRequest,
+			models.ErrorCodeInvalidRequest,
+		))
+		return
+	}
+
+	if h.chat.config.Logging.LogRequests {
+		reqJSON, _ := json.MarshalIndent(req, "", "  ")
+		log.Printf("📥 Client Request (responses):\n%s", string(reqJSON))
+	}
+
+	// previous_response_id: 取出之前保存的完整对话
+	var history []models.Message
+	if req.PreviousResponseID != "" {
+		stored, ok := h.store.Get(req.PreviousResponseID, responseOwner(c))
+		if !ok {
+			errResp := models.NewErrorResponse(
+				fmt.Sprintf("Previous response with id '%s' not found.", req.PreviousResponseID),
+				models.ErrorTypeInvalidRequest,
+				models.ErrorCodeResponseNotFound,
+			)
+			errResp.Error.Param = "previous_response_id"
+			c.JSON(http.StatusBadRequest, errResp)
+			return
+		}
+		history = stored.Messages
+	}
+
+	chatReq, input, err := responses.ToChatRequest(&req, history)
+	if err != nil {
+		h.chat.handleProviderError(c, err)
+		return
+	}
+
+	p, actualModel, apiKey, ok := h.chat.selectProvider(c, req.Model)
+	if !ok {
+		return
+	}
+	chatReq.Model = actualModel
+
+	// 响应中的 model 使用原始模型名称（带前缀）
+	resp := responses.NewResponse(&req)
+	messages := append(append([]models.Message{}, history...), input...)
+
+	if req.Stream {
+		h.handleStreamRequest(c, p, chatReq, apiKey, resp, messages)
+		return
+	}
+
+	chatResp, err := p.ChatCompletion(chatReq, apiKey)
+	if err != nil {
+		log.Printf("❌ Provider error: %v", err)
+		h.chat.handleProviderError(c, err)
+		return
+	}
+
+	if err := validateStructuredOutput(chatReq, chatResp); err != nil {
+		log.Printf("❌ Structured output validation failed: %v", err)
+		c.JSON(http.StatusBadGateway, models.NewErrorResponse(
+			err.Error(),
+			models.ErrorTypeAPIError,
+			models.ErrorCodeSchemaMismatch,
+		))
+		return
+	}
+
+	responses.ApplyChatResponse(resp, chatResp)
+	if len(chatResp.Choices) > 0 {
+		h.save(c, resp, messages, chatResp.Choices[0].Message)
+	}
+
+	if h.chat.config.Logging.LogResponses {
+		respJSON, _ := json.MarshalIndent(resp, "", "  ")
+		log.Printf("📤 Response:\n%s", string(respJSON))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// handleStreamRequest 以 Responses API 语义事件输出流式响应（没有 [DONE]）
+func (h *ResponsesHandler) handleStreamRequest(c *gin.Context, p provider.Provider, chatReq *models.ChatCompletionRequest, apiKey string, resp *models.Response, messages []models.Message) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		log.Printf("❌ Streaming not supported")
+		c.JSON(http.StatusInternalServerError, models.NewErrorResponse(
+			"Streaming not supported",
+			models.ErrorTypeServerError,
+			models.ErrorCodeServerError,
+		))
+		return
+	}
+
+	converter := responses.NewStreamConverter(resp)
+	writeEvents := func(events []models.ResponseStreamEvent) {
+		for _, event := range events {
+			data, err := json.Marshal(event)
+			if err != nil {
+				log.Printf("❌ Error marshaling event: %v", err)
+				continue
+			}
+			c.Writer.Write([]byte("event: " + event.Type + "\ndata: "))
+			c.Writer.Write(data)
+			c.Writer.Write([]byte("\n\n"))
+		}
+		flusher.Flush()
+	}
+	// 尚未输出任何内容时按普通错误返回，否则以 response.failed 结束
+	writeError := func(err error) {
+		log.Printf("❌ Stream error: %v", err)
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Type")
+			h.chat.handleProviderError(c, err)
+			return
+		}
+		writeEvents(converter.Fail(models.ErrorCodeServerError, err.Error()))
+	}
+
+	chunkChan, errChan := p.ChatCompletionStream(chatReq, apiKey)
+
+	for {
+		select {
+		case chunk, ok := <-chunkChan:
+			if !ok {
+				// Provider 先关闭 errChan 再关闭 chunkChan，错误可能还未被读取
+				if errChan != nil {
+					if err := <-errChan; err != nil {
+						writeError(err)
+						return
+					}
+				}
+				writeEvents(converter.Finish())
+				h.save(c, converter.Response(), messages, converter.Message())
+				log.Printf("✅ Stream completed")
+				return
+			}
+			writeEvents(converter.Convert(chunk))
+
+		case err, ok := <-errChan:
+			if !ok {
+				errChan = nil
+				continue
+			}
+			if err != nil {
+				writeError(err)
+				return
+			}
+		}
+	}
+}
+
+// save 保存响应和完整对话，供 previous_response_id 续接
+func (h *ResponsesHandler) save(c *gin.Context, resp *models.Response, messages []models.Message, output models.ResponseMessage) {
+	if !resp.Store {
+		return
+	}
+	h.store.Put(&responses.Stored{
+		Owner:    responseOwner(c),
+		Response: resp,
+		Messages: append(messages, responses.AssistantMessage(output)),
+	})
+}
+
+func (h *ResponsesHandler) GetResponse(c *gin.Context) {
+	stored, ok := h.store.Get(c.Param("id"), responseOwner(c))
+	if !ok {
+		h.notFound(c)
+		return
+	}
+	c.JSON(http.StatusOK, stored.Response)
+}
+
+func (h *ResponsesHandler) DeleteResponse(c *gin.Context) {
+	id := c.Param("id")
+	if !h.store.Delete(id, responseOwner(c)) {
+		h.notFound(c)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"id":      id,
+		"object":  "response.deleted",
+		"deleted": true,
+	})
+}
+
+// responseOwner 保存的响应归属的调用方：用户系统的 Key 按用户名区分，配置文件中的客户端 Key 按 Key 区分（只保存摘要）
+func responseOwner(c *gin.Context) string {
+	if username := c.GetString("username"); username != "" {
+		return "user:" + username
+	}
+	key := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	sum := sha256.Sum256([]byte(key))
+	return "key:" + hex.EncodeToString(sum[:])
+}
+
+func (h *ResponsesHandler) notFound(c *gin.Context) {
+	c.JSON(http.StatusNotFound, models.NewErrorResponse(
+		fmt.Sprintf("Response with id '%s' not found.", c.Param("id")),
+		models.ErrorTypeNotFound,
+		models.ErrorCodeResponseNotFound,
+	))
+}
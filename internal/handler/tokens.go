@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"openbridge/internal/models"
+	"openbridge/internal/provider"
+	"openbridge/internal/tokenizer"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TokensHandler /v1/chat/completions/count_tokens，计算 Chat Completions 请求的输入 token 数，用于发送前检查上下文长度
+// Claude 和 Gemini 使用上游的计数接口，OpenAI 使用本地分词器，其他 Provider 借用 o200k_base 近似计算
+type TokensHandler struct {
+	chat *ChatHandler
+}
+
+func NewTokensHandler(chat *ChatHandler) *TokensHandler {
+	return &TokensHandler{chat: chat}
+}
+
+func (h *TokensHandler) CountTokens(c *gin.Context) {
+	var req models.ChatCompletionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewErrorResponse(
+			err.Error(),
+			models.ErrorTypeInvalidRequest,
+			models.ErrorCodeInvalidRequest,
+		))
+		return
+	}
+
+	if len(req.Messages) == 0 {
+		h.chat.handleProviderError(c, models.NewRequestError("messages", "messages is required"))
+		return
+	}
+
+	p, actualModel, apiKey, ok := h.chat.selectProvider(c, req.Model)
+	if !ok {
+		return
+	}
+
+	originalModel := req.Model
+	req.Model = actualModel
+
+	var resp *models.TokenCountResponse
+	if counter, ok := p.(provider.TokenCounter); ok {
+		var err error
+		resp, err = counter.CountTokens(&req, apiKey)
+		if err != nil {
+			log.Printf("❌ Provider error: %v", err)
+			h.chat.handleProviderError(c, err)
+			return
+		}
+	} else {
+		// 没有计数接口的 Provider 使用默认编码近似计算
+		count, _ := tokenizer.Default().CountChat(&req)
+		resp = &models.TokenCountResponse{InputTokens: count, Estimated: true}
+	}
+
+	resp.Object = "token_count"
+	resp.Model = originalModel
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,379 @@
+// Package media 下载并校验请求中引用的远程媒体（图片等），供不支持远程 URL 的上游使用
+package media
+
+import (
+	"container/list"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Media 下载或解析后的媒体内容
+type Media struct {
+	MimeType string
+	Data     []byte
+}
+
+// Base64 返回 base64 编码的内容
+func (m *Media) Base64() string {
+	return base64.StdEncoding.EncodeToString(m.Data)
+}
+
+// Options 远程媒体下载配置
+type Options struct {
+	MaxBytes     int64         `yaml:"max_bytes"`     // 单个文件大小上限，默认 20MB
+	Timeout      time.Duration `yaml:"timeout"`       // 单次下载超时，默认 10s
+	AllowedHosts []string      `yaml:"allowed_hosts"` // 为空时允许所有公网地址，支持 *.example.com
+	AllowPrivate bool          `yaml:"allow_private"` // 允许访问内网/回环地址（默认禁止，防止 SSRF）
+	CacheSize    int           `yaml:"cache_size"`    // 缓存的条目数，默认 64，负数关闭缓存
+	CacheTTL     time.Duration `yaml:"cache_ttl"`     // 缓存有效期，默认 10m
+}
+
+// Fetcher 带大小/时间限制、SSRF 防护和缓存的远程媒体下载器
+type Fetcher struct {
+	opts   Options
+	client *http.Client
+	cache  *cache
+}
+
+// maxRedirects 最多跟随的重定向次数
+const maxRedirects = 5
+
+// New 创建 Fetcher
+func New(opts Options) *Fetcher {
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = 20 << 20
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.CacheSize == 0 {
+		opts.CacheSize = 64
+	}
+	if opts.CacheTTL <= 0 {
+		opts.CacheTTL = 10 * time.Minute
+	}
+
+	f := &Fetcher{opts: opts}
+
+	// 在建立连接时检查解析后的 IP，避免 DNS rebinding 绕过检查
+	dialer := &net.Dialer{
+		Timeout: opts.Timeout,
+		Control: func(network, address string, c syscall.RawConn) error {
+			if opts.AllowPrivate {
+				return nil
+			}
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isPrivateIP(ip) {
+				return fmt.Errorf("address %s is not allowed", host)
+			}
+			return nil
+		},
+	}
+
+	f.client = &http.Client{
+		Timeout: opts.Timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: opts.Timeout,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return errors.New("too many redirects")
+			}
+			// 重定向目标同样需要通过白名单
+			return f.checkURL(req.URL)
+		},
+	}
+
+	if opts.CacheSize > 0 {
+		f.cache = newCache(opts.CacheSize, opts.CacheTTL)
+	}
+	return f
+}
+
+var (
+	defaultMu      sync.RWMutex
+	defaultFetcher = New(Options{})
+)
+
+// SetDefault 替换全局默认 Fetcher（启动时根据配置调用）
+func SetDefault(f *Fetcher) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultFetcher = f
+}
+
+// Default 返回全局默认 Fetcher
+func Default() *Fetcher {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultFetcher
+}
+
+// Fetch 下载远程文件，返回内容和检测到的 MIME 类型
+func (f *Fetcher) Fetch(rawURL string) (*Media, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	if err := f.checkURL(u); err != nil {
+		return nil, err
+	}
+
+	if f.cache != nil {
+		if m, ok := f.cache.get(rawURL); ok {
+			return m, nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), f.opts.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	req.Header.Set("User-Agent", "OpenBridge")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", u.Redacted(), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: HTTP %d", u.Redacted(), resp.StatusCode)
+	}
+	if resp.ContentLength > f.opts.MaxBytes {
+		return nil, fmt.Errorf("%s is larger than the %d byte limit", u.Redacted(), f.opts.MaxBytes)
+	}
+
+	// 多读一个字节以判断是否超过上限
+	data, err := io.ReadAll(io.LimitReader(resp.Body, f.opts.MaxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", u.Redacted(), err)
+	}
+	if int64(len(data)) > f.opts.MaxBytes {
+		return nil, fmt.Errorf("%s is larger than the %d byte limit", u.Redacted(), f.opts.MaxBytes)
+	}
+
+	m := &Media{
+		MimeType: detectMimeType(resp.Header.Get("Content-Type"), data),
+		Data:     data,
+	}
+	if f.cache != nil {
+		f.cache.put(rawURL, m)
+	}
+	return m, nil
+}
+
+// checkURL 校验协议和白名单
+func (f *Fetcher) checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "" {
+		return errors.New("URL has no host")
+	}
+	if len(f.opts.AllowedHosts) == 0 {
+		return nil
+	}
+	for _, pattern := range f.opts.AllowedHosts {
+		pattern = strings.ToLower(pattern)
+		if strings.HasPrefix(pattern, "*.") {
+			if strings.HasSuffix(host, pattern[1:]) {
+				return nil
+			}
+		} else if host == pattern {
+			return nil
+		}
+	}
+	return fmt.Errorf("host %q is not in the allowed hosts list", host)
+}
+
+// deniedPrefixes net.IP 的 IsPrivate 等方法没有覆盖、但同样不应被访问的地址段
+var deniedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // 本网络
+	netip.MustParsePrefix("100.64.0.0/10"),   // 运营商级 NAT（CGNAT），部分云的元数据服务（如 100.100.100.200）也在其中
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF 协议分配
+	netip.MustParsePrefix("192.0.2.0/24"),    // 文档示例（TEST-NET-1）
+	netip.MustParsePrefix("198.18.0.0/15"),   // 网络设备基准测试
+	netip.MustParsePrefix("198.51.100.0/24"), // 文档示例（TEST-NET-2）
+	netip.MustParsePrefix("203.0.113.0/24"),  // 文档示例（TEST-NET-3）
+	netip.MustParsePrefix("240.0.0.0/4"),     // 保留地址及广播地址
+	netip.MustParsePrefix("::/96"),           // 已废弃的 IPv4 兼容地址
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // 本地使用的 NAT64
+	netip.MustParsePrefix("100::/64"),        // 丢弃前缀
+	netip.MustParsePrefix("2001::/32"),       // Teredo
+	netip.MustParsePrefix("2001:db8::/32"),   // 文档示例
+}
+
+// 内嵌 IPv4 地址的 IPv6 前缀，按内嵌的 IPv4 地址判断
+var (
+	nat64Prefix = netip.MustParsePrefix("64:ff9b::/96") // NAT64，IPv4 地址在最后 32 位
+	sixToFour   = netip.MustParsePrefix("2002::/16")    // 6to4，IPv4 地址在第 16-48 位
+)
+
+// isPrivateIP 判断是否为内网、回环、链路本地等不应被访问的地址
+// IPv4 映射地址（::ffff:a.b.c.d）按 IPv4 地址判断；NAT64、6to4 地址按其中内嵌的 IPv4 地址判断
+func isPrivateIP(ip net.IP) bool {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return true
+	}
+	addr = addr.Unmap()
+
+	if addr.Is6() {
+		b := addr.As16()
+		switch {
+		case nat64Prefix.Contains(addr):
+			return isPrivateIP(net.IP(b[12:16]))
+		case sixToFour.Contains(addr):
+			return isPrivateIP(net.IP(b[2:6]))
+		}
+	}
+
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() || addr.IsUnspecified() {
+		return true
+	}
+	for _, prefix := range deniedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// detectMimeType 以内容嗅探为准，嗅探不出具体类型时使用响应头
+func detectMimeType(header string, data []byte) string {
+	sniffed := http.DetectContentType(data)
+	if sniffed != "application/octet-stream" && !strings.HasPrefix(sniffed, "text/plain") {
+		return stripParams(sniffed)
+	}
+	if header != "" {
+		return stripParams(header)
+	}
+	return stripParams(sniffed)
+}
+
+func stripParams(contentType string) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mediaType
+	}
+	return contentType
+}
+
+// ParseDataURI 解析 data:[<mediatype>][;base64],<data> 形式的 URI
+func ParseDataURI(uri string) (*Media, error) {
+	if !strings.HasPrefix(uri, "data:") {
+		return nil, errors.New("not a data URI")
+	}
+	header, payload, ok := strings.Cut(strings.TrimPrefix(uri, "data:"), ",")
+	if !ok {
+		return nil, errors.New("malformed data URI")
+	}
+
+	params := strings.Split(header, ";")
+	isBase64 := false
+	for _, p := range params[1:] {
+		if p == "base64" {
+			isBase64 = true
+		}
+	}
+
+	var data []byte
+	if isBase64 {
+		decoded, err := base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 in data URI: %w", err)
+		}
+		data = decoded
+	} else {
+		decoded, err := url.PathUnescape(payload)
+		if err != nil {
+			return nil, fmt.Errorf("invalid data URI: %w", err)
+		}
+		data = []byte(decoded)
+	}
+
+	return &Media{
+		MimeType: detectMimeType(params[0], data),
+		Data:     data,
+	}, nil
+}
+
+// cache 按 URL 缓存下载结果的 LRU
+type cache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key     string
+	media   *Media
+	expires time.Time
+}
+
+func newCache(size int, ttl time.Duration) *cache {
+	return &cache{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (c *cache) get(key string) (*Media, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return entry.media, true
+}
+
+func (c *cache) put(key string, m *Media) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		c.order.Remove(el)
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, media: m, expires: time.Now().Add(c.ttl)})
+
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
@@ -0,0 +1,49 @@
+package media
+
+import (
+	"net"
+	"testing"
+)
+
+func TestIsPrivateIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"0.0.0.0", true},
+		{"100.64.0.1", true},
+		{"100.100.100.200", true},
+		{"192.0.0.170", true},
+		{"198.18.0.1", true},
+		{"198.19.255.255", true},
+		{"240.0.0.1", true},
+		{"255.255.255.255", true},
+		{"::1", true},
+		{"fd00::1", true},
+		{"fe80::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"::ffff:100.100.100.200", true},
+		{"64:ff9b::a9fe:a9fe", true}, // NAT64 169.254.169.254
+		{"64:ff9b::7f00:1", true},    // NAT64 127.0.0.1
+		{"2002:c0a8:101::1", true},   // 6to4 192.168.1.1
+		{"2002:a00:1::", true},       // 6to4 10.0.0.1
+
+		{"8.8.8.8", false},
+		{"100.128.0.1", false},
+		{"198.20.0.1", false},
+		{"2606:4700:4700::1111", false},
+		{"::ffff:8.8.8.8", false},
+		{"64:ff9b::808:808", false}, // NAT64 8.8.8.8
+		{"2002:808:808::1", false},  // 6to4 8.8.8.8
+	}
+	for _, tt := range tests {
+		if got := isPrivateIP(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("isPrivateIP(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package media
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"path/filepath"
+	"strings"
+)
+
+// DecodeFileData 解码 file 内容块的 file_data
+// 支持 data URI 和纯 base64；MIME 类型优先使用 data URI / mimeType 声明，其次按内容嗅探，最后按文件扩展名推断
+func DecodeFileData(fileData, filename, mimeType string) (*Media, error) {
+	if fileData == "" {
+		return nil, errors.New("file_data is empty")
+	}
+
+	if strings.HasPrefix(fileData, "data:") {
+		m, err := ParseDataURI(fileData)
+		if err != nil {
+			return nil, err
+		}
+		if mimeType != "" {
+			m.MimeType = stripParams(mimeType)
+		}
+		return m, nil
+	}
+
+	data, err := base64.StdEncoding.DecodeString(fileData)
+	if err != nil {
+		return nil, fmt.Errorf("file_data is not valid base64: %w", err)
+	}
+
+	m := &Media{Data: data}
+	switch {
+	case mimeType != "":
+		m.MimeType = stripParams(mimeType)
+	default:
+		m.MimeType = detectMimeType(mime.TypeByExtension(strings.ToLower(filepath.Ext(filename))), data)
+	}
+	return m, nil
+}
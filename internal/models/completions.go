@@ -0,0 +1,112 @@
+package models
+
+import "encoding/json"
+
+// OpenAI 旧版 Completions API (POST /v1/completions)
+
+// CompletionRequest 文本补全请求
+type CompletionRequest struct {
+	Model            string             `json:"model"`
+	Prompt           json.RawMessage    `json:"prompt"` // string、string 数组、token 数组或 token 数组的数组
+	Suffix           string             `json:"suffix,omitempty"`
+	MaxTokens        int                `json:"max_tokens,omitempty"`
+	Temperature      *float64           `json:"temperature,omitempty"`
+	TopP             *float64           `json:"top_p,omitempty"`
+	N                int                `json:"n,omitempty"`
+	Stream           bool               `json:"stream,omitempty"`
+	StreamOptions    *StreamOptions     `json:"stream_options,omitempty"`
+	Logprobs         *int               `json:"logprobs,omitempty"` // 每个位置返回概率最高的 logprobs 个 token，最大 5
+	Echo             bool               `json:"echo,omitempty"`
+	Stop             any                `json:"stop,omitempty"` // string 或 []string
+	PresencePenalty  float64            `json:"presence_penalty,omitempty"`
+	FrequencyPenalty float64            `json:"frequency_penalty,omitempty"`
+	BestOf           int                `json:"best_of,omitempty"`
+	LogitBias        map[string]float64 `json:"logit_bias,omitempty"`
+	Seed             *int               `json:"seed,omitempty"`
+	User             string             `json:"user,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"` // 未建模的字段，原样透传
+}
+
+func (r *CompletionRequest) UnmarshalJSON(data []byte) error {
+	type alias CompletionRequest
+	return unmarshalWithExtra(data, (*alias)(r), &r.Extra)
+}
+
+func (r CompletionRequest) MarshalJSON() ([]byte, error) {
+	type alias CompletionRequest
+	return marshalWithExtra(alias(r), r.Extra)
+}
+
+// IncludeUsage 客户端是否要求在流的最后发送携带 usage 的 chunk
+func (r *CompletionRequest) IncludeUsage() bool {
+	return r.StreamOptions != nil && r.StreamOptions.IncludeUsage
+}
+
+// Prompts 将 prompt 拆分为文本，token 数组只有 OpenAI 兼容上游能处理
+func (r *CompletionRequest) Prompts() ([]string, error) {
+	items, err := splitInputs(r.Prompt, "prompt")
+	if err != nil {
+		return nil, err
+	}
+
+	prompts := make([]string, 0, len(items))
+	for _, item := range items {
+		var prompt string
+		if err := json.Unmarshal(item, &prompt); err != nil {
+			return nil, NewRequestError("prompt", "token array prompts are only supported by OpenAI-compatible upstreams")
+		}
+		prompts = append(prompts, prompt)
+	}
+	return prompts, nil
+}
+
+// CompletionResponse 文本补全响应，流式 chunk 使用相同的结构
+type CompletionResponse struct {
+	ID                string             `json:"id"`
+	Object            string             `json:"object"` // text_completion
+	Created           int64              `json:"created"`
+	Model             string             `json:"model"`
+	Choices           []CompletionChoice `json:"choices"`
+	Usage             *Usage             `json:"usage,omitempty"`
+	SystemFingerprint string             `json:"system_fingerprint,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"` // 未建模的字段，原样透传
+}
+
+func (v *CompletionResponse) UnmarshalJSON(data []byte) error {
+	type alias CompletionResponse
+	return unmarshalWithExtra(data, (*alias)(v), &v.Extra)
+}
+
+func (v CompletionResponse) MarshalJSON() ([]byte, error) {
+	type alias CompletionResponse
+	return marshalWithExtra(alias(v), v.Extra)
+}
+
+type CompletionChoice struct {
+	Text         string              `json:"text"`
+	Index        int                 `json:"index"`
+	Logprobs     *CompletionLogprobs `json:"logprobs"`
+	FinishReason *string             `json:"finish_reason"` // 流式中间的 chunk 为 null
+
+	Extra map[string]json.RawMessage `json:"-"` // 未建模的字段，原样透传
+}
+
+func (v *CompletionChoice) UnmarshalJSON(data []byte) error {
+	type alias CompletionChoice
+	return unmarshalWithExtra(data, (*alias)(v), &v.Extra)
+}
+
+func (v CompletionChoice) MarshalJSON() ([]byte, error) {
+	type alias CompletionChoice
+	return marshalWithExtra(alias(v), v.Extra)
+}
+
+// CompletionLogprobs 旧版 logprobs 格式，各数组按 token 一一对应
+type CompletionLogprobs struct {
+	Tokens        []string             `json:"tokens"`
+	TokenLogprobs []float64            `json:"token_logprobs"`
+	TopLogprobs   []map[string]float64 `json:"top_logprobs"`
+	TextOffset    []int                `json:"text_offset"` // token 在 text 中的字符位置
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+)
+
+// OpenAI Embeddings API (POST /v1/embeddings)
+
+// EmbeddingRequest 向量生成请求
+type EmbeddingRequest struct {
+	Model          string          `json:"model"`
+	Input          json.RawMessage `json:"input"`                     // string、string 数组、token 数组或 token 数组的数组
+	EncodingFormat string          `json:"encoding_format,omitempty"` // float（默认）或 base64
+	Dimensions     int             `json:"dimensions,omitempty"`
+	User           string          `json:"user,omitempty"`
+
+	// 非标准字段：Gemini 的 taskType，如 RETRIEVAL_QUERY、RETRIEVAL_DOCUMENT
+	TaskType string `json:"task_type,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"` // 未建模的字段，原样透传
+}
+
+func (r *EmbeddingRequest) UnmarshalJSON(data []byte) error {
+	type alias EmbeddingRequest
+	return unmarshalWithExtra(data, (*alias)(r), &r.Extra)
+}
+
+func (r EmbeddingRequest) MarshalJSON() ([]byte, error) {
+	type alias EmbeddingRequest
+	return marshalWithExtra(alias(r), r.Extra)
+}
+
+// Inputs 将 input 拆分为单独的输入，每一项是 JSON 字符串或 token 数组
+func (r *EmbeddingRequest) Inputs() ([]json.RawMessage, error) {
+	return splitInputs(r.Input, "input")
+}
+
+// splitInputs 拆分 string、string 数组、token 数组或 token 数组的数组形式的输入（embeddings 的 input、completions 的 prompt）
+func splitInputs(raw json.RawMessage, param string) ([]json.RawMessage, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil, NewRequestError(param, "%s is required", param)
+	}
+	if raw[0] == '"' {
+		return []json.RawMessage{raw}, nil
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, NewRequestError(param, "%s must be a string, an array of strings or an array of token arrays", param)
+	}
+	if len(items) == 0 {
+		return nil, NewRequestError(param, "%s must not be empty", param)
+	}
+	// 单个 token 数组，如 [1, 2, 3]
+	if first := bytes.TrimSpace(items[0]); len(first) > 0 && first[0] != '"' && first[0] != '[' {
+		return []json.RawMessage{raw}, nil
+	}
+	return items, nil
+}
+
+// WithInputs 返回 input 替换为 inputs 的请求副本，用于分批请求
+func (r *EmbeddingRequest) WithInputs(inputs []json.RawMessage) *EmbeddingRequest {
+	batch := *r
+	batch.Input, _ = json.Marshal(inputs)
+	return &batch
+}
+
+// EmbeddingResponse 向量生成响应
+type EmbeddingResponse struct {
+	Object string         `json:"object"` // list
+	Data   []Embedding    `json:"data"`
+	Model  string         `json:"model"`
+	Usage  EmbeddingUsage `json:"usage"`
+}
+
+// Embedding 单个输入的向量
+type Embedding struct {
+	Object    string          `json:"object"` // embedding
+	Index     int             `json:"index"`
+	Embedding json.RawMessage `json:"embedding"` // float 数组，encoding_format 为 base64 时为字符串
+}
+
+// NewEmbedding 按 encoding_format 编码向量，base64 与 OpenAI 相同：little-endian float32 序列
+func NewEmbedding(index int, values []float64, encodingFormat string) Embedding {
+	var data []byte
+	if encodingFormat == "base64" {
+		buf := make([]byte, 4*len(values))
+		for i, v := range values {
+			binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(float32(v)))
+		}
+		data, _ = json.Marshal(base64.StdEncoding.EncodeToString(buf))
+	} else {
+		if values == nil {
+			values = []float64{}
+		}
+		data, _ = json.Marshal(values)
+	}
+	return Embedding{Object: "embedding", Index: index, Embedding: data}
+}
+
+type EmbeddingUsage struct {
+	PromptTokens int  `json:"prompt_tokens"`
+	TotalTokens  int  `json:"total_tokens"`
+	Estimated    bool `json:"estimated,omitempty"` // 上游不返回 token 数，由网关用分词器估算（非 OpenAI 标准）
+}
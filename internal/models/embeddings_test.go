@@ -0,0 +1,57 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestEmbeddingInputs(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{`"hello"`, []string{`"hello"`}},
+		{`["a", "b"]`, []string{`"a"`, `"b"`}},
+		{`[1, 2, 3]`, []string{`[1, 2, 3]`}},
+		{`[[1, 2], [3]]`, []string{`[1, 2]`, `[3]`}},
+	}
+	for _, tt := range tests {
+		req := &EmbeddingRequest{Input: json.RawMessage(tt.input)}
+		inputs, err := req.Inputs()
+		if err != nil {
+			t.Errorf("Inputs(%s): %v", tt.input, err)
+			continue
+		}
+		if len(inputs) != len(tt.want) {
+			t.Errorf("Inputs(%s) = %s, want %v", tt.input, inputs, tt.want)
+			continue
+		}
+		for i := range inputs {
+			if string(inputs[i]) != tt.want[i] {
+				t.Errorf("Inputs(%s)[%d] = %s, want %s", tt.input, i, inputs[i], tt.want[i])
+			}
+		}
+	}
+
+	for _, input := range []string{``, `null`, `[]`, `{"a":1}`, `42`} {
+		req := &EmbeddingRequest{Input: json.RawMessage(input)}
+		_, err := req.Inputs()
+		var reqErr *RequestError
+		if !errors.As(err, &reqErr) || reqErr.Param != "input" {
+			t.Errorf("Inputs(%q) error = %v, want a RequestError for input", input, err)
+		}
+	}
+}
+
+func TestEmbeddingWithInputs(t *testing.T) {
+	req := &EmbeddingRequest{Model: "m", Input: json.RawMessage(`["a","b","c"]`)}
+	inputs, _ := req.Inputs()
+	batch := req.WithInputs(inputs[1:])
+	if string(batch.Input) != `["b","c"]` || batch.Model != "m" {
+		t.Errorf("batch = %+v", batch)
+	}
+	if string(req.Input) != `["a","b","c"]` {
+		t.Errorf("original input modified: %s", req.Input)
+	}
+}
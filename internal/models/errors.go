@@ -1,5 +1,7 @@
 package models
 
+import "fmt"
+
 // OpenAI Standard Error Response
 
 type ErrorResponse struct {
@@ -35,6 +37,9 @@ const (
 	ErrorCodeContextLengthExceeded = "context_length_exceeded"
 	ErrorCodeInvalidRequest        = "invalid_request"
 	ErrorCodeServerError           = "server_error"
+	ErrorCodeSchemaMismatch        = "response_schema_mismatch"
+	ErrorCodeContentFilter         = "content_filter"
+	ErrorCodeResponseNotFound      = "response_not_found"
 )
 
 // NewErrorResponse creates a standard OpenAI error response
@@ -47,3 +52,23 @@ func NewErrorResponse(message, errorType, code string) *ErrorResponse {
 		},
 	}
 }
+
+// RequestError 请求内容无法被目标 Provider 接受（参数不支持、格式错误等）
+// handler 会将其转换为 400 invalid_request_error，而不是等待上游返回不透明的错误
+type RequestError struct {
+	Param   string
+	Message string
+	Code    string // 为空时使用 invalid_request
+}
+
+func (e *RequestError) Error() string {
+	return e.Message
+}
+
+// NewRequestError 创建 RequestError，param 为出错的请求字段
+func NewRequestError(param, format string, args ...interface{}) *RequestError {
+	return &RequestError{
+		Param:   param,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
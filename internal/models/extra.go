@@ -0,0 +1,84 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// 未建模字段的无损透传
+// 带有 Extra 字段的结构体在反序列化时把未知字段原样保存到 Extra，序列化时再合并回去，
+// 这样 OpenAI 兼容上游可以收到客户端发送的所有字段，客户端也能收到上游返回的所有字段
+
+var knownFieldsCache sync.Map // reflect.Type -> map[string]bool
+
+// knownFields 返回结构体所有 JSON 字段名
+func knownFields(t reflect.Type) map[string]bool {
+	if cached, ok := knownFieldsCache.Load(t); ok {
+		return cached.(map[string]bool)
+	}
+
+	fields := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = true
+	}
+
+	knownFieldsCache.Store(t, fields)
+	return fields
+}
+
+// unmarshalWithExtra 反序列化到 v（结构体指针），未知字段写入 extra
+func unmarshalWithExtra(data []byte, v interface{}, extra *map[string]json.RawMessage) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	known := knownFields(reflect.TypeOf(v).Elem())
+	*extra = nil
+	for k, val := range raw {
+		if known[k] {
+			continue
+		}
+		if *extra == nil {
+			*extra = make(map[string]json.RawMessage)
+		}
+		(*extra)[k] = val
+	}
+	return nil
+}
+
+// marshalWithExtra 序列化 v 并合并 extra 中的字段（已建模字段优先）
+func marshalWithExtra(v interface{}, extra map[string]json.RawMessage) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+
+	var merged map[string]json.RawMessage
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return nil, err
+	}
+	known := knownFields(reflect.TypeOf(v))
+	for k, val := range extra {
+		if known[k] {
+			continue
+		}
+		merged[k] = val
+	}
+	return json.Marshal(merged)
+}
@@ -0,0 +1,67 @@
+package models
+
+import "encoding/json"
+
+// OpenAI Images API (POST /v1/images/generations)
+
+// ImageGenerationRequest 图片生成请求
+type ImageGenerationRequest struct {
+	Model          string `json:"model"`
+	Prompt         string `json:"prompt"`
+	N              int    `json:"n,omitempty"`
+	Size           string `json:"size,omitempty"` // 如 1024x1024、1792x1024，auto 由模型决定
+	Quality        string `json:"quality,omitempty"`
+	Style          string `json:"style,omitempty"`
+	ResponseFormat string `json:"response_format,omitempty"` // url 或 b64_json
+	OutputFormat   string `json:"output_format,omitempty"`   // png、jpeg、webp
+	User           string `json:"user,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"` // 未建模的字段，原样透传
+}
+
+func (r *ImageGenerationRequest) UnmarshalJSON(data []byte) error {
+	type alias ImageGenerationRequest
+	return unmarshalWithExtra(data, (*alias)(r), &r.Extra)
+}
+
+func (r ImageGenerationRequest) MarshalJSON() ([]byte, error) {
+	type alias ImageGenerationRequest
+	return marshalWithExtra(alias(r), r.Extra)
+}
+
+// ImageResponse 图片生成响应
+type ImageResponse struct {
+	Created int64       `json:"created"`
+	Data    []ImageData `json:"data"`
+
+	Extra map[string]json.RawMessage `json:"-"` // 未建模的字段（如 gpt-image 的 usage），原样透传
+}
+
+func (v *ImageResponse) UnmarshalJSON(data []byte) error {
+	type alias ImageResponse
+	return unmarshalWithExtra(data, (*alias)(v), &v.Extra)
+}
+
+func (v ImageResponse) MarshalJSON() ([]byte, error) {
+	type alias ImageResponse
+	return marshalWithExtra(alias(v), v.Extra)
+}
+
+// ImageData 单张图片，url 和 b64_json 二选一
+type ImageData struct {
+	URL           string `json:"url,omitempty"`
+	B64JSON       string `json:"b64_json,omitempty"`
+	RevisedPrompt string `json:"revised_prompt,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"` // 未建模的字段，原样透传
+}
+
+func (v *ImageData) UnmarshalJSON(data []byte) error {
+	type alias ImageData
+	return unmarshalWithExtra(data, (*alias)(v), &v.Extra)
+}
+
+func (v ImageData) MarshalJSON() ([]byte, error) {
+	type alias ImageData
+	return marshalWithExtra(alias(v), v.Extra)
+}
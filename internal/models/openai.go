@@ -1,13 +1,18 @@
 package models
 
+import (
+	"encoding/json"
+	"time"
+)
+
 // OpenAI Standard Request/Response Models
 
 type ChatCompletionRequest struct {
 	Model            string          `json:"model"`
 	Messages         []Message       `json:"messages"`
 	MaxTokens        int             `json:"max_tokens,omitempty"`
-	Temperature      float64         `json:"temperature,omitempty"`
-	TopP             float64         `json:"top_p,omitempty"`
+	Temperature      *float64        `json:"temperature,omitempty"`
+	TopP             *float64        `json:"top_p,omitempty"`
 	PresencePenalty  float64         `json:"presence_penalty,omitempty"`
 	FrequencyPenalty float64         `json:"frequency_penalty,omitempty"`
 	Stream           bool            `json:"stream,omitempty"`
@@ -18,27 +23,358 @@ type ChatCompletionRequest struct {
 	N                int             `json:"n,omitempty"`
 	Logprobs         bool            `json:"logprobs,omitempty"`
 	TopLogprobs      int             `json:"top_logprobs,omitempty"`
+
+	Stop                any                `json:"stop,omitempty"` // string 或 []string
+	Seed                *int               `json:"seed,omitempty"`
+	User                string             `json:"user,omitempty"`
+	LogitBias           map[string]float64 `json:"logit_bias,omitempty"`
+	ParallelToolCalls   *bool              `json:"parallel_tool_calls,omitempty"`
+	MaxCompletionTokens int                `json:"max_completion_tokens,omitempty"`
+	Metadata            map[string]string  `json:"metadata,omitempty"`
+	TopK                int                `json:"top_k,omitempty"`           // 非 OpenAI 标准，Claude / Gemini / Cohere 支持
+	SafetySettings      []SafetySetting    `json:"safety_settings,omitempty"` // 非 OpenAI 标准，Gemini 支持，覆盖 Provider 配置中同类别的设置
+
+	// 推理控制: reasoning_effort 为 OpenAI 标准，thinking 与 Claude 格式一致，两者同时存在时 thinking 优先
+	ReasoningEffort string          `json:"reasoning_effort,omitempty"` // none, minimal, low, medium, high
+	Thinking        *ThinkingConfig `json:"thinking,omitempty"`
+
+	// 输出模态，如 ["text", "audio"]；audio 为音频输出配置
+	Modalities []string     `json:"modalities,omitempty"`
+	Audio      *AudioConfig `json:"audio,omitempty"`
+
+	// 联网搜索：Gemini 映射为 googleSearch grounding，Claude 映射为 web_search 服务端工具
+	WebSearchOptions *WebSearchOptions `json:"web_search_options,omitempty"`
+
+	// Extra 未建模的字段，原样透传给 OpenAI 兼容上游
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+func (r *ChatCompletionRequest) UnmarshalJSON(data []byte) error {
+	type alias ChatCompletionRequest
+	return unmarshalWithExtra(data, (*alias)(r), &r.Extra)
+}
+
+func (r ChatCompletionRequest) MarshalJSON() ([]byte, error) {
+	type alias ChatCompletionRequest
+	return marshalWithExtra(alias(r), r.Extra)
+}
+
+// StopSequences 将 stop 统一为字符串数组
+func (r *ChatCompletionRequest) StopSequences() ([]string, error) {
+	switch v := r.Stop.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		if v == "" {
+			return nil, nil
+		}
+		return []string{v}, nil
+	case []string:
+		return v, nil
+	case []interface{}:
+		stops := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, NewRequestError("stop", "stop must be a string or an array of strings")
+			}
+			stops = append(stops, s)
+		}
+		return stops, nil
+	}
+	return nil, NewRequestError("stop", "stop must be a string or an array of strings")
+}
+
+// reasoningEffortBudgets reasoning_effort 对应的思考 token 预算
+var reasoningEffortBudgets = map[string]int{
+	"none":    0,
+	"minimal": 0,
+	"low":     1024,
+	"medium":  8192,
+	"high":    24576,
+}
+
+// ThinkingBudget 根据 thinking 或 reasoning_effort 返回思考 token 预算
+// 返回 nil 表示客户端未指定，0 表示关闭思考
+func (r *ChatCompletionRequest) ThinkingBudget() (*int, error) {
+	if r.Thinking != nil {
+		switch r.Thinking.Type {
+		case "disabled":
+			budget := 0
+			return &budget, nil
+		case "enabled", "":
+			if r.Thinking.BudgetTokens <= 0 {
+				return nil, NewRequestError("thinking.budget_tokens", "thinking.budget_tokens must be a positive integer")
+			}
+			budget := r.Thinking.BudgetTokens
+			return &budget, nil
+		default:
+			return nil, NewRequestError("thinking.type", "thinking.type must be \"enabled\" or \"disabled\"")
+		}
+	}
+
+	if r.ReasoningEffort == "" {
+		return nil, nil
+	}
+	budget, ok := reasoningEffortBudgets[r.ReasoningEffort]
+	if !ok {
+		return nil, NewRequestError("reasoning_effort", "unknown reasoning_effort %q (supported: none, minimal, low, medium, high)", r.ReasoningEffort)
+	}
+	return &budget, nil
+}
+
+// WantsAudio 请求是否要求音频输出
+func (r *ChatCompletionRequest) WantsAudio() bool {
+	for _, m := range r.Modalities {
+		if m == "audio" {
+			return true
+		}
+	}
+	return false
+}
+
+// MaxOutputTokens 返回输出 token 上限，max_completion_tokens 优先于 max_tokens
+func (r *ChatCompletionRequest) MaxOutputTokens() int {
+	if r.MaxCompletionTokens > 0 {
+		return r.MaxCompletionTokens
+	}
+	return r.MaxTokens
+}
+
+// ThinkingConfig 思考（扩展推理）配置
+type ThinkingConfig struct {
+	Type         string `json:"type"` // enabled 或 disabled
+	BudgetTokens int    `json:"budget_tokens,omitempty"`
+}
+
+// ThinkingBlock 模型返回的一段思考内容
+// 客户端在多轮对话中需要原样回传（Claude 要求带签名的 thinking 块出现在工具调用之前）
+type ThinkingBlock struct {
+	Type      string `json:"type"` // thinking 或 redacted_thinking
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Data      string `json:"data,omitempty"` // redacted_thinking 的加密内容
+}
+
+// CacheControl 提示缓存断点，格式与 Claude 一致
+// 可以出现在 message、content part（content 数组元素的 cache_control 字段）和 tool 上
+type CacheControl struct {
+	Type string `json:"type"`          // ephemeral
+	TTL  string `json:"ttl,omitempty"` // 5m（默认）或 1h
+}
+
+// ParseCacheControl 读取 content 数组元素上的 cache_control，没有时返回 nil
+func ParseCacheControl(part map[string]interface{}) *CacheControl {
+	ccMap, ok := part["cache_control"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	cc := &CacheControl{}
+	cc.Type, _ = ccMap["type"].(string)
+	cc.TTL, _ = ccMap["ttl"].(string)
+	if cc.Type == "" {
+		cc.Type = "ephemeral"
+	}
+	return cc
+}
+
+// AudioConfig 音频输出配置
+type AudioConfig struct {
+	Voice  string `json:"voice"`
+	Format string `json:"format"` // wav, mp3, flac, opus, pcm16
+}
+
+// AudioOutput 模型生成的音频，流式时各字段为增量
+type AudioOutput struct {
+	ID         string `json:"id,omitempty"`
+	Data       string `json:"data,omitempty"` // base64
+	ExpiresAt  int64  `json:"expires_at,omitempty"`
+	Transcript string `json:"transcript,omitempty"`
+}
+
+// AudioRef 多轮对话中引用之前生成的音频
+type AudioRef struct {
+	ID string `json:"id"`
+}
+
+// WebSearchOptions 联网搜索配置
+type WebSearchOptions struct {
+	SearchContextSize string        `json:"search_context_size,omitempty"` // low, medium, high
+	UserLocation      *UserLocation `json:"user_location,omitempty"`
+}
+
+// UserLocation 用于优化搜索结果的大致位置
+type UserLocation struct {
+	Type        string               `json:"type"` // approximate
+	Approximate *ApproximateLocation `json:"approximate,omitempty"`
+}
+
+type ApproximateLocation struct {
+	City     string `json:"city,omitempty"`
+	Country  string `json:"country,omitempty"` // ISO 3166-1 两位国家代码
+	Region   string `json:"region,omitempty"`
+	Timezone string `json:"timezone,omitempty"` // IANA 时区
+}
+
+// SafetySetting Gemini 安全过滤设置，例如 {"category": "HARM_CATEGORY_HARASSMENT", "threshold": "BLOCK_ONLY_HIGH"}
+type SafetySetting struct {
+	Category  string `json:"category"`
+	Threshold string `json:"threshold"`
 }
 
 type StreamOptions struct {
 	IncludeUsage bool `json:"include_usage,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"` // 未建模的字段，原样透传
+}
+
+func (v *StreamOptions) UnmarshalJSON(data []byte) error {
+	type alias StreamOptions
+	return unmarshalWithExtra(data, (*alias)(v), &v.Extra)
+}
+
+func (v StreamOptions) MarshalJSON() ([]byte, error) {
+	type alias StreamOptions
+	return marshalWithExtra(alias(v), v.Extra)
+}
+
+// IncludeUsage 客户端是否要求在流的最后发送携带 usage 的 chunk
+func (r *ChatCompletionRequest) IncludeUsage() bool {
+	return r.StreamOptions != nil && r.StreamOptions.IncludeUsage
+}
+
+// NewUsageChunk 创建流式响应最后的 usage chunk，choices 为空数组
+func NewUsageChunk(id string, model string, usage Usage) *ChatCompletionChunk {
+	return &ChatCompletionChunk{
+		ID:      id,
+		Object:  "chat.completion.chunk",
+		Created: time.Now().Unix(),
+		Model:   model,
+		Choices: []ChunkChoice{},
+		Usage:   &usage,
+	}
 }
 
 type ResponseFormat struct {
-	Type string `json:"type"` // "text" or "json_object"
+	Type       string      `json:"type"` // "text", "json_object" or "json_schema"
+	JSONSchema *JSONSchema `json:"json_schema,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"` // 未建模的字段，原样透传
+}
+
+func (v *ResponseFormat) UnmarshalJSON(data []byte) error {
+	type alias ResponseFormat
+	return unmarshalWithExtra(data, (*alias)(v), &v.Extra)
+}
+
+func (v ResponseFormat) MarshalJSON() ([]byte, error) {
+	type alias ResponseFormat
+	return marshalWithExtra(alias(v), v.Extra)
+}
+
+// JSONSchema response_format 为 json_schema 时的结构定义
+type JSONSchema struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Schema      map[string]any `json:"schema,omitempty"`
+	Strict      *bool          `json:"strict,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"` // 未建模的字段，原样透传
+}
+
+func (v *JSONSchema) UnmarshalJSON(data []byte) error {
+	type alias JSONSchema
+	return unmarshalWithExtra(data, (*alias)(v), &v.Extra)
+}
+
+func (v JSONSchema) MarshalJSON() ([]byte, error) {
+	type alias JSONSchema
+	return marshalWithExtra(alias(v), v.Extra)
 }
 
 type Message struct {
-	Role    string      `json:"role"`
-	Content interface{} `json:"content"` // 可以是 string 或 array
-	Name    string      `json:"name,omitempty"`
+	Role       string      `json:"role"`
+	Content    interface{} `json:"content"` // 可以是 string 或 array
+	Name       string      `json:"name,omitempty"`
+	ToolCalls  []ToolCall  `json:"tool_calls,omitempty"`   // assistant 发起的工具调用
+	ToolCallID string      `json:"tool_call_id,omitempty"` // role 为 tool 时对应的调用 ID
+
+	// assistant 消息回传的思考内容
+	ReasoningContent string          `json:"reasoning_content,omitempty"`
+	ThinkingBlocks   []ThinkingBlock `json:"thinking_blocks,omitempty"`
+
+	Audio *AudioRef `json:"audio,omitempty"` // assistant 消息引用之前生成的音频
+
+	CacheControl *CacheControl `json:"cache_control,omitempty"` // 非 OpenAI 标准，标记缓存断点（Claude prompt caching）
+
+	Extra map[string]json.RawMessage `json:"-"` // 未建模的字段，原样透传
+}
+
+func (v *Message) UnmarshalJSON(data []byte) error {
+	type alias Message
+	return unmarshalWithExtra(data, (*alias)(v), &v.Extra)
+}
+
+func (v Message) MarshalJSON() ([]byte, error) {
+	type alias Message
+	return marshalWithExtra(alias(v), v.Extra)
+}
+
+// IsSystem system 和 developer（新版 OpenAI 对 system 的称呼）都作为系统指令处理
+func (v Message) IsSystem() bool {
+	return v.Role == "system" || v.Role == "developer"
 }
 
 // ContentPart represents a part of multi-modal content
 type ContentPart struct {
-	Type     string    `json:"type"` // "text" or "image_url"
-	Text     string    `json:"text,omitempty"`
-	ImageURL *ImageURL `json:"image_url,omitempty"`
+	Type       string      `json:"type"` // "text", "image_url", "file" or "input_audio"
+	Text       string      `json:"text,omitempty"`
+	ImageURL   *ImageURL   `json:"image_url,omitempty"`
+	File       *File       `json:"file,omitempty"`
+	InputAudio *InputAudio `json:"input_audio,omitempty"`
+}
+
+// InputAudio 音频输入，data 为 base64
+type InputAudio struct {
+	Data   string `json:"data"`
+	Format string `json:"format"` // wav 或 mp3
+}
+
+// File 文件内容（如 PDF），file_data 为 data URI 或 base64
+type File struct {
+	FileData string `json:"file_data,omitempty"`
+	FileID   string `json:"file_id,omitempty"`
+	Filename string `json:"filename,omitempty"`
+	MimeType string `json:"mime_type,omitempty"` // 非 OpenAI 标准，file_data 为纯 base64 时可显式指定类型
+}
+
+// ParseFilePart 从 content 数组的 file 元素中读取文件信息，缺少 file 对象时返回 nil
+func ParseFilePart(part map[string]interface{}) *File {
+	fileMap, ok := part["file"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	f := &File{}
+	f.FileData, _ = fileMap["file_data"].(string)
+	f.FileID, _ = fileMap["file_id"].(string)
+	f.Filename, _ = fileMap["filename"].(string)
+	f.MimeType, _ = fileMap["mime_type"].(string)
+	return f
+}
+
+// ParseInputAudioPart 从 content 数组的 input_audio 元素中读取音频，缺少 input_audio 对象时返回 nil
+func ParseInputAudioPart(part map[string]interface{}) *InputAudio {
+	audioMap, ok := part["input_audio"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	audio := &InputAudio{}
+	audio.Data, _ = audioMap["data"].(string)
+	audio.Format, _ = audioMap["format"].(string)
+	return audio
 }
 
 type ImageURL struct {
@@ -47,14 +383,39 @@ type ImageURL struct {
 }
 
 type Tool struct {
-	Type     string             `json:"type"`
-	Function FunctionDefinition `json:"function"`
+	Type         string             `json:"type"`
+	Function     FunctionDefinition `json:"function"`
+	CacheControl *CacheControl      `json:"cache_control,omitempty"` // 非 OpenAI 标准，见 Message.CacheControl
+
+	Extra map[string]json.RawMessage `json:"-"` // 未建模的字段，原样透传
+}
+
+func (v *Tool) UnmarshalJSON(data []byte) error {
+	type alias Tool
+	return unmarshalWithExtra(data, (*alias)(v), &v.Extra)
+}
+
+func (v Tool) MarshalJSON() ([]byte, error) {
+	type alias Tool
+	return marshalWithExtra(alias(v), v.Extra)
 }
 
 type FunctionDefinition struct {
 	Name        string         `json:"name"`
 	Description string         `json:"description,omitempty"`
 	Parameters  map[string]any `json:"parameters"`
+
+	Extra map[string]json.RawMessage `json:"-"` // 未建模的字段，原样透传
+}
+
+func (v *FunctionDefinition) UnmarshalJSON(data []byte) error {
+	type alias FunctionDefinition
+	return unmarshalWithExtra(data, (*alias)(v), &v.Extra)
+}
+
+func (v FunctionDefinition) MarshalJSON() ([]byte, error) {
+	type alias FunctionDefinition
+	return marshalWithExtra(alias(v), v.Extra)
 }
 
 type ChatCompletionResponse struct {
@@ -64,6 +425,18 @@ type ChatCompletionResponse struct {
 	Model   string   `json:"model"`
 	Choices []Choice `json:"choices"`
 	Usage   Usage    `json:"usage"`
+
+	Extra map[string]json.RawMessage `json:"-"` // 未建模的字段，原样透传
+}
+
+func (v *ChatCompletionResponse) UnmarshalJSON(data []byte) error {
+	type alias ChatCompletionResponse
+	return unmarshalWithExtra(data, (*alias)(v), &v.Extra)
+}
+
+func (v ChatCompletionResponse) MarshalJSON() ([]byte, error) {
+	type alias ChatCompletionResponse
+	return marshalWithExtra(alias(v), v.Extra)
 }
 
 type Choice struct {
@@ -71,38 +444,268 @@ type Choice struct {
 	Message      ResponseMessage `json:"message"`
 	FinishReason string          `json:"finish_reason"`
 	Logprobs     *Logprobs       `json:"logprobs,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"` // 未建模的字段，原样透传
+}
+
+func (v *Choice) UnmarshalJSON(data []byte) error {
+	type alias Choice
+	return unmarshalWithExtra(data, (*alias)(v), &v.Extra)
+}
+
+func (v Choice) MarshalJSON() ([]byte, error) {
+	type alias Choice
+	return marshalWithExtra(alias(v), v.Extra)
 }
 
 type Logprobs struct {
 	Content []TokenLogprob `json:"content,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"` // 未建模的字段，原样透传
+}
+
+func (v *Logprobs) UnmarshalJSON(data []byte) error {
+	type alias Logprobs
+	return unmarshalWithExtra(data, (*alias)(v), &v.Extra)
+}
+
+func (v Logprobs) MarshalJSON() ([]byte, error) {
+	type alias Logprobs
+	return marshalWithExtra(alias(v), v.Extra)
 }
 
 type TokenLogprob struct {
+	Token       string       `json:"token"`
+	Logprob     float64      `json:"logprob"`
+	Bytes       []int        `json:"bytes"` // token 的 UTF-8 字节
+	TopLogprobs []TopLogprob `json:"top_logprobs"`
+
+	Extra map[string]json.RawMessage `json:"-"` // 未建模的字段，原样透传
+}
+
+func (v *TokenLogprob) UnmarshalJSON(data []byte) error {
+	type alias TokenLogprob
+	return unmarshalWithExtra(data, (*alias)(v), &v.Extra)
+}
+
+func (v TokenLogprob) MarshalJSON() ([]byte, error) {
+	type alias TokenLogprob
+	return marshalWithExtra(alias(v), v.Extra)
+}
+
+// Annotation 消息内容的标注，目前只有 url_citation（联网搜索引用）
+type Annotation struct {
+	Type        string       `json:"type"`
+	URLCitation *URLCitation `json:"url_citation,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"` // 未建模的字段，原样透传
+}
+
+func (v *Annotation) UnmarshalJSON(data []byte) error {
+	type alias Annotation
+	return unmarshalWithExtra(data, (*alias)(v), &v.Extra)
+}
+
+func (v Annotation) MarshalJSON() ([]byte, error) {
+	type alias Annotation
+	return marshalWithExtra(alias(v), v.Extra)
+}
+
+// URLCitation 引用的网页，start_index / end_index 为 content 中被引用文本的字符区间
+type URLCitation struct {
+	StartIndex int    `json:"start_index"`
+	EndIndex   int    `json:"end_index"`
+	URL        string `json:"url"`
+	Title      string `json:"title,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"` // 未建模的字段，原样透传
+}
+
+func (v *URLCitation) UnmarshalJSON(data []byte) error {
+	type alias URLCitation
+	return unmarshalWithExtra(data, (*alias)(v), &v.Extra)
+}
+
+func (v URLCitation) MarshalJSON() ([]byte, error) {
+	type alias URLCitation
+	return marshalWithExtra(alias(v), v.Extra)
+}
+
+// NewURLCitation 创建 url_citation 标注
+func NewURLCitation(start, end int, url, title string) Annotation {
+	return Annotation{
+		Type:        "url_citation",
+		URLCitation: &URLCitation{StartIndex: start, EndIndex: end, URL: url, Title: title},
+	}
+}
+
+// TopLogprob 某个位置上概率最高的候选 token 之一
+type TopLogprob struct {
 	Token   string  `json:"token"`
 	Logprob float64 `json:"logprob"`
+	Bytes   []int   `json:"bytes"`
+
+	Extra map[string]json.RawMessage `json:"-"` // 未建模的字段，原样透传
+}
+
+func (v *TopLogprob) UnmarshalJSON(data []byte) error {
+	type alias TopLogprob
+	return unmarshalWithExtra(data, (*alias)(v), &v.Extra)
+}
+
+func (v TopLogprob) MarshalJSON() ([]byte, error) {
+	type alias TopLogprob
+	return marshalWithExtra(alias(v), v.Extra)
+}
+
+// TokenBytes 返回 token 的 UTF-8 字节，用于填充 bytes 字段
+func TokenBytes(token string) []int {
+	b := make([]int, len(token))
+	for i := 0; i < len(token); i++ {
+		b[i] = int(token[i])
+	}
+	return b
 }
 
 type ResponseMessage struct {
 	Role      string     `json:"role"`
 	Content   string     `json:"content"`
 	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+
+	// 思考内容（Claude extended thinking / Gemini thinking）
+	ReasoningContent string          `json:"reasoning_content,omitempty"`
+	ThinkingBlocks   []ThinkingBlock `json:"thinking_blocks,omitempty"`
+
+	Audio *AudioOutput `json:"audio,omitempty"` // 音频输出（modalities 包含 audio 时）
+
+	// 联网搜索的引用来源；search_queries 非 OpenAI 标准，为 Gemini grounding 使用的搜索词
+	Annotations   []Annotation `json:"annotations,omitempty"`
+	SearchQueries []string     `json:"search_queries,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"` // 未建模的字段，原样透传
+}
+
+func (v *ResponseMessage) UnmarshalJSON(data []byte) error {
+	type alias ResponseMessage
+	return unmarshalWithExtra(data, (*alias)(v), &v.Extra)
+}
+
+func (v ResponseMessage) MarshalJSON() ([]byte, error) {
+	type alias ResponseMessage
+	return marshalWithExtra(alias(v), v.Extra)
 }
 
 type ToolCall struct {
-	ID       string       `json:"id"`
-	Type     string       `json:"type"`
+	Index    *int         `json:"index,omitempty"` // 仅在流式增量中使用
+	ID       string       `json:"id,omitempty"`
+	Type     string       `json:"type,omitempty"`
 	Function FunctionCall `json:"function"`
+
+	Extra map[string]json.RawMessage `json:"-"` // 未建模的字段，原样透传
+}
+
+func (v *ToolCall) UnmarshalJSON(data []byte) error {
+	type alias ToolCall
+	return unmarshalWithExtra(data, (*alias)(v), &v.Extra)
+}
+
+func (v ToolCall) MarshalJSON() ([]byte, error) {
+	type alias ToolCall
+	return marshalWithExtra(alias(v), v.Extra)
 }
 
 type FunctionCall struct {
 	Name      string `json:"name"`
 	Arguments string `json:"arguments"`
+
+	Extra map[string]json.RawMessage `json:"-"` // 未建模的字段，原样透传
+}
+
+func (v *FunctionCall) UnmarshalJSON(data []byte) error {
+	type alias FunctionCall
+	return unmarshalWithExtra(data, (*alias)(v), &v.Extra)
+}
+
+func (v FunctionCall) MarshalJSON() ([]byte, error) {
+	type alias FunctionCall
+	return marshalWithExtra(alias(v), v.Extra)
 }
 
 type Usage struct {
 	PromptTokens     int `json:"prompt_tokens"`
 	CompletionTokens int `json:"completion_tokens"`
 	TotalTokens      int `json:"total_tokens"`
+
+	PromptTokensDetails     *PromptTokensDetails     `json:"prompt_tokens_details,omitempty"`
+	CompletionTokensDetails *CompletionTokensDetails `json:"completion_tokens_details,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"` // 未建模的字段，原样透传
+}
+
+// Add 累加另一份 usage（合并多个上游请求的用量）
+func (v *Usage) Add(other Usage) {
+	v.PromptTokens += other.PromptTokens
+	v.CompletionTokens += other.CompletionTokens
+	v.TotalTokens += other.TotalTokens
+
+	if other.PromptTokensDetails != nil {
+		if v.PromptTokensDetails == nil {
+			v.PromptTokensDetails = &PromptTokensDetails{}
+		}
+		v.PromptTokensDetails.CachedTokens += other.PromptTokensDetails.CachedTokens
+		v.PromptTokensDetails.CacheCreationTokens += other.PromptTokensDetails.CacheCreationTokens
+	}
+	if other.CompletionTokensDetails != nil {
+		if v.CompletionTokensDetails == nil {
+			v.CompletionTokensDetails = &CompletionTokensDetails{}
+		}
+		v.CompletionTokensDetails.ReasoningTokens += other.CompletionTokensDetails.ReasoningTokens
+	}
+}
+
+func (v *Usage) UnmarshalJSON(data []byte) error {
+	type alias Usage
+	return unmarshalWithExtra(data, (*alias)(v), &v.Extra)
+}
+
+func (v Usage) MarshalJSON() ([]byte, error) {
+	type alias Usage
+	return marshalWithExtra(alias(v), v.Extra)
+}
+
+// PromptTokensDetails 输入 token 明细
+type PromptTokensDetails struct {
+	CachedTokens        int `json:"cached_tokens"`                   // 命中缓存的 token 数（已计入 prompt_tokens）
+	CacheCreationTokens int `json:"cache_creation_tokens,omitempty"` // 非 OpenAI 标准，写入缓存的 token 数（Claude）
+
+	Extra map[string]json.RawMessage `json:"-"` // 未建模的字段，原样透传
+}
+
+func (v *PromptTokensDetails) UnmarshalJSON(data []byte) error {
+	type alias PromptTokensDetails
+	return unmarshalWithExtra(data, (*alias)(v), &v.Extra)
+}
+
+func (v PromptTokensDetails) MarshalJSON() ([]byte, error) {
+	type alias PromptTokensDetails
+	return marshalWithExtra(alias(v), v.Extra)
+}
+
+// CompletionTokensDetails 输出 token 明细
+type CompletionTokensDetails struct {
+	ReasoningTokens int `json:"reasoning_tokens"`
+
+	Extra map[string]json.RawMessage `json:"-"` // 未建模的字段，原样透传
+}
+
+func (v *CompletionTokensDetails) UnmarshalJSON(data []byte) error {
+	type alias CompletionTokensDetails
+	return unmarshalWithExtra(data, (*alias)(v), &v.Extra)
+}
+
+func (v CompletionTokensDetails) MarshalJSON() ([]byte, error) {
+	type alias CompletionTokensDetails
+	return marshalWithExtra(alias(v), v.Extra)
 }
 
 // ChatCompletionChunk 流式响应块
@@ -113,18 +716,65 @@ type ChatCompletionChunk struct {
 	Model   string        `json:"model"`
 	Choices []ChunkChoice `json:"choices"`
 	Usage   *Usage        `json:"usage,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"` // 未建模的字段，原样透传
+}
+
+func (v *ChatCompletionChunk) UnmarshalJSON(data []byte) error {
+	type alias ChatCompletionChunk
+	return unmarshalWithExtra(data, (*alias)(v), &v.Extra)
+}
+
+func (v ChatCompletionChunk) MarshalJSON() ([]byte, error) {
+	type alias ChatCompletionChunk
+	return marshalWithExtra(alias(v), v.Extra)
 }
 
 type ChunkChoice struct {
-	Index        int          `json:"index"`
-	Delta        ChunkDelta   `json:"delta"`
-	FinishReason *string      `json:"finish_reason"`
+	Index        int        `json:"index"`
+	Delta        ChunkDelta `json:"delta"`
+	Logprobs     *Logprobs  `json:"logprobs,omitempty"`
+	FinishReason *string    `json:"finish_reason"`
+
+	Extra map[string]json.RawMessage `json:"-"` // 未建模的字段，原样透传
+}
+
+func (v *ChunkChoice) UnmarshalJSON(data []byte) error {
+	type alias ChunkChoice
+	return unmarshalWithExtra(data, (*alias)(v), &v.Extra)
+}
+
+func (v ChunkChoice) MarshalJSON() ([]byte, error) {
+	type alias ChunkChoice
+	return marshalWithExtra(alias(v), v.Extra)
 }
 
 type ChunkDelta struct {
 	Role      string     `json:"role,omitempty"`
 	Content   string     `json:"content,omitempty"`
 	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+
+	// 思考内容增量；thinking_blocks 在每个思考块结束时下发完整的块（含签名）
+	ReasoningContent string          `json:"reasoning_content,omitempty"`
+	ThinkingBlocks   []ThinkingBlock `json:"thinking_blocks,omitempty"`
+
+	Audio *AudioOutput `json:"audio,omitempty"` // 音频输出（modalities 包含 audio 时）
+
+	// 联网搜索的引用来源，索引相对于该 choice 的完整 content
+	Annotations   []Annotation `json:"annotations,omitempty"`
+	SearchQueries []string     `json:"search_queries,omitempty"`
+
+	Extra map[string]json.RawMessage `json:"-"` // 未建模的字段，原样透传
+}
+
+func (v *ChunkDelta) UnmarshalJSON(data []byte) error {
+	type alias ChunkDelta
+	return unmarshalWithExtra(data, (*alias)(v), &v.Extra)
+}
+
+func (v ChunkDelta) MarshalJSON() ([]byte, error) {
+	type alias ChunkDelta
+	return marshalWithExtra(alias(v), v.Extra)
 }
 
 // ModelList 模型列表响应
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// 透传：显式的零值和未建模的嵌套字段在反序列化再序列化后保持不变
+func TestChatCompletionRequestRoundTrip(t *testing.T) {
+	const input = `{
+		"model": "gpt-4o",
+		"temperature": 0,
+		"top_p": 0,
+		"stream": true,
+		"stream_options": {"include_usage": true, "include_obfuscation": false},
+		"response_format": {"type": "json_schema", "future_option": 1,
+			"json_schema": {"name": "answer", "schema": {"type": "object"}, "strict": true, "x_hint": "a"}},
+		"messages": [
+			{"role": "assistant", "content": null, "tool_calls": [
+				{"id": "call_1", "type": "function", "x_call": true,
+				 "function": {"name": "f", "arguments": "{}", "x_fn": 2}}
+			]}
+		]
+	}`
+
+	var req ChatCompletionRequest
+	if err := json.Unmarshal([]byte(input), &req); err != nil {
+		t.Fatal(err)
+	}
+	if req.Temperature == nil || *req.Temperature != 0 || req.TopP == nil || *req.TopP != 0 {
+		t.Errorf("temperature = %v, top_p = %v, want explicit zeros", req.Temperature, req.TopP)
+	}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got, want map[string]any
+	json.Unmarshal(data, &got)
+	json.Unmarshal([]byte(input), &want)
+	gotJSON, _ := json.Marshal(got)
+	wantJSON, _ := json.Marshal(want)
+	if string(gotJSON) != string(wantJSON) {
+		t.Errorf("round trip changed the request:\n got %s\nwant %s", gotJSON, wantJSON)
+	}
+}
@@ -0,0 +1,247 @@
+package models
+
+import "encoding/json"
+
+// OpenAI Responses API (POST /v1/responses)
+// 请求会被转换为 ChatCompletionRequest 交给任意 Provider 处理，结果再转换回 Response
+
+// ResponsesRequest Responses API 请求
+type ResponsesRequest struct {
+	Model              string              `json:"model"`
+	Input              json.RawMessage     `json:"input"` // string 或 input item 数组
+	Instructions       string              `json:"instructions,omitempty"`
+	Tools              []ResponsesTool     `json:"tools,omitempty"`
+	ToolChoice         any                 `json:"tool_choice,omitempty"` // "auto" / "none" / "required" 或 {"type": "function", "name": ...}
+	ParallelToolCalls  *bool               `json:"parallel_tool_calls,omitempty"`
+	Temperature        *float64            `json:"temperature,omitempty"`
+	TopP               *float64            `json:"top_p,omitempty"`
+	MaxOutputTokens    int                 `json:"max_output_tokens,omitempty"`
+	Stream             bool                `json:"stream,omitempty"`
+	Store              *bool               `json:"store,omitempty"` // 默认 true
+	PreviousResponseID string              `json:"previous_response_id,omitempty"`
+	Metadata           map[string]string   `json:"metadata,omitempty"`
+	Text               *ResponsesText      `json:"text,omitempty"`
+	Reasoning          *ResponsesReasoning `json:"reasoning,omitempty"`
+	User               string              `json:"user,omitempty"`
+}
+
+// ShouldStore 是否保存响应以便通过 previous_response_id 继续对话
+func (r *ResponsesRequest) ShouldStore() bool {
+	return r.Store == nil || *r.Store
+}
+
+// ResponsesTool Responses API 工具，function 工具的字段直接位于顶层
+type ResponsesTool struct {
+	Type        string         `json:"type"` // function、web_search、web_search_preview
+	Name        string         `json:"name,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters,omitempty"`
+	Strict      *bool          `json:"strict,omitempty"`
+
+	UserLocation *UserLocation `json:"user_location,omitempty"` // web_search
+}
+
+// ResponsesText 文本输出配置
+type ResponsesText struct {
+	Format *ResponsesTextFormat `json:"format,omitempty"`
+}
+
+// ResponsesTextFormat 输出格式，json_schema 的字段直接位于顶层
+type ResponsesTextFormat struct {
+	Type        string         `json:"type"` // text、json_object、json_schema
+	Name        string         `json:"name,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Schema      map[string]any `json:"schema,omitempty"`
+	Strict      *bool          `json:"strict,omitempty"`
+}
+
+// ResponsesReasoning 推理配置
+type ResponsesReasoning struct {
+	Effort  string `json:"effort,omitempty"`
+	Summary string `json:"summary,omitempty"`
+}
+
+// ResponseInputItem input 数组中的一项
+type ResponseInputItem struct {
+	Type    string          `json:"type,omitempty"` // message（可省略）、function_call、function_call_output、reasoning
+	Role    string          `json:"role,omitempty"`
+	Content json.RawMessage `json:"content,omitempty"` // string 或 ResponseInputContent 数组
+
+	// function_call / function_call_output
+	ID        string          `json:"id,omitempty"`
+	CallID    string          `json:"call_id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Arguments string          `json:"arguments,omitempty"`
+	Output    json.RawMessage `json:"output,omitempty"` // string 或 ResponseInputContent 数组
+}
+
+// ResponseInputContent message 的内容块
+type ResponseInputContent struct {
+	Type     string `json:"type"` // input_text、output_text、input_image、input_file
+	Text     string `json:"text,omitempty"`
+	ImageURL string `json:"image_url,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	FileID   string `json:"file_id,omitempty"`
+	FileData string `json:"file_data,omitempty"`
+	Filename string `json:"filename,omitempty"`
+}
+
+// Response Responses API 响应
+type Response struct {
+	ID                 string               `json:"id"`
+	Object             string               `json:"object"` // response
+	CreatedAt          int64                `json:"created_at"`
+	Status             string               `json:"status"` // in_progress、completed、incomplete、failed
+	Model              string               `json:"model"`
+	Output             []ResponseOutputItem `json:"output"`
+	Usage              *ResponseUsage       `json:"usage"`
+	Error              *ResponseError       `json:"error"`
+	IncompleteDetails  *IncompleteDetails   `json:"incomplete_details"`
+	Instructions       string               `json:"instructions,omitempty"`
+	PreviousResponseID string               `json:"previous_response_id,omitempty"`
+	Metadata           map[string]string    `json:"metadata"`
+	Tools              []ResponsesTool      `json:"tools"`
+	ToolChoice         any                  `json:"tool_choice"`
+	ParallelToolCalls  bool                 `json:"parallel_tool_calls"`
+	Temperature        *float64             `json:"temperature,omitempty"`
+	TopP               *float64             `json:"top_p,omitempty"`
+	MaxOutputTokens    int                  `json:"max_output_tokens,omitempty"`
+	Text               *ResponsesText       `json:"text,omitempty"`
+	Store              bool                 `json:"store"`
+}
+
+// ResponseOutputItem output 数组中的一项：message、reasoning 或 function_call
+type ResponseOutputItem struct {
+	Type   string `json:"type"`
+	ID     string `json:"id"`
+	Status string `json:"status,omitempty"` // in_progress、completed、incomplete
+
+	// message
+	Role    string                `json:"role,omitempty"`
+	Content []ResponseContentPart `json:"content,omitempty"`
+
+	// reasoning
+	Summary []ResponseContentPart `json:"summary,omitempty"`
+
+	// function_call
+	CallID    string `json:"call_id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+}
+
+// MarshalJSON message 的 content 和 reasoning 的 summary 为空时也输出空数组
+func (v ResponseOutputItem) MarshalJSON() ([]byte, error) {
+	type alias ResponseOutputItem
+	switch v.Type {
+	case "message":
+		return json.Marshal(struct {
+			alias
+			Content []ResponseContentPart `json:"content"`
+		}{alias(v), nonNilParts(v.Content)})
+	case "reasoning":
+		return json.Marshal(struct {
+			alias
+			Summary []ResponseContentPart `json:"summary"`
+		}{alias(v), nonNilParts(v.Summary)})
+	case "function_call":
+		return json.Marshal(struct {
+			alias
+			Arguments string `json:"arguments"`
+		}{alias(v), v.Arguments})
+	}
+	return json.Marshal(alias(v))
+}
+
+func nonNilParts(parts []ResponseContentPart) []ResponseContentPart {
+	if parts == nil {
+		return []ResponseContentPart{}
+	}
+	return parts
+}
+
+// ResponseContentPart 输出内容块：output_text（message）或 summary_text（reasoning）
+type ResponseContentPart struct {
+	Type        string               `json:"type"`
+	Text        string               `json:"text"`
+	Annotations []ResponseAnnotation `json:"annotations,omitempty"`
+}
+
+// MarshalJSON output_text 总是包含 annotations 数组
+func (v ResponseContentPart) MarshalJSON() ([]byte, error) {
+	type alias ResponseContentPart
+	if v.Type != "output_text" {
+		return json.Marshal(alias(v))
+	}
+	annotations := v.Annotations
+	if annotations == nil {
+		annotations = []ResponseAnnotation{}
+	}
+	return json.Marshal(struct {
+		alias
+		Annotations []ResponseAnnotation `json:"annotations"`
+	}{alias(v), annotations})
+}
+
+// ResponseAnnotation output_text 的标注，字段与 Chat Completions 的 url_citation 相同但位于顶层
+type ResponseAnnotation struct {
+	Type       string `json:"type"` // url_citation
+	StartIndex int    `json:"start_index"`
+	EndIndex   int    `json:"end_index"`
+	URL        string `json:"url"`
+	Title      string `json:"title,omitempty"`
+}
+
+// ResponseUsage Responses API 的 token 用量
+type ResponseUsage struct {
+	InputTokens         int                 `json:"input_tokens"`
+	OutputTokens        int                 `json:"output_tokens"`
+	TotalTokens         int                 `json:"total_tokens"`
+	InputTokensDetails  InputTokensDetails  `json:"input_tokens_details"`
+	OutputTokensDetails OutputTokensDetails `json:"output_tokens_details"`
+}
+
+type InputTokensDetails struct {
+	CachedTokens int `json:"cached_tokens"`
+}
+
+type OutputTokensDetails struct {
+	ReasoningTokens int `json:"reasoning_tokens"`
+}
+
+// ResponseError status 为 failed 时的错误
+type ResponseError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// IncompleteDetails status 为 incomplete 时的原因：max_output_tokens 或 content_filter
+type IncompleteDetails struct {
+	Reason string `json:"reason"`
+}
+
+// ResponseStreamEvent Responses API 流式事件，SSE 的 event 字段与 type 相同
+type ResponseStreamEvent struct {
+	Type           string `json:"type"`
+	SequenceNumber int    `json:"sequence_number"`
+
+	Response *Response `json:"response,omitempty"` // response.created / in_progress / completed / incomplete / failed
+
+	OutputIndex  *int                 `json:"output_index,omitempty"`
+	ItemID       string               `json:"item_id,omitempty"`
+	ContentIndex *int                 `json:"content_index,omitempty"`
+	SummaryIndex *int                 `json:"summary_index,omitempty"`
+	Item         *ResponseOutputItem  `json:"item,omitempty"`
+	Part         *ResponseContentPart `json:"part,omitempty"`
+
+	Delta      string              `json:"delta,omitempty"`
+	Text       string              `json:"text,omitempty"`      // output_text.done / reasoning_summary_text.done
+	Arguments  string              `json:"arguments,omitempty"` // function_call_arguments.done
+	Annotation *ResponseAnnotation `json:"annotation,omitempty"`
+
+	AnnotationIndex *int `json:"annotation_index,omitempty"` // output_text.annotation.added
+
+	// error
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+	Param   string `json:"param,omitempty"`
+}
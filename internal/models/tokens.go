@@ -0,0 +1,9 @@
+package models
+
+// TokenCountResponse POST /v1/chat/completions/count_tokens 的响应（非 OpenAI 标准）
+type TokenCountResponse struct {
+	Object      string `json:"object"` // token_count
+	Model       string `json:"model"`
+	InputTokens int    `json:"input_tokens"`
+	Estimated   bool   `json:"estimated,omitempty"` // 没有对应的分词器，按长度估算
+}
@@ -3,13 +3,18 @@ package anthropic
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"openbridge/internal/config"
 	"openbridge/internal/models"
+	"openbridge/internal/provider"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,9 +22,11 @@ import (
 
 // Provider Claude (Anthropic) 原生 API 提供商实现
 type Provider struct {
-	name    string
-	baseURL string
-	version string
+	name      string
+	baseURL   string
+	version   string
+	convert   ConvertOptions
+	transport http.RoundTripper // 为 nil 时使用 http.DefaultTransport
 }
 
 // New 创建新的 Anthropic Provider
@@ -37,6 +44,33 @@ func New(name, baseURL string) *Provider {
 	}
 }
 
+// Options Anthropic Provider 专属选项
+type Options struct {
+	Version        string `yaml:"version"`          // anthropic-version 请求头，默认 2023-06-01
+	ImageURLSource bool   `yaml:"image_url_source"` // 远程图片 URL 直接交给 Claude 下载
+}
+
+func init() {
+	provider.RegisterFactory(func(name string, cfg config.ProviderConfig) (provider.Provider, error) {
+		var opts Options
+		if err := cfg.DecodeOptions(&opts); err != nil {
+			return nil, err
+		}
+
+		p := New(name, cfg.BaseURL)
+		if opts.Version != "" {
+			p.version = opts.Version
+		}
+		p.convert.ImageURLSource = opts.ImageURLSource
+		return p, nil
+	}, "anthropic", "claude")
+}
+
+// SetTransport 替换访问上游使用的 http.RoundTripper
+func (p *Provider) SetTransport(rt http.RoundTripper) {
+	p.transport = rt
+}
+
 func (p *Provider) Name() string {
 	return p.name
 }
@@ -49,10 +83,29 @@ func (p *Provider) SupportsStreaming() bool {
 	return true
 }
 
-// ChatCompletion 发送非流式聊天请求
+// maxChoices n 的上限，Claude 不支持多个候选，每个 choice 都是一次独立的上游请求
+const maxChoices = 8
+
+// choiceCount 返回请求的 choice 数量
+func choiceCount(req *models.ChatCompletionRequest) (int, error) {
+	if req.N > maxChoices {
+		return 0, models.NewRequestError("n", "n must be at most %d for Claude models, got %d", maxChoices, req.N)
+	}
+	if req.N < 1 {
+		return 1, nil
+	}
+	return req.N, nil
+}
+
+// ChatCompletion 发送非流式聊天请求，n > 1 时并行发送 n 个请求并合并 choices
 func (p *Provider) ChatCompletion(req *models.ChatCompletionRequest, apiKey string) (*models.ChatCompletionResponse, error) {
+	n, err := choiceCount(req)
+	if err != nil {
+		return nil, err
+	}
+
 	// 转换为 Claude 格式
-	claudeReq, err := ConvertFromOpenAI(req)
+	claudeReq, err := ConvertFromOpenAI(req, p.convert)
 	if err != nil {
 		return nil, fmt.Errorf("failed to convert request: %w", err)
 	}
@@ -64,6 +117,39 @@ func (p *Provider) ChatCompletion(req *models.ChatCompletionRequest, apiKey stri
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
+	responses := make([]*ChatResponse, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			responses[i], errs[i] = p.createMessage(reqBody, apiKey)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// 转换为 OpenAI 格式，按请求顺序编号 choices 并累加 usage
+	openaiResp := ConvertToOpenAI(responses[0], req.Model, ResponseToolName(req))
+	for i, claudeResp := range responses[1:] {
+		other := ConvertToOpenAI(claudeResp, req.Model, ResponseToolName(req))
+		choice := other.Choices[0]
+		choice.Index = i + 1
+		openaiResp.Choices = append(openaiResp.Choices, choice)
+		openaiResp.Usage.Add(other.Usage)
+	}
+	openaiResp.Created = time.Now().Unix()
+
+	return openaiResp, nil
+}
+
+// createMessage 发送一次非流式 /v1/messages 请求
+func (p *Provider) createMessage(reqBody []byte, apiKey string) (*ChatResponse, error) {
 	url := p.baseURL + "/v1/messages"
 	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(reqBody))
 	if err != nil {
@@ -74,7 +160,7 @@ func (p *Provider) ChatCompletion(req *models.ChatCompletionRequest, apiKey stri
 	httpReq.Header.Set("x-api-key", apiKey)
 	httpReq.Header.Set("anthropic-version", p.version)
 
-	client := &http.Client{Timeout: 120 * time.Second}
+	client := &http.Client{Timeout: 120 * time.Second, Transport: p.transport}
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
@@ -87,34 +173,17 @@ func (p *Provider) ChatCompletion(req *models.ChatCompletionRequest, apiKey stri
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		// 尝试解析错误响应
-		var errResp ErrorResponse
-		if err := json.Unmarshal(body, &errResp); err == nil {
-			return nil, &APIError{
-				StatusCode: resp.StatusCode,
-				Message:    errResp.Error.Message,
-				Type:       errResp.Error.Type,
-			}
-		}
-		return nil, &APIError{
-			StatusCode: resp.StatusCode,
-			Message:    string(body),
-		}
+		return nil, newAPIError(resp.StatusCode, body)
 	}
 
 	var claudeResp ChatResponse
 	if err := json.Unmarshal(body, &claudeResp); err != nil {
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
-
-	// 转换为 OpenAI 格式
-	openaiResp := ConvertToOpenAI(&claudeResp, req.Model)
-	openaiResp.Created = time.Now().Unix()
-
-	return openaiResp, nil
+	return &claudeResp, nil
 }
 
-// ChatCompletionStream 发送流式聊天请求
+// ChatCompletionStream 发送流式聊天请求，n > 1 时并行发送 n 个流式请求，chunk 按 choice 序号交错下发
 func (p *Provider) ChatCompletionStream(req *models.ChatCompletionRequest, apiKey string) (<-chan *models.ChatCompletionChunk, <-chan error) {
 	chunkChan := make(chan *models.ChatCompletionChunk, 100)
 	errChan := make(chan error, 1)
@@ -123,8 +192,14 @@ func (p *Provider) ChatCompletionStream(req *models.ChatCompletionRequest, apiKe
 		defer close(chunkChan)
 		defer close(errChan)
 
+		n, err := choiceCount(req)
+		if err != nil {
+			errChan <- err
+			return
+		}
+
 		// 转换为 Claude 格式
-		claudeReq, err := ConvertFromOpenAI(req)
+		claudeReq, err := ConvertFromOpenAI(req, p.convert)
 		if err != nil {
 			errChan <- fmt.Errorf("failed to convert request: %w", err)
 			return
@@ -138,97 +213,122 @@ func (p *Provider) ChatCompletionStream(req *models.ChatCompletionRequest, apiKe
 			return
 		}
 
-		url := p.baseURL + "/v1/messages"
-		httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(reqBody))
-		if err != nil {
-			errChan <- fmt.Errorf("failed to create request: %w", err)
-			return
-		}
+		// 生成唯一的 chunk ID，所有 choice 共用
+		chunkID := "chatcmpl-" + uuid.New().String()
 
-		httpReq.Header.Set("Content-Type", "application/json")
-		httpReq.Header.Set("x-api-key", apiKey)
-		httpReq.Header.Set("anthropic-version", p.version)
-		httpReq.Header.Set("Accept", "text/event-stream")
+		// 任一请求失败时取消其余请求
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		converters := make([]*StreamConverter, n)
+		errs := make([]error, n)
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			converters[i] = NewStreamConverter(chunkID, req.Model, ResponseToolName(req))
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				errs[i] = p.streamMessage(ctx, reqBody, apiKey, converters[i], func(chunk *models.ChatCompletionChunk) {
+					for j := range chunk.Choices {
+						chunk.Choices[j].Index = i
+					}
+					chunkChan <- chunk
+				})
+				if errs[i] != nil {
+					cancel()
+				}
+			}(i)
+		}
+		wg.Wait()
 
-		client := &http.Client{Timeout: 120 * time.Second}
-		resp, err := client.Do(httpReq)
-		if err != nil {
-			errChan <- fmt.Errorf("failed to send request: %w", err)
-			return
+		for _, err := range errs {
+			// 被取消的请求不是根因，返回第一个真正的错误
+			if err != nil && !errors.Is(err, context.Canceled) {
+				errChan <- err
+				return
+			}
 		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			var errResp ErrorResponse
-			if err := json.Unmarshal(body, &errResp); err == nil {
-				errChan <- &APIError{
-					StatusCode: resp.StatusCode,
-					Message:    errResp.Error.Message,
-					Type:       errResp.Error.Type,
-				}
-			} else {
-				errChan <- &APIError{
-					StatusCode: resp.StatusCode,
-					Message:    string(body),
-				}
+
+		if req.IncludeUsage() {
+			var usage models.Usage
+			for _, converter := range converters {
+				usage.Add(converter.Usage())
 			}
-			return
+			chunkChan <- models.NewUsageChunk(chunkID, req.Model, usage)
 		}
+	}()
 
-		// 生成唯一的 chunk ID
-		chunkID := "chatcmpl-" + uuid.New().String()
+	return chunkChan, errChan
+}
 
-		// 解析 SSE 流
-		scanner := bufio.NewScanner(resp.Body)
-		for scanner.Scan() {
-			line := scanner.Text()
+// streamMessage 发送一次流式 /v1/messages 请求，将转换后的 chunk 交给 emit，直到 message_stop
+func (p *Provider) streamMessage(ctx context.Context, reqBody []byte, apiKey string, converter *StreamConverter, emit func(*models.ChatCompletionChunk)) error {
+	url := p.baseURL + "/v1/messages"
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
 
-			if line == "" {
-				continue
-			}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", apiKey)
+	httpReq.Header.Set("anthropic-version", p.version)
+	httpReq.Header.Set("Accept", "text/event-stream")
 
-			// Claude 的 SSE 格式: "event: xxx" 和 "data: xxx"
-			if strings.HasPrefix(line, "event: ") {
-				// 事件类型，暂时忽略
-				continue
-			}
+	client := &http.Client{Timeout: 120 * time.Second, Transport: p.transport}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
 
-			if !strings.HasPrefix(line, "data: ") {
-				continue
-			}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return newAPIError(resp.StatusCode, body)
+	}
 
-			data := strings.TrimPrefix(line, "data: ")
+	// 解析 SSE 流
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
 
-			var event StreamEvent
-			if err := json.Unmarshal([]byte(data), &event); err != nil {
-				log.Printf("Failed to parse Claude stream event: %v, data: %s", err, data)
-				continue
-			}
+		if line == "" {
+			continue
+		}
 
-			// 转换为 OpenAI 格式的 chunk
-			chunk := ConvertStreamEventToChunk(&event, chunkID, req.Model)
-			chunk.Created = time.Now().Unix()
+		// Claude 的 SSE 格式: "event: xxx" 和 "data: xxx"
+		if strings.HasPrefix(line, "event: ") {
+			// 事件类型，暂时忽略
+			continue
+		}
 
-			// 只发送有内容的 chunk
-			if chunk.Choices[0].Delta.Role != "" || 
-			   chunk.Choices[0].Delta.Content != "" || 
-			   chunk.Choices[0].FinishReason != nil {
-				chunkChan <- chunk
-			}
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
 
-			// 如果是结束事件，退出
-			if event.Type == "message_stop" {
-				return
-			}
+		data := strings.TrimPrefix(line, "data: ")
+
+		var event StreamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			log.Printf("Failed to parse Claude stream event: %v, data: %s", err, data)
+			continue
 		}
 
-		if err := scanner.Err(); err != nil {
-			errChan <- fmt.Errorf("stream read error: %w", err)
+		// 转换为 OpenAI 格式的 chunk，只发送有内容的 chunk
+		if chunk := converter.Convert(&event); chunk != nil {
+			chunk.Created = time.Now().Unix()
+			emit(chunk)
 		}
-	}()
 
-	return chunkChan, errChan
+		// 如果是结束事件，退出
+		if event.Type == "message_stop" {
+			return nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("stream read error: %w", err)
+	}
+	return nil
 }
 
 // ListModels 获取模型列表
@@ -263,6 +363,27 @@ type APIError struct {
 	Type       string
 }
 
+// HTTPStatus 返回上游 HTTP 状态码，供 handler 原样返回给客户端
+func (e *APIError) HTTPStatus() int {
+	return e.StatusCode
+}
+
+// newAPIError 解析上游错误响应
+func newAPIError(statusCode int, body []byte) *APIError {
+	var errResp ErrorResponse
+	if err := json.Unmarshal(body, &errResp); err == nil {
+		return &APIError{
+			StatusCode: statusCode,
+			Message:    errResp.Error.Message,
+			Type:       errResp.Error.Type,
+		}
+	}
+	return &APIError{
+		StatusCode: statusCode,
+		Message:    string(body),
+	}
+}
+
 func (e *APIError) Error() string {
 	if e.Type != "" {
 		return fmt.Sprintf("Claude API error (status %d, type %s): %s", e.StatusCode, e.Type, e.Message)
@@ -0,0 +1,116 @@
+package anthropic
+
+import (
+	"encoding/json"
+	"openbridge/internal/cassette"
+	"openbridge/internal/models"
+	"strings"
+	"testing"
+)
+
+// 回放 testdata/cassettes 中录制的 /v1/messages 交互，校验请求转换（匹配键包含转换后的请求体）和响应转换
+// 转换逻辑变化导致请求体不同时回放会失败，需要重新录制
+
+const toolCallRequest = `{
+	"model": "claude-3-5-haiku-20241022",
+	"messages": [
+		{"role": "system", "content": "You are a weather assistant."},
+		{"role": "user", "content": "What's the weather in Paris?"}
+	],
+	"max_tokens": 256,
+	"tools": [{"type": "function", "function": {
+		"name": "get_weather",
+		"description": "Get the current weather for a city",
+		"parameters": {"type": "object", "properties": {"city": {"type": "string"}}, "required": ["city"]}
+	}}]
+}`
+
+const streamRequest = `{
+	"model": "claude-3-5-haiku-20241022",
+	"messages": [{"role": "user", "content": "What is 2+2? Answer briefly."}],
+	"max_tokens": 64,
+	"stream": true,
+	"stream_options": {"include_usage": true}
+}`
+
+func newReplayProvider(t *testing.T) *Provider {
+	t.Helper()
+	rt, err := cassette.NewTransport(cassette.ModeReplay, "testdata/cassettes", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := New("claude", "")
+	p.SetTransport(rt)
+	return p
+}
+
+func parseRequest(t *testing.T, data string) *models.ChatCompletionRequest {
+	t.Helper()
+	var req models.ChatCompletionRequest
+	if err := json.Unmarshal([]byte(data), &req); err != nil {
+		t.Fatal(err)
+	}
+	return &req
+}
+
+func TestCassetteToolCall(t *testing.T) {
+	p := newReplayProvider(t)
+
+	resp, err := p.ChatCompletion(parseRequest(t, toolCallRequest), "test-key")
+	if err != nil {
+		t.Fatalf("ChatCompletion: %v", err)
+	}
+
+	choice := resp.Choices[0]
+	if choice.FinishReason != "tool_calls" {
+		t.Errorf("finish_reason = %q, want tool_calls", choice.FinishReason)
+	}
+	if choice.Message.Content != "I'll check the weather in Paris." {
+		t.Errorf("content = %q", choice.Message.Content)
+	}
+	if len(choice.Message.ToolCalls) != 1 {
+		t.Fatalf("tool_calls = %+v, want 1", choice.Message.ToolCalls)
+	}
+	call := choice.Message.ToolCalls[0]
+	if call.ID != "toolu_01A09q90qw90lq917835lq9" || call.Function.Name != "get_weather" || call.Function.Arguments != `{"city":"Paris"}` {
+		t.Errorf("tool call = %+v", call)
+	}
+	if resp.Usage.PromptTokens != 412 || resp.Usage.CompletionTokens != 58 || resp.Usage.TotalTokens != 470 {
+		t.Errorf("usage = %+v, want 412 + 58", resp.Usage)
+	}
+}
+
+func TestCassetteStream(t *testing.T) {
+	p := newReplayProvider(t)
+
+	chunkChan, errChan := p.ChatCompletionStream(parseRequest(t, streamRequest), "test-key")
+	var (
+		content      strings.Builder
+		finishReason string
+		usage        *models.Usage
+	)
+	for chunk := range chunkChan {
+		for _, choice := range chunk.Choices {
+			content.WriteString(choice.Delta.Content)
+			if choice.FinishReason != nil {
+				finishReason = *choice.FinishReason
+			}
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+	}
+	if err := <-errChan; err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+
+	if content.String() != "2 + 2 = 4." {
+		t.Errorf("content = %q, want %q", content.String(), "2 + 2 = 4.")
+	}
+	if finishReason != "stop" {
+		t.Errorf("finish_reason = %q, want stop", finishReason)
+	}
+	if usage == nil || usage.PromptTokens != 18 || usage.CompletionTokens != 11 {
+		t.Errorf("usage = %+v, want 18 + 11", usage)
+	}
+}
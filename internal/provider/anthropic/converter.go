@@ -1,126 +1,394 @@
 package anthropic
 
 import (
+	"fmt"
+	"openbridge/internal/media"
 	"openbridge/internal/models"
 	"strings"
+	"unicode/utf8"
 )
 
+// ConvertOptions 影响请求转换的 Provider 选项
+type ConvertOptions struct {
+	ImageURLSource bool // 远程图片以 url source 交给 Claude 下载，而不是由网关下载后内联
+}
+
 // ConvertFromOpenAI 将 OpenAI 格式转换为 Claude 格式
-func ConvertFromOpenAI(req *models.ChatCompletionRequest) (*ChatRequest, error) {
+func ConvertFromOpenAI(req *models.ChatCompletionRequest, opts ConvertOptions) (*ChatRequest, error) {
 	claudeReq := &ChatRequest{
 		Model:       req.Model,
 		Messages:    make([]Message, 0),
-		MaxTokens:   req.MaxTokens,
+		MaxTokens:   req.MaxOutputTokens(),
 		Temperature: req.Temperature,
 		TopP:        req.TopP,
+		TopK:        req.TopK,
 		Stream:      req.Stream,
 	}
 
+	// Claude 不支持的参数直接拒绝，避免静默忽略
+	if req.WantsAudio() {
+		return nil, models.NewRequestError("modalities", "audio output is not supported by Claude models")
+	}
+	if req.Seed != nil {
+		return nil, models.NewRequestError("seed", "seed is not supported by Claude models")
+	}
+	if len(req.LogitBias) > 0 {
+		return nil, models.NewRequestError("logit_bias", "logit_bias is not supported by Claude models")
+	}
+	if req.Logprobs || req.TopLogprobs > 0 {
+		return nil, models.NewRequestError("logprobs", "logprobs are not supported by Claude models")
+	}
+
+	stops, err := req.StopSequences()
+	if err != nil {
+		return nil, err
+	}
+	claudeReq.StopSequences = stops
+
+	if req.User != "" {
+		claudeReq.Metadata = &Metadata{UserID: req.User}
+	}
+
 	// 默认 max_tokens (Claude 要求必须设置)
 	if claudeReq.MaxTokens == 0 {
-		claudeReq.MaxTokens = 4096
+		claudeReq.MaxTokens = defaultMaxTokens
 	}
 
-	// 提取 system message
-	var systemMessages []string
+	// 提取 system / developer 消息，每条消息转换为 text 块以便单独设置缓存断点
 	for _, msg := range req.Messages {
-		if msg.Role == "system" {
-			if text, ok := msg.Content.(string); ok {
-				systemMessages = append(systemMessages, text)
+		if msg.IsSystem() {
+			claudeReq.System = append(claudeReq.System, systemBlocks(msg)...)
+		}
+	}
+
+	// 转换其余消息并按 Claude 的角色规则规范化
+	messages, err := convertMessages(req.Messages, opts)
+	if err != nil {
+		return nil, err
+	}
+	claudeReq.Messages = messages
+
+	// 转换 tools
+	claudeReq.Tools = convertTools(req.Tools)
+	claudeReq.ToolChoice = convertToolChoice(req.ToolChoice)
+
+	// web_search_options -> web_search 服务端工具
+	if req.WebSearchOptions != nil {
+		claudeReq.Tools = append(claudeReq.Tools, webSearchTool(req.WebSearchOptions))
+	}
+
+	// response_format: Claude 没有原生支持，通过强制调用一个以目标 schema 为参数的工具来模拟
+	if name := ResponseToolName(req); name != "" {
+		for _, tool := range req.Tools {
+			if tool.Function.Name == name {
+				return nil, models.NewRequestError("response_format", "response_format name %q conflicts with a tool of the same name", name)
 			}
 		}
+		claudeReq.Tools = append(claudeReq.Tools, responseFormatTool(req.ResponseFormat, name))
+		claudeReq.ToolChoice = responseToolChoice(claudeReq.ToolChoice, name, len(req.Tools) > 0)
 	}
-	if len(systemMessages) > 0 {
-		claudeReq.System = strings.Join(systemMessages, "\n\n")
+
+	// parallel_tool_calls: false 对应 disable_parallel_tool_use
+	if req.ParallelToolCalls != nil && !*req.ParallelToolCalls && len(claudeReq.Tools) > 0 {
+		if claudeReq.ToolChoice == nil {
+			claudeReq.ToolChoice = &ToolChoice{Type: "auto"}
+		}
+		if claudeReq.ToolChoice.Type != "none" {
+			claudeReq.ToolChoice.DisableParallelToolUse = true
+		}
 	}
 
-	// 转换 messages (跳过 system)
-	for _, msg := range req.Messages {
-		if msg.Role == "system" {
-			continue
+	if n := countCacheBreakpoints(claudeReq); n > maxCacheBreakpoints {
+		return nil, models.NewRequestError("cache_control", "Claude allows at most %d cache_control breakpoints, got %d", maxCacheBreakpoints, n)
+	}
+
+	// thinking / reasoning_effort -> extended thinking
+	budget, err := req.ThinkingBudget()
+	if err != nil {
+		return nil, err
+	}
+	if budget != nil && *budget > 0 {
+		if err := applyThinking(claudeReq, req, *budget); err != nil {
+			return nil, err
 		}
+	}
+
+	return claudeReq, nil
+}
+
+// supportedImageTypes Claude 支持的图片格式
+var supportedImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// convertImage 将 image_url 转换为 image 内容块
+// data URI 直接解码；远程 URL 由网关下载后内联，或在开启 ImageURLSource 时交给 Claude 下载
+func convertImage(url string, opts ConvertOptions) (*ContentBlock, error) {
+	if opts.ImageURLSource && (strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "http://")) {
+		return &ContentBlock{Type: "image", Source: &ImageSource{Type: "url", URL: url}}, nil
+	}
+
+	var m *media.Media
+	var err error
+	if strings.HasPrefix(url, "data:") {
+		m, err = media.ParseDataURI(url)
+	} else {
+		m, err = media.Default().Fetch(url)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !supportedImageTypes[m.MimeType] {
+		return nil, fmt.Errorf("unsupported image type %q (supported: jpeg, png, gif, webp)", m.MimeType)
+	}
+
+	return &ContentBlock{
+		Type: "image",
+		Source: &ImageSource{
+			Type:      "base64",
+			MediaType: m.MimeType,
+			Data:      m.Base64(),
+		},
+	}, nil
+}
+
+// maxDocumentBytes Claude 单个文档的大小上限
+const maxDocumentBytes = 32 << 20
+
+// convertDocument 将 file 内容块转换为 document 块（PDF / 纯文本），图片文件转换为 image 块
+func convertDocument(file *models.File) (*ContentBlock, error) {
+	if file == nil {
+		return nil, fmt.Errorf("file part is missing the file object")
+	}
+	if file.FileData == "" && file.FileID != "" {
+		return nil, fmt.Errorf("file_id is not supported by Claude models, send the content as file_data")
+	}
+
+	m, err := media.DecodeFileData(file.FileData, file.Filename, file.MimeType)
+	if err != nil {
+		return nil, err
+	}
+	if len(m.Data) > maxDocumentBytes {
+		return nil, fmt.Errorf("file is %d bytes, Claude accepts documents up to %d bytes", len(m.Data), maxDocumentBytes)
+	}
+
+	switch {
+	case m.MimeType == "application/pdf":
+		return &ContentBlock{
+			Type:   "document",
+			Source: &ImageSource{Type: "base64", MediaType: m.MimeType, Data: m.Base64()},
+			Title:  file.Filename,
+		}, nil
+	case strings.HasPrefix(m.MimeType, "text/"):
+		return &ContentBlock{
+			Type:   "document",
+			Source: &ImageSource{Type: "text", MediaType: "text/plain", Data: string(m.Data)},
+			Title:  file.Filename,
+		}, nil
+	case supportedImageTypes[m.MimeType]:
+		return &ContentBlock{
+			Type:   "image",
+			Source: &ImageSource{Type: "base64", MediaType: m.MimeType, Data: m.Base64()},
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported file type %q (supported: application/pdf, text/*, images)", m.MimeType)
+}
+
+// maxCacheBreakpoints Claude 单个请求允许的缓存断点数量
+const maxCacheBreakpoints = 4
 
-		claudeMsg := Message{
-			Role: msg.Role,
+// convertCacheControl 转换缓存断点
+func convertCacheControl(cc *models.CacheControl) *CacheControl {
+	if cc == nil {
+		return nil
+	}
+	return &CacheControl{Type: cc.Type, TTL: cc.TTL}
+}
+
+// systemBlocks 将 system 消息转换为 text 块，跳过空文本（Claude 不接受空的 text 块）
+func systemBlocks(msg models.Message) []ContentBlock {
+	var blocks []ContentBlock
+	switch v := msg.Content.(type) {
+	case string:
+		if v != "" {
+			blocks = append(blocks, ContentBlock{Type: "text", Text: v})
+		}
+	case []interface{}:
+		for _, part := range v {
+			partMap, ok := part.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if text, _ := partMap["text"].(string); text != "" {
+				blocks = append(blocks, ContentBlock{
+					Type:         "text",
+					Text:         text,
+					CacheControl: convertCacheControl(models.ParseCacheControl(partMap)),
+				})
+			}
 		}
+	}
 
-		// 处理 content
-		switch content := msg.Content.(type) {
-		case string:
-			claudeMsg.Content = content
-		case []interface{}:
-			// 多模态内容
-			contentBlocks := make([]ContentBlock, 0)
-			for _, part := range content {
-				partMap, ok := part.(map[string]interface{})
-				if !ok {
-					continue
-				}
+	if msg.CacheControl != nil && len(blocks) > 0 {
+		blocks[len(blocks)-1].CacheControl = convertCacheControl(msg.CacheControl)
+	}
+	return blocks
+}
 
-				typeStr, _ := partMap["type"].(string)
-				switch typeStr {
-				case "text":
-					if text, ok := partMap["text"].(string); ok {
-						contentBlocks = append(contentBlocks, ContentBlock{
-							Type: "text",
-							Text: text,
-						})
-					}
-				case "image_url":
-					// 转换图片 URL 为 base64
-					imageURL, ok := partMap["image_url"].(map[string]interface{})
-					if !ok {
-						continue
-					}
-					url, _ := imageURL["url"].(string)
-					
-					// 如果是 data URI，提取 base64 数据
-					if strings.HasPrefix(url, "data:") {
-						parts := strings.SplitN(url, ",", 2)
-						if len(parts) == 2 {
-							mediaType := "image/png"
-							if strings.Contains(parts[0], "image/jpeg") {
-								mediaType = "image/jpeg"
-							} else if strings.Contains(parts[0], "image/webp") {
-								mediaType = "image/webp"
-							} else if strings.Contains(parts[0], "image/gif") {
-								mediaType = "image/gif"
-							}
-							
-							contentBlocks = append(contentBlocks, ContentBlock{
-								Type: "image",
-								Source: &ImageSource{
-									Type:      "base64",
-									MediaType: mediaType,
-									Data:      parts[1],
-								},
-							})
-						}
-					}
+// countCacheBreakpoints 统计请求中的缓存断点数量
+func countCacheBreakpoints(claudeReq *ChatRequest) int {
+	n := 0
+	for _, block := range claudeReq.System {
+		if block.CacheControl != nil {
+			n++
+		}
+	}
+	for _, msg := range claudeReq.Messages {
+		if blocks, ok := msg.Content.([]ContentBlock); ok {
+			for _, block := range blocks {
+				if block.CacheControl != nil {
+					n++
 				}
 			}
-			if len(contentBlocks) > 0 {
-				claudeMsg.Content = contentBlocks
-			}
 		}
+	}
+	for _, tool := range claudeReq.Tools {
+		if tool.CacheControl != nil {
+			n++
+		}
+	}
+	return n
+}
 
-		claudeReq.Messages = append(claudeReq.Messages, claudeMsg)
+// convertUsage 转换 usage
+// Claude 的 input_tokens 不含缓存部分，OpenAI 的 prompt_tokens 包含全部输入 token
+func convertUsage(usage Usage) models.Usage {
+	prompt := usage.InputTokens + usage.CacheCreationInputTokens + usage.CacheReadInputTokens
+	result := models.Usage{
+		PromptTokens:     prompt,
+		CompletionTokens: usage.OutputTokens,
+		TotalTokens:      prompt + usage.OutputTokens,
 	}
+	if usage.CacheCreationInputTokens > 0 || usage.CacheReadInputTokens > 0 {
+		result.PromptTokensDetails = &models.PromptTokensDetails{
+			CachedTokens:        usage.CacheReadInputTokens,
+			CacheCreationTokens: usage.CacheCreationInputTokens,
+		}
+	}
+	return result
+}
 
-	return claudeReq, nil
+// defaultMaxTokens 客户端未指定 max_tokens 时使用的默认值
+const defaultMaxTokens = 4096
+
+// minThinkingBudget Claude 允许的最小思考预算
+const minThinkingBudget = 1024
+
+// applyThinking 开启 extended thinking，并检查 Claude 在思考模式下对其他参数的限制
+func applyThinking(claudeReq *ChatRequest, req *models.ChatCompletionRequest, budget int) error {
+	param := "thinking.budget_tokens"
+	if req.Thinking == nil {
+		param = "reasoning_effort"
+	}
+
+	// 思考预算计入 max_tokens，必须小于它
+	maxTokens := req.MaxOutputTokens()
+	switch {
+	case maxTokens == 0:
+		claudeReq.MaxTokens = budget + defaultMaxTokens
+	case budget >= maxTokens:
+		if req.Thinking != nil {
+			return models.NewRequestError(param, "thinking.budget_tokens must be less than max_tokens")
+		}
+		// reasoning_effort 只是预算档位，压缩到输出上限以内
+		budget = maxTokens - 1
+	}
+	if budget < minThinkingBudget {
+		return models.NewRequestError(param, "Claude requires a thinking budget of at least %d tokens (and max_tokens above it)", minThinkingBudget)
+	}
+
+	if req.Temperature != nil && *req.Temperature != 1 {
+		return models.NewRequestError("temperature", "temperature cannot be changed when thinking is enabled")
+	}
+	if req.TopK != 0 {
+		return models.NewRequestError("top_k", "top_k is not supported when thinking is enabled")
+	}
+	if req.TopP != nil && *req.TopP < 0.95 {
+		return models.NewRequestError("top_p", "top_p must be at least 0.95 when thinking is enabled")
+	}
+
+	// 思考模式下不能强制调用工具
+	if claudeReq.ToolChoice != nil && (claudeReq.ToolChoice.Type == "any" || claudeReq.ToolChoice.Type == "tool") {
+		if ResponseToolName(req) == "" {
+			return models.NewRequestError("tool_choice", "tool_choice %q is not supported when thinking is enabled", claudeReq.ToolChoice.Type)
+		}
+		// 结构化输出改为 auto，由工具描述引导模型调用
+		claudeReq.ToolChoice.Type = "auto"
+		claudeReq.ToolChoice.Name = ""
+	}
+
+	claudeReq.Thinking = &Thinking{Type: "enabled", BudgetTokens: budget}
+	return nil
 }
 
 // ConvertToOpenAI 将 Claude 格式转换为 OpenAI 格式
-func ConvertToOpenAI(resp *ChatResponse, requestModel string) *models.ChatCompletionResponse {
-	// 提取文本内容
-	var content string
-	if len(resp.Content) > 0 {
-		for _, block := range resp.Content {
-			if block.Type == "text" {
-				content += block.Text
+// responseTool 为模拟 response_format 的工具名，其调用参数作为消息内容返回
+func ConvertToOpenAI(resp *ChatResponse, requestModel string, responseTool string) *models.ChatCompletionResponse {
+	// 提取文本内容和工具调用
+	var content, structured, reasoning string
+	var toolCalls []models.ToolCall
+	var thinking []models.ThinkingBlock
+	var annotations []models.Annotation
+	for _, block := range resp.Content {
+		switch block.Type {
+		case "text":
+			start := utf8.RuneCountInString(content)
+			content += block.Text
+			annotations = append(annotations, citationAnnotations(block.Citations, start, utf8.RuneCountInString(content))...)
+		case "thinking":
+			reasoning += block.Thinking
+			thinking = append(thinking, models.ThinkingBlock{
+				Type:      "thinking",
+				Thinking:  block.Thinking,
+				Signature: block.Signature,
+			})
+		case "redacted_thinking":
+			thinking = append(thinking, models.ThinkingBlock{
+				Type: "redacted_thinking",
+				Data: block.Data,
+			})
+		case "tool_use":
+			args := string(block.Input)
+			if args == "" {
+				args = "{}"
 			}
+			if responseTool != "" && block.Name == responseTool {
+				structured = args
+				continue
+			}
+			toolCalls = append(toolCalls, models.ToolCall{
+				ID:   block.ID,
+				Type: "function",
+				Function: models.FunctionCall{
+					Name:      block.Name,
+					Arguments: args,
+				},
+			})
 		}
 	}
+	if structured != "" {
+		content = structured
+		annotations = nil
+	}
+
+	finishReason := convertFinishReason(resp.StopReason)
+	if finishReason == "tool_calls" && len(toolCalls) == 0 {
+		// 只调用了 response_format 工具，对客户端而言是正常结束
+		finishReason = "stop"
+	}
 
 	return &models.ChatCompletionResponse{
 		ID:      resp.ID,
@@ -131,27 +399,63 @@ func ConvertToOpenAI(resp *ChatResponse, requestModel string) *models.ChatComple
 			{
 				Index: 0,
 				Message: models.ResponseMessage{
-					Role:    resp.Role,
-					Content: content,
+					Role:             resp.Role,
+					Content:          content,
+					ToolCalls:        toolCalls,
+					ReasoningContent: reasoning,
+					ThinkingBlocks:   thinking,
+					Annotations:      annotations,
 				},
-				FinishReason: convertFinishReason(resp.StopReason),
+				FinishReason: finishReason,
 			},
 		},
-		Usage: models.Usage{
-			PromptTokens:     resp.Usage.InputTokens,
-			CompletionTokens: resp.Usage.OutputTokens,
-			TotalTokens:      resp.Usage.InputTokens + resp.Usage.OutputTokens,
-		},
+		Usage: convertUsage(resp.Usage),
+	}
+}
+
+// responseBlock 标记 response_format 工具对应的内容块
+const responseBlock = -1
+
+// StreamConverter 将 Claude 流式事件转换为 OpenAI 流式块
+// tool_use 的参数增量只携带内容块序号，因此需要跨事件记录内容块到工具调用的映射
+type StreamConverter struct {
+	chunkID      string
+	requestModel string
+	responseTool string
+	blocks       map[int]int // 内容块序号 -> tool_calls 序号（responseBlock 表示结构化输出）
+	toolCalls    int
+	thinking     map[int]*strings.Builder // 内容块序号 -> 已收到的思考内容，签名到达时下发完整的块
+	usage        Usage                    // input 来自 message_start，output 来自 message_delta
+	contentLen   int                      // 已发送的 content 字符数，用于计算引用位置
+	textStart    map[int]int              // text 内容块序号 -> 起始字符位置
+	citations    map[int][]Citation       // text 内容块序号 -> 引用，块结束时下发
+
+	// 模拟 response_format 时先暂存文本，结构化输出工具被调用时丢弃（与非流式一致），否则在结束时下发
+	heldText        strings.Builder
+	heldAnnotations []models.Annotation
+	responseCalled  bool
+}
+
+// NewStreamConverter 创建流式转换器
+func NewStreamConverter(chunkID string, requestModel string, responseTool string) *StreamConverter {
+	return &StreamConverter{
+		chunkID:      chunkID,
+		requestModel: requestModel,
+		responseTool: responseTool,
+		blocks:       make(map[int]int),
+		thinking:     make(map[int]*strings.Builder),
+		textStart:    make(map[int]int),
+		citations:    make(map[int][]Citation),
 	}
 }
 
-// ConvertStreamEventToChunk 将 Claude 流式事件转换为 OpenAI 流式块
-func ConvertStreamEventToChunk(event *StreamEvent, chunkID string, requestModel string) *models.ChatCompletionChunk {
+// Convert 转换单个流式事件，返回 nil 表示该事件不需要下发
+func (c *StreamConverter) Convert(event *StreamEvent) *models.ChatCompletionChunk {
 	chunk := &models.ChatCompletionChunk{
-		ID:      chunkID,
+		ID:      c.chunkID,
 		Object:  "chat.completion.chunk",
 		Created: 0,
-		Model:   requestModel,
+		Model:   c.requestModel,
 		Choices: []models.ChunkChoice{
 			{
 				Index: 0,
@@ -159,43 +463,312 @@ func ConvertStreamEventToChunk(event *StreamEvent, chunkID string, requestModel
 			},
 		},
 	}
+	delta := &chunk.Choices[0].Delta
 
 	switch event.Type {
 	case "message_start":
 		// 消息开始，发送 role
-		chunk.Choices[0].Delta.Role = "assistant"
+		if event.Message != nil {
+			c.usage = event.Message.Usage
+		}
+		delta.Role = "assistant"
+
+	case "content_block_start":
+		if event.ContentBlock == nil {
+			return nil
+		}
+		switch event.ContentBlock.Type {
+		case "text":
+			c.textStart[event.Index] = c.contentLen
+			c.citations[event.Index] = append(c.citations[event.Index], event.ContentBlock.Citations...)
+			return nil
+		case "thinking":
+			c.thinking[event.Index] = &strings.Builder{}
+			return nil
+		case "redacted_thinking":
+			// 加密的思考块没有增量，直接整块下发
+			delta.ThinkingBlocks = []models.ThinkingBlock{
+				{Type: "redacted_thinking", Data: event.ContentBlock.Data},
+			}
+			return chunk
+		case "tool_use":
+		default:
+			return nil
+		}
+
+		// 工具调用开始，发送 id 和函数名
+		if c.responseTool != "" && event.ContentBlock.Name == c.responseTool {
+			c.blocks[event.Index] = responseBlock
+			c.responseCalled = true
+			return nil
+		}
+		index := c.toolCalls
+		c.toolCalls++
+		c.blocks[event.Index] = index
+		delta.ToolCalls = []models.ToolCall{
+			{
+				Index: &index,
+				ID:    event.ContentBlock.ID,
+				Type:  "function",
+				Function: models.FunctionCall{
+					Name: event.ContentBlock.Name,
+				},
+			},
+		}
 
 	case "content_block_delta":
 		// 内容增量
-		if event.Delta != nil && event.Delta.Type == "text_delta" {
-			chunk.Choices[0].Delta.Content = event.Delta.Text
+		if event.Delta == nil {
+			return nil
+		}
+		switch event.Delta.Type {
+		case "text_delta":
+			if event.Delta.Text == "" {
+				return nil
+			}
+			c.contentLen += utf8.RuneCountInString(event.Delta.Text)
+			if c.responseTool != "" {
+				c.heldText.WriteString(event.Delta.Text)
+				return nil
+			}
+			delta.Content = event.Delta.Text
+		case "citations_delta":
+			if event.Delta.Citation == nil {
+				return nil
+			}
+			c.citations[event.Index] = append(c.citations[event.Index], *event.Delta.Citation)
+			return nil
+		case "thinking_delta":
+			if event.Delta.Thinking == "" {
+				return nil
+			}
+			delta.ReasoningContent = event.Delta.Thinking
+			if b, ok := c.thinking[event.Index]; ok {
+				b.WriteString(event.Delta.Thinking)
+			}
+		case "signature_delta":
+			text := ""
+			if b, ok := c.thinking[event.Index]; ok {
+				text = b.String()
+			}
+			delta.ThinkingBlocks = []models.ThinkingBlock{
+				{Type: "thinking", Thinking: text, Signature: event.Delta.Signature},
+			}
+		case "input_json_delta":
+			index, ok := c.blocks[event.Index]
+			if !ok || event.Delta.PartialJSON == "" {
+				return nil
+			}
+			if index == responseBlock {
+				delta.Content = event.Delta.PartialJSON
+				c.contentLen += utf8.RuneCountInString(event.Delta.PartialJSON)
+			} else {
+				delta.ToolCalls = []models.ToolCall{
+					{
+						Index:    &index,
+						Function: models.FunctionCall{Arguments: event.Delta.PartialJSON},
+					},
+				}
+			}
+		default:
+			return nil
 		}
 
-	case "message_delta":
-		// 消息结束，设置 finish_reason
-		if event.Delta != nil && event.Delta.StopReason != "" {
-			finishReason := convertFinishReason(event.Delta.StopReason)
-			chunk.Choices[0].FinishReason = &finishReason
+	case "content_block_stop":
+		// text 块结束，下发其引用
+		citations, ok := c.citations[event.Index]
+		if !ok {
+			return nil
+		}
+		delete(c.citations, event.Index)
+		delta.Annotations = citationAnnotations(citations, c.textStart[event.Index], c.contentLen)
+		if c.responseTool != "" {
+			c.heldAnnotations = append(c.heldAnnotations, delta.Annotations...)
+			return nil
+		}
+		if len(delta.Annotations) == 0 {
+			return nil
 		}
 
-	case "message_stop":
-		// 流结束
-		finishReason := "stop"
-		chunk.Choices[0].FinishReason = &finishReason
-		
-		// 添加 usage 信息
+	case "message_delta":
+		// 消息结束，设置 finish_reason；usage 中的 token 数是累计值
 		if event.Usage != nil {
-			chunk.Usage = &models.Usage{
-				PromptTokens:     event.Usage.InputTokens,
-				CompletionTokens: event.Usage.OutputTokens,
-				TotalTokens:      event.Usage.InputTokens + event.Usage.OutputTokens,
-			}
+			c.mergeUsage(*event.Usage)
+		}
+		if event.Delta == nil || event.Delta.StopReason == "" {
+			return nil
+		}
+		finishReason := convertFinishReason(event.Delta.StopReason)
+		if finishReason == "tool_calls" && c.toolCalls == 0 {
+			finishReason = "stop"
 		}
+		chunk.Choices[0].FinishReason = &finishReason
+		if c.responseTool != "" && !c.responseCalled {
+			// 没有调用结构化输出工具，下发暂存的文本
+			delta.Content = c.heldText.String()
+			delta.Annotations = c.heldAnnotations
+		}
+
+	default:
+		// message_stop 不携带内容，finish_reason 已在 message_delta 中发送
+		return nil
 	}
 
 	return chunk
 }
 
+// mergeUsage 合并 message_delta 中的 usage，只覆盖出现的字段
+func (c *StreamConverter) mergeUsage(usage Usage) {
+	if usage.InputTokens > 0 {
+		c.usage.InputTokens = usage.InputTokens
+	}
+	if usage.OutputTokens > 0 {
+		c.usage.OutputTokens = usage.OutputTokens
+	}
+	if usage.CacheCreationInputTokens > 0 {
+		c.usage.CacheCreationInputTokens = usage.CacheCreationInputTokens
+	}
+	if usage.CacheReadInputTokens > 0 {
+		c.usage.CacheReadInputTokens = usage.CacheReadInputTokens
+	}
+}
+
+// Usage 返回截至目前累计的 usage
+func (c *StreamConverter) Usage() models.Usage {
+	return convertUsage(c.usage)
+}
+
+// UsageChunk 返回携带累计 usage 的最后一个 chunk（stream_options.include_usage）
+func (c *StreamConverter) UsageChunk() *models.ChatCompletionChunk {
+	return models.NewUsageChunk(c.chunkID, c.requestModel, convertUsage(c.usage))
+}
+
+// webSearchTool 创建 Claude web_search 服务端工具
+func webSearchTool(opts *models.WebSearchOptions) Tool {
+	tool := Tool{Type: "web_search_20250305", Name: "web_search"}
+	if loc := opts.UserLocation; loc != nil && loc.Approximate != nil {
+		tool.UserLocation = &UserLocation{
+			Type:     "approximate",
+			City:     loc.Approximate.City,
+			Region:   loc.Approximate.Region,
+			Country:  loc.Approximate.Country,
+			Timezone: loc.Approximate.Timezone,
+		}
+	}
+	return tool
+}
+
+// citationAnnotations 将 text 块的引用转换为 url_citation，start / end 为该块在 content 中的字符区间
+func citationAnnotations(citations []Citation, start, end int) []models.Annotation {
+	var annotations []models.Annotation
+	for _, citation := range citations {
+		if citation.Type != "web_search_result_location" || citation.URL == "" {
+			continue
+		}
+		annotations = append(annotations, models.NewURLCitation(start, end, citation.URL, citation.Title))
+	}
+	return annotations
+}
+
+// convertTools 转换 OpenAI tools 定义
+func convertTools(tools []models.Tool) []Tool {
+	var claudeTools []Tool
+	for _, tool := range tools {
+		if tool.Type != "" && tool.Type != "function" {
+			continue
+		}
+		claudeTools = append(claudeTools, Tool{
+			Name:         tool.Function.Name,
+			Description:  tool.Function.Description,
+			InputSchema:  inputSchema(tool.Function.Parameters),
+			CacheControl: convertCacheControl(tool.CacheControl),
+		})
+	}
+	return claudeTools
+}
+
+// inputSchema Claude 要求 input_schema 必须是 object 类型
+func inputSchema(parameters map[string]any) map[string]any {
+	if len(parameters) == 0 {
+		return map[string]any{"type": "object", "properties": map[string]any{}}
+	}
+	return parameters
+}
+
+// convertToolChoice 转换 OpenAI tool_choice
+// "auto" -> auto, "required" -> any, "none" -> none, {"function": {"name"}} -> tool
+func convertToolChoice(toolChoice any) *ToolChoice {
+	switch v := toolChoice.(type) {
+	case string:
+		switch v {
+		case "auto":
+			return &ToolChoice{Type: "auto"}
+		case "required":
+			return &ToolChoice{Type: "any"}
+		case "none":
+			return &ToolChoice{Type: "none"}
+		}
+	case map[string]interface{}:
+		if fn, ok := v["function"].(map[string]interface{}); ok {
+			if name, _ := fn["name"].(string); name != "" {
+				return &ToolChoice{Type: "tool", Name: name}
+			}
+		}
+	}
+	return nil
+}
+
+// defaultResponseTool json_object 或未命名 schema 时使用的工具名
+const defaultResponseTool = "json_response"
+
+// ResponseToolName 返回模拟 response_format 所用的工具名，不需要模拟时返回空字符串
+func ResponseToolName(req *models.ChatCompletionRequest) string {
+	if req.ResponseFormat == nil {
+		return ""
+	}
+	switch req.ResponseFormat.Type {
+	case "json_schema":
+		if req.ResponseFormat.JSONSchema != nil && req.ResponseFormat.JSONSchema.Name != "" {
+			return req.ResponseFormat.JSONSchema.Name
+		}
+		return defaultResponseTool
+	case "json_object":
+		return defaultResponseTool
+	}
+	return ""
+}
+
+// responseToolChoice 模拟 response_format 时的 tool_choice
+// 没有业务工具时强制调用结构化输出工具；有业务工具时保留客户端指定的函数，
+// none 表示不调用业务工具，因此只强制调用结构化输出工具，auto / required / 未设置时让模型在业务工具和结构化输出之间选择
+func responseToolChoice(choice *ToolChoice, name string, hasTools bool) *ToolChoice {
+	if hasTools && choice != nil && choice.Type == "tool" {
+		return choice
+	}
+	if hasTools && (choice == nil || choice.Type != "none") {
+		return &ToolChoice{Type: "any"}
+	}
+	return &ToolChoice{Type: "tool", Name: name}
+}
+
+// responseFormatTool 构造模拟 response_format 的工具，其 input_schema 即目标 JSON Schema
+func responseFormatTool(format *models.ResponseFormat, name string) Tool {
+	tool := Tool{
+		Name:        name,
+		Description: "Respond to the user with a JSON object. Always call this tool to deliver the final answer.",
+		InputSchema: map[string]any{"type": "object"},
+	}
+	if format.JSONSchema != nil {
+		if format.JSONSchema.Description != "" {
+			tool.Description = format.JSONSchema.Description
+		}
+		if len(format.JSONSchema.Schema) > 0 {
+			tool.InputSchema = format.JSONSchema.Schema
+		}
+	}
+	return tool
+}
+
 func convertFinishReason(claudeReason string) string {
 	switch claudeReason {
 	case "end_turn":
@@ -204,8 +777,9 @@ func convertFinishReason(claudeReason string) string {
 		return "length"
 	case "stop_sequence":
 		return "stop"
+	case "tool_use":
+		return "tool_calls"
 	default:
 		return "stop"
 	}
 }
-
@@ -0,0 +1,105 @@
+package anthropic
+
+import (
+	"errors"
+	"openbridge/internal/models"
+	"testing"
+)
+
+func TestResponseFormatToolChoice(t *testing.T) {
+	weather := models.Tool{Type: "function", Function: models.FunctionDefinition{Name: "get_weather"}}
+	format := &models.ResponseFormat{Type: "json_schema", JSONSchema: &models.JSONSchema{Name: "answer"}}
+
+	tests := []struct {
+		name       string
+		tools      []models.Tool
+		toolChoice any
+		want       ToolChoice
+	}{
+		{"no tools", nil, nil, ToolChoice{Type: "tool", Name: "answer"}},
+		{"tools without choice", []models.Tool{weather}, nil, ToolChoice{Type: "any"}},
+		{"auto", []models.Tool{weather}, "auto", ToolChoice{Type: "any"}},
+		{"required", []models.Tool{weather}, "required", ToolChoice{Type: "any"}},
+		{"none", []models.Tool{weather}, "none", ToolChoice{Type: "tool", Name: "answer"}},
+		{"named function", []models.Tool{weather},
+			map[string]interface{}{"type": "function", "function": map[string]interface{}{"name": "get_weather"}},
+			ToolChoice{Type: "tool", Name: "get_weather"}},
+	}
+	for _, tt := range tests {
+		req := &models.ChatCompletionRequest{
+			Model:          "claude-3-5-haiku-20241022",
+			Messages:       []models.Message{{Role: "user", Content: "hi"}},
+			Tools:          tt.tools,
+			ToolChoice:     tt.toolChoice,
+			ResponseFormat: format,
+		}
+		claudeReq, err := ConvertFromOpenAI(req, ConvertOptions{})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if claudeReq.ToolChoice == nil || *claudeReq.ToolChoice != tt.want {
+			t.Errorf("%s: tool_choice = %+v, want %+v", tt.name, claudeReq.ToolChoice, tt.want)
+		}
+		if n := len(claudeReq.Tools); n != len(tt.tools)+1 {
+			t.Errorf("%s: %d tools, want %d", tt.name, n, len(tt.tools)+1)
+		}
+	}
+}
+
+func TestResponseFormatToolNameConflict(t *testing.T) {
+	req := &models.ChatCompletionRequest{
+		Model:          "claude-3-5-haiku-20241022",
+		Messages:       []models.Message{{Role: "user", Content: "hi"}},
+		Tools:          []models.Tool{{Type: "function", Function: models.FunctionDefinition{Name: "answer"}}},
+		ResponseFormat: &models.ResponseFormat{Type: "json_schema", JSONSchema: &models.JSONSchema{Name: "answer"}},
+	}
+	_, err := ConvertFromOpenAI(req, ConvertOptions{})
+	var reqErr *models.RequestError
+	if !errors.As(err, &reqErr) {
+		t.Errorf("err = %v, want a RequestError", err)
+	}
+}
+
+func streamText(t *testing.T, c *StreamConverter, events []StreamEvent) (content, finishReason string) {
+	t.Helper()
+	for i := range events {
+		chunk := c.Convert(&events[i])
+		if chunk == nil {
+			continue
+		}
+		content += chunk.Choices[0].Delta.Content
+		if fr := chunk.Choices[0].FinishReason; fr != nil {
+			finishReason = *fr
+		}
+	}
+	return content, finishReason
+}
+
+// 模拟 response_format 时，工具调用之前的文本不能混入 JSON 内容
+func TestStreamResponseFormatHoldsText(t *testing.T) {
+	textEvents := []StreamEvent{
+		{Type: "message_start", Message: &ChatResponse{}},
+		{Type: "content_block_start", Index: 0, ContentBlock: &ContentBlock{Type: "text"}},
+		{Type: "content_block_delta", Index: 0, Delta: &StreamDelta{Type: "text_delta", Text: "Sure: "}},
+		{Type: "content_block_stop", Index: 0},
+	}
+	toolEvents := []StreamEvent{
+		{Type: "content_block_start", Index: 1, ContentBlock: &ContentBlock{Type: "tool_use", ID: "toolu_1", Name: "answer"}},
+		{Type: "content_block_delta", Index: 1, Delta: &StreamDelta{Type: "input_json_delta", PartialJSON: `{"ok":`}},
+		{Type: "content_block_delta", Index: 1, Delta: &StreamDelta{Type: "input_json_delta", PartialJSON: `true}`}},
+		{Type: "content_block_stop", Index: 1},
+		{Type: "message_delta", Delta: &StreamDelta{StopReason: "tool_use"}},
+	}
+
+	content, finishReason := streamText(t, NewStreamConverter("id", "claude", "answer"), append(append([]StreamEvent{}, textEvents...), toolEvents...))
+	if content != `{"ok":true}` || finishReason != "stop" {
+		t.Errorf("content = %q, finish_reason = %q, want the JSON only and stop", content, finishReason)
+	}
+
+	// 没有调用结构化输出工具时，结束时下发暂存的文本
+	content, finishReason = streamText(t, NewStreamConverter("id", "claude", "answer"),
+		append(textEvents, StreamEvent{Type: "message_delta", Delta: &StreamDelta{StopReason: "end_turn"}}))
+	if content != "Sure: " || finishReason != "stop" {
+		t.Errorf("content = %q, finish_reason = %q, want the held text", content, finishReason)
+	}
+}
@@ -0,0 +1,204 @@
+package anthropic
+
+import (
+	"encoding/json"
+	"fmt"
+	"openbridge/internal/models"
+	"strings"
+)
+
+// placeholderText Claude 要求消息非空且以 user 开头，缺失时插入的占位内容
+const placeholderText = "..."
+
+// convertMessages 转换 system 之外的消息，并按 Claude 的规则规范化：
+//   - tool 消息转换为 user 消息中的 tool_result 块，assistant 的 tool_calls 转换为 tool_use 块
+//   - 相邻的同角色消息合并为一条
+//   - 跳过空消息，第一条消息不是 user 时插入占位消息
+//   - 最后一条 assistant 消息（预填充）去掉结尾空白
+func convertMessages(messages []models.Message, opts ConvertOptions) ([]Message, error) {
+	var result []Message
+
+	for i, msg := range messages {
+		if msg.IsSystem() {
+			continue
+		}
+
+		var role string
+		var blocks []ContentBlock
+		var err error
+
+		switch msg.Role {
+		case "user":
+			role = "user"
+			blocks, err = convertContentBlocks(msg.Content, i, opts)
+
+		case "assistant":
+			role = "assistant"
+			// 回传上一轮的思考块，Claude 要求其位于文本和工具调用之前
+			blocks = thinkingContentBlocks(msg.ThinkingBlocks)
+			var content []ContentBlock
+			content, err = convertContentBlocks(msg.Content, i, opts)
+			blocks = append(blocks, content...)
+			if err == nil {
+				var toolUse []ContentBlock
+				toolUse, err = convertToolCalls(msg.ToolCalls, i)
+				blocks = append(blocks, toolUse...)
+			}
+
+		case "tool":
+			if msg.ToolCallID == "" {
+				return nil, models.NewRequestError(fmt.Sprintf("messages[%d].tool_call_id", i), "tool messages must have a tool_call_id")
+			}
+			role = "user"
+			var content []ContentBlock
+			content, err = convertContentBlocks(msg.Content, i, opts)
+			blocks = []ContentBlock{{Type: "tool_result", ToolUseID: msg.ToolCallID, Content: content}}
+
+		case "function":
+			return nil, models.NewRequestError(fmt.Sprintf("messages[%d].role", i), "the legacy function role is not supported by Claude models, use tools and the tool role instead")
+
+		default:
+			return nil, models.NewRequestError(fmt.Sprintf("messages[%d].role", i), "unknown message role %q", msg.Role)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// message 上的缓存断点放在最后一个内容块
+		if msg.CacheControl != nil && len(blocks) > 0 {
+			blocks[len(blocks)-1].CacheControl = convertCacheControl(msg.CacheControl)
+		}
+
+		if len(blocks) == 0 {
+			continue
+		}
+
+		if n := len(result); n > 0 && result[n-1].Role == role {
+			prev := result[n-1].Content.([]ContentBlock)
+			result[n-1].Content = append(prev, blocks...)
+			continue
+		}
+		result = append(result, Message{Role: role, Content: blocks})
+	}
+
+	if len(result) == 0 || result[0].Role != "user" {
+		placeholder := Message{Role: "user", Content: []ContentBlock{{Type: "text", Text: placeholderText}}}
+		result = append([]Message{placeholder}, result...)
+	}
+
+	// 预填充的 assistant 内容不能以空白结尾
+	if last := result[len(result)-1]; last.Role == "assistant" {
+		blocks := last.Content.([]ContentBlock)
+		if b := &blocks[len(blocks)-1]; b.Type == "text" {
+			b.Text = strings.TrimRight(b.Text, " \t\r\n")
+			if b.Text == "" {
+				blocks = blocks[:len(blocks)-1]
+			}
+		}
+		if len(blocks) == 0 {
+			result = result[:len(result)-1]
+		} else {
+			result[len(result)-1].Content = blocks
+		}
+	}
+
+	return result, nil
+}
+
+// convertContentBlocks 将 OpenAI content（字符串或数组）转换为 Claude 内容块，index 为消息序号
+func convertContentBlocks(content interface{}, index int, opts ConvertOptions) ([]ContentBlock, error) {
+	switch v := content.(type) {
+	case string:
+		if v == "" {
+			return nil, nil
+		}
+		return []ContentBlock{{Type: "text", Text: v}}, nil
+
+	case []interface{}:
+		blocks := make([]ContentBlock, 0, len(v))
+		for j, part := range v {
+			partMap, ok := part.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			before := len(blocks)
+			typeStr, _ := partMap["type"].(string)
+			switch typeStr {
+			case "text":
+				if text, _ := partMap["text"].(string); text != "" {
+					blocks = append(blocks, ContentBlock{Type: "text", Text: text})
+				}
+			case "image_url":
+				imageURL, ok := partMap["image_url"].(map[string]interface{})
+				if !ok {
+					continue
+				}
+				url, _ := imageURL["url"].(string)
+
+				block, err := convertImage(url, opts)
+				if err != nil {
+					return nil, models.NewRequestError(fmt.Sprintf("messages[%d].content[%d].image_url", index, j), "cannot load image: %v", err)
+				}
+				blocks = append(blocks, *block)
+			case "file":
+				block, err := convertDocument(models.ParseFilePart(partMap))
+				if err != nil {
+					return nil, models.NewRequestError(fmt.Sprintf("messages[%d].content[%d].file", index, j), "%v", err)
+				}
+				blocks = append(blocks, *block)
+			case "input_audio":
+				return nil, models.NewRequestError(fmt.Sprintf("messages[%d].content[%d].input_audio", index, j), "audio input is not supported by Claude models")
+			}
+
+			// content part 上的缓存断点
+			if cc := models.ParseCacheControl(partMap); cc != nil && len(blocks) > before {
+				blocks[len(blocks)-1].CacheControl = convertCacheControl(cc)
+			}
+		}
+		return blocks, nil
+	}
+	return nil, nil
+}
+
+// convertToolCalls 将 assistant 的 tool_calls 转换为 tool_use 块
+func convertToolCalls(toolCalls []models.ToolCall, index int) ([]ContentBlock, error) {
+	blocks := make([]ContentBlock, 0, len(toolCalls))
+	for k, tc := range toolCalls {
+		if tc.ID == "" {
+			return nil, models.NewRequestError(fmt.Sprintf("messages[%d].tool_calls[%d].id", index, k), "tool calls must have an id")
+		}
+		args := strings.TrimSpace(tc.Function.Arguments)
+		if args == "" {
+			args = "{}"
+		}
+		if !json.Valid([]byte(args)) {
+			return nil, models.NewRequestError(fmt.Sprintf("messages[%d].tool_calls[%d].function.arguments", index, k), "arguments must be a JSON object")
+		}
+		blocks = append(blocks, ContentBlock{
+			Type:  "tool_use",
+			ID:    tc.ID,
+			Name:  tc.Function.Name,
+			Input: json.RawMessage(args),
+		})
+	}
+	return blocks, nil
+}
+
+// thinkingContentBlocks 转换客户端回传的思考块
+// 没有签名的思考内容无法通过 Claude 校验，直接丢弃
+func thinkingContentBlocks(thinking []models.ThinkingBlock) []ContentBlock {
+	var blocks []ContentBlock
+	for _, tb := range thinking {
+		switch tb.Type {
+		case "redacted_thinking":
+			blocks = append(blocks, ContentBlock{Type: "redacted_thinking", Data: tb.Data})
+		default:
+			if tb.Signature == "" {
+				continue
+			}
+			blocks = append(blocks, ContentBlock{Type: "thinking", Thinking: tb.Thinking, Signature: tb.Signature})
+		}
+	}
+	return blocks
+}
@@ -1,18 +1,65 @@
 package anthropic
 
+import "encoding/json"
+
 // Claude API 原生格式定义
 
 // ChatRequest Claude API 聊天请求
 type ChatRequest struct {
-	Model         string    `json:"model"`
-	Messages      []Message `json:"messages"`
-	MaxTokens     int       `json:"max_tokens"`
-	Temperature   float64   `json:"temperature,omitempty"`
-	TopP          float64   `json:"top_p,omitempty"`
-	TopK          int       `json:"top_k,omitempty"`
-	Stream        bool      `json:"stream,omitempty"`
-	StopSequences []string  `json:"stop_sequences,omitempty"`
-	System        string    `json:"system,omitempty"`
+	Model         string         `json:"model"`
+	Messages      []Message      `json:"messages"`
+	MaxTokens     int            `json:"max_tokens"`
+	Temperature   *float64       `json:"temperature,omitempty"`
+	TopP          *float64       `json:"top_p,omitempty"`
+	TopK          int            `json:"top_k,omitempty"`
+	Stream        bool           `json:"stream,omitempty"`
+	StopSequences []string       `json:"stop_sequences,omitempty"`
+	System        []ContentBlock `json:"system,omitempty"`
+	Tools         []Tool         `json:"tools,omitempty"`
+	ToolChoice    *ToolChoice    `json:"tool_choice,omitempty"`
+	Metadata      *Metadata      `json:"metadata,omitempty"`
+	Thinking      *Thinking      `json:"thinking,omitempty"`
+}
+
+// Thinking extended thinking 配置
+type Thinking struct {
+	Type         string `json:"type"` // enabled
+	BudgetTokens int    `json:"budget_tokens"`
+}
+
+type Metadata struct {
+	UserID string `json:"user_id,omitempty"`
+}
+
+type Tool struct {
+	Type         string         `json:"type,omitempty"` // 服务端工具类型，如 web_search_20250305；自定义工具为空
+	Name         string         `json:"name"`
+	Description  string         `json:"description,omitempty"`
+	InputSchema  map[string]any `json:"input_schema,omitempty"`
+	CacheControl *CacheControl  `json:"cache_control,omitempty"`
+
+	UserLocation *UserLocation `json:"user_location,omitempty"` // web_search
+}
+
+// UserLocation web_search 工具用于优化搜索结果的大致位置
+type UserLocation struct {
+	Type     string `json:"type"` // approximate
+	City     string `json:"city,omitempty"`
+	Region   string `json:"region,omitempty"`
+	Country  string `json:"country,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// CacheControl prompt caching 断点
+type CacheControl struct {
+	Type string `json:"type"` // ephemeral
+	TTL  string `json:"ttl,omitempty"`
+}
+
+type ToolChoice struct {
+	Type                   string `json:"type"` // auto, any, tool, none
+	Name                   string `json:"name,omitempty"`
+	DisableParallelToolUse bool   `json:"disable_parallel_tool_use,omitempty"`
 }
 
 type Message struct {
@@ -21,15 +68,44 @@ type Message struct {
 }
 
 type ContentBlock struct {
-	Type   string      `json:"type"`
-	Text   string      `json:"text,omitempty"`
-	Source *ImageSource `json:"source,omitempty"`
+	Type   string       `json:"type"`
+	Text   string       `json:"text,omitempty"`
+	Source *ImageSource `json:"source,omitempty"` // image / document
+	Title  string       `json:"title,omitempty"`  // document
+
+	CacheControl *CacheControl `json:"cache_control,omitempty"`
+
+	// tool_use
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// tool_result
+	ToolUseID string         `json:"tool_use_id,omitempty"`
+	Content   []ContentBlock `json:"content,omitempty"`
+
+	// thinking / redacted_thinking
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Data      string `json:"data,omitempty"`
+
+	// text 块的引用来源（web_search 等）
+	Citations []Citation `json:"citations,omitempty"`
 }
 
-type ImageSource struct {
+// Citation 文本块的引用，只建模 web_search_result_location 使用的字段
+type Citation struct {
 	Type      string `json:"type"`
-	MediaType string `json:"media_type"`
-	Data      string `json:"data"`
+	CitedText string `json:"cited_text,omitempty"`
+	URL       string `json:"url,omitempty"`
+	Title     string `json:"title,omitempty"`
+}
+
+type ImageSource struct {
+	Type      string `json:"type"` // base64、url 或 text（纯文本 document）
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
 }
 
 // ChatResponse Claude API 聊天响应
@@ -45,25 +121,31 @@ type ChatResponse struct {
 }
 
 type Usage struct {
-	InputTokens  int `json:"input_tokens"`
-	OutputTokens int `json:"output_tokens"`
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
 }
 
 // StreamEvent Claude API 流式事件
 type StreamEvent struct {
-	Type         string         `json:"type"`
-	Message      *ChatResponse  `json:"message,omitempty"`
-	Index        int            `json:"index,omitempty"`
-	ContentBlock *ContentBlock  `json:"content_block,omitempty"`
-	Delta        *StreamDelta   `json:"delta,omitempty"`
-	Usage        *Usage         `json:"usage,omitempty"`
+	Type         string        `json:"type"`
+	Message      *ChatResponse `json:"message,omitempty"`
+	Index        int           `json:"index,omitempty"`
+	ContentBlock *ContentBlock `json:"content_block,omitempty"`
+	Delta        *StreamDelta  `json:"delta,omitempty"`
+	Usage        *Usage        `json:"usage,omitempty"`
 }
 
 type StreamDelta struct {
-	Type         string `json:"type"`
-	Text         string `json:"text,omitempty"`
-	StopReason   string `json:"stop_reason,omitempty"`
-	StopSequence string `json:"stop_sequence,omitempty"`
+	Type         string    `json:"type"`
+	Text         string    `json:"text,omitempty"`
+	PartialJSON  string    `json:"partial_json,omitempty"` // input_json_delta
+	Thinking     string    `json:"thinking,omitempty"`     // thinking_delta
+	Signature    string    `json:"signature,omitempty"`    // signature_delta
+	Citation     *Citation `json:"citation,omitempty"`     // citations_delta
+	StopReason   string    `json:"stop_reason,omitempty"`
+	StopSequence string    `json:"stop_sequence,omitempty"`
 }
 
 // ErrorResponse Claude API 错误响应
@@ -77,3 +159,17 @@ type Error struct {
 	Message string `json:"message"`
 }
 
+// CountTokensRequest /v1/messages/count_tokens 请求，只接受影响输入的字段
+type CountTokensRequest struct {
+	Model      string         `json:"model"`
+	Messages   []Message      `json:"messages"`
+	System     []ContentBlock `json:"system,omitempty"`
+	Tools      []Tool         `json:"tools,omitempty"`
+	ToolChoice *ToolChoice    `json:"tool_choice,omitempty"`
+	Thinking   *Thinking      `json:"thinking,omitempty"`
+}
+
+// CountTokensResponse /v1/messages/count_tokens 响应
+type CountTokensResponse struct {
+	InputTokens int `json:"input_tokens"`
+}
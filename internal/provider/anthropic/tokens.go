@@ -0,0 +1,65 @@
+package anthropic
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"openbridge/internal/models"
+	"time"
+)
+
+// CountTokens 通过 /v1/messages/count_tokens 计算输入 token 数
+// 请求与 ChatCompletion 使用相同的转换，结果包含 system、tools 以及 response_format 模拟工具的开销
+func (p *Provider) CountTokens(req *models.ChatCompletionRequest, apiKey string) (*models.TokenCountResponse, error) {
+	claudeReq, err := ConvertFromOpenAI(req, p.convert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert request: %w", err)
+	}
+
+	reqBody, err := json.Marshal(CountTokensRequest{
+		Model:      claudeReq.Model,
+		Messages:   claudeReq.Messages,
+		System:     claudeReq.System,
+		Tools:      claudeReq.Tools,
+		ToolChoice: claudeReq.ToolChoice,
+		Thinking:   claudeReq.Thinking,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := p.baseURL + "/v1/messages/count_tokens"
+	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", apiKey)
+	httpReq.Header.Set("anthropic-version", p.version)
+
+	client := &http.Client{Timeout: 30 * time.Second, Transport: p.transport}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp.StatusCode, body)
+	}
+
+	var countResp CountTokensResponse
+	if err := json.Unmarshal(body, &countResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return &models.TokenCountResponse{InputTokens: countResp.InputTokens}, nil
+}
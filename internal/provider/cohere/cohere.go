@@ -0,0 +1,293 @@
+package cohere
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"openbridge/internal/config"
+	"openbridge/internal/models"
+	"openbridge/internal/provider"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Provider Cohere v2 Chat API 提供商实现
+type Provider struct {
+	name      string
+	baseURL   string
+	transport http.RoundTripper // 为 nil 时使用 http.DefaultTransport
+}
+
+// New 创建新的 Cohere Provider
+func New(name, baseURL string) *Provider {
+	if baseURL == "" {
+		baseURL = "https://api.cohere.com"
+	}
+	// 确保 baseURL 不以 / 结尾
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	return &Provider{
+		name:    name,
+		baseURL: baseURL,
+	}
+}
+
+func init() {
+	provider.RegisterFactory(func(name string, cfg config.ProviderConfig) (provider.Provider, error) {
+		return New(name, cfg.BaseURL), nil
+	}, "cohere")
+}
+
+// SetTransport 替换访问上游使用的 http.RoundTripper
+func (p *Provider) SetTransport(rt http.RoundTripper) {
+	p.transport = rt
+}
+
+func (p *Provider) Name() string {
+	return p.name
+}
+
+func (p *Provider) Type() string {
+	return "cohere"
+}
+
+func (p *Provider) SupportsStreaming() bool {
+	return true
+}
+
+// ChatCompletion 发送非流式聊天请求
+func (p *Provider) ChatCompletion(req *models.ChatCompletionRequest, apiKey string) (*models.ChatCompletionResponse, error) {
+	// 转换为 Cohere 格式
+	cohereReq, err := ConvertFromOpenAI(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert request: %w", err)
+	}
+
+	cohereReq.Stream = false
+
+	reqBody, err := json.Marshal(cohereReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := p.baseURL + "/v2/chat"
+	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+
+	client := &http.Client{Timeout: 120 * time.Second, Transport: p.transport}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, parseAPIError(resp.StatusCode, body)
+	}
+
+	var cohereResp ChatResponse
+	if err := json.Unmarshal(body, &cohereResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	// 转换为 OpenAI 格式
+	openaiResp := ConvertToOpenAI(&cohereResp, req.Model)
+	openaiResp.Created = time.Now().Unix()
+
+	return openaiResp, nil
+}
+
+// ChatCompletionStream 发送流式聊天请求
+func (p *Provider) ChatCompletionStream(req *models.ChatCompletionRequest, apiKey string) (<-chan *models.ChatCompletionChunk, <-chan error) {
+	chunkChan := make(chan *models.ChatCompletionChunk, 100)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(chunkChan)
+		defer close(errChan)
+
+		// 转换为 Cohere 格式
+		cohereReq, err := ConvertFromOpenAI(req)
+		if err != nil {
+			errChan <- fmt.Errorf("failed to convert request: %w", err)
+			return
+		}
+
+		cohereReq.Stream = true
+
+		reqBody, err := json.Marshal(cohereReq)
+		if err != nil {
+			errChan <- fmt.Errorf("failed to marshal request: %w", err)
+			return
+		}
+
+		url := p.baseURL + "/v2/chat"
+		httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(reqBody))
+		if err != nil {
+			errChan <- fmt.Errorf("failed to create request: %w", err)
+			return
+		}
+
+		httpReq.Header.Set("Content-Type", "application/json")
+		httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+		httpReq.Header.Set("Accept", "text/event-stream")
+
+		client := &http.Client{Timeout: 120 * time.Second, Transport: p.transport}
+		resp, err := client.Do(httpReq)
+		if err != nil {
+			errChan <- fmt.Errorf("failed to send request: %w", err)
+			return
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			errChan <- parseAPIError(resp.StatusCode, body)
+			return
+		}
+
+		// 生成唯一的 chunk ID
+		chunkID := "chatcmpl-" + uuid.New().String()
+
+		// 解析 SSE 流
+		scanner := bufio.NewScanner(resp.Body)
+		buf := make([]byte, 0, 64*1024)
+		scanner.Buffer(buf, 1024*1024)
+
+		for scanner.Scan() {
+			line := scanner.Text()
+
+			if line == "" {
+				continue
+			}
+
+			// Cohere 的 SSE 格式: "event: xxx" 和 "data: xxx"，data 中同样带有 type
+			if !strings.HasPrefix(line, "data: ") {
+				continue
+			}
+
+			data := strings.TrimPrefix(line, "data: ")
+			if data == "[DONE]" {
+				return
+			}
+
+			var event StreamEvent
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				log.Printf("Failed to parse Cohere stream event: %v, data: %s", err, data)
+				continue
+			}
+
+			// 转换为 OpenAI 格式的 chunk
+			if chunk := ConvertStreamEventToChunk(&event, chunkID, req.Model); chunk != nil {
+				chunk.Created = time.Now().Unix()
+				chunkChan <- chunk
+			}
+
+			if event.Type == "message-end" {
+				return
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			errChan <- fmt.Errorf("stream read error: %w", err)
+		}
+	}()
+
+	return chunkChan, errChan
+}
+
+// ListModels 获取模型列表
+func (p *Provider) ListModels(apiKey string) (*models.ModelList, error) {
+	// 只列出支持 chat 端点的模型
+	url := p.baseURL + "/v1/models?endpoint=chat&page_size=1000"
+
+	httpReq, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+
+	client := &http.Client{Timeout: 30 * time.Second, Transport: p.transport}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, parseAPIError(resp.StatusCode, body)
+	}
+
+	var cohereModels ModelsListResponse
+	if err := json.Unmarshal(body, &cohereModels); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	// 转换为 OpenAI 格式
+	modelList := &models.ModelList{
+		Object: "list",
+		Data:   make([]models.Model, 0, len(cohereModels.Models)),
+	}
+	for _, m := range cohereModels.Models {
+		modelList.Data = append(modelList.Data, models.Model{
+			ID:      m.Name,
+			Object:  "model",
+			Created: time.Now().Unix(),
+			OwnedBy: "cohere",
+		})
+	}
+
+	return modelList, nil
+}
+
+// parseAPIError 解析 Cohere 错误响应
+func parseAPIError(statusCode int, body []byte) error {
+	var errResp ErrorResponse
+	if err := json.Unmarshal(body, &errResp); err == nil && errResp.Message != "" {
+		return &APIError{
+			StatusCode: statusCode,
+			Message:    errResp.Message,
+		}
+	}
+	return &APIError{
+		StatusCode: statusCode,
+		Message:    string(body),
+	}
+}
+
+// APIError API 错误
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+// HTTPStatus 返回上游 HTTP 状态码，供 handler 原样返回给客户端
+func (e *APIError) HTTPStatus() int {
+	return e.StatusCode
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("Cohere API error (status %d): %s", e.StatusCode, e.Message)
+}
@@ -0,0 +1,365 @@
+package cohere
+
+import (
+	"encoding/json"
+	"fmt"
+	"openbridge/internal/models"
+	"strings"
+)
+
+// ConvertFromOpenAI 将 OpenAI 格式转换为 Cohere v2 格式
+func ConvertFromOpenAI(req *models.ChatCompletionRequest) (*ChatRequest, error) {
+	cohereReq := &ChatRequest{
+		Model:            req.Model,
+		Messages:         make([]Message, 0, len(req.Messages)),
+		Stream:           req.Stream,
+		MaxTokens:        req.MaxOutputTokens(),
+		Temperature:      req.Temperature,
+		P:                req.TopP,
+		FrequencyPenalty: req.FrequencyPenalty,
+		PresencePenalty:  req.PresencePenalty,
+		K:                req.TopK,
+		Seed:             req.Seed,
+	}
+
+	if req.WantsAudio() {
+		return nil, models.NewRequestError("modalities", "audio output is not supported by Cohere models")
+	}
+	if len(req.LogitBias) > 0 {
+		return nil, models.NewRequestError("logit_bias", "logit_bias is not supported by Cohere models")
+	}
+	if req.WebSearchOptions != nil {
+		return nil, models.NewRequestError("web_search_options", "web search is not supported by Cohere models")
+	}
+	if req.Logprobs || req.TopLogprobs > 0 {
+		return nil, models.NewRequestError("logprobs", "logprobs are not supported by Cohere models")
+	}
+	if req.Thinking != nil {
+		return nil, models.NewRequestError("thinking", "thinking is not supported by Cohere models")
+	}
+	if req.ReasoningEffort != "" {
+		return nil, models.NewRequestError("reasoning_effort", "reasoning_effort is not supported by Cohere models")
+	}
+	if req.N > 1 {
+		return nil, models.NewRequestError("n", "Cohere models return a single choice, n must be 1")
+	}
+	if req.ParallelToolCalls != nil && !*req.ParallelToolCalls && len(req.Tools) > 0 {
+		return nil, models.NewRequestError("parallel_tool_calls", "Cohere models cannot disable parallel tool calls")
+	}
+
+	stops, err := req.StopSequences()
+	if err != nil {
+		return nil, err
+	}
+	cohereReq.StopSequences = stops
+
+	// 转换 messages
+	for i, msg := range req.Messages {
+		cohereMsg := Message{
+			Role: msg.Role,
+		}
+
+		switch {
+		case msg.IsSystem():
+			// developer 是新版 OpenAI 对 system 的称呼，Cohere 只认 system
+			cohereMsg.Role = "system"
+			content, err := convertContent(msg.Content, i)
+			if err != nil {
+				return nil, err
+			}
+			cohereMsg.Content = content
+
+		case msg.Role == "tool" || msg.Role == "function":
+			// Cohere 的工具结果使用 role: tool + tool_call_id
+			cohereMsg.Role = "tool"
+			cohereMsg.ToolCallID = msg.ToolCallID
+			cohereMsg.Content = extractText(msg.Content)
+
+		case msg.Role == "assistant":
+			if text := extractText(msg.Content); text != "" {
+				if len(msg.ToolCalls) > 0 {
+					// 带工具调用的 assistant 消息，文本作为 tool_plan
+					cohereMsg.ToolPlan = text
+				} else {
+					cohereMsg.Content = text
+				}
+			}
+			for _, tc := range msg.ToolCalls {
+				cohereMsg.ToolCalls = append(cohereMsg.ToolCalls, ToolCall{
+					ID:   tc.ID,
+					Type: "function",
+					Function: FunctionCall{
+						Name:      tc.Function.Name,
+						Arguments: tc.Function.Arguments,
+					},
+				})
+			}
+
+		default:
+			content, err := convertContent(msg.Content, i)
+			if err != nil {
+				return nil, err
+			}
+			cohereMsg.Content = content
+		}
+
+		cohereReq.Messages = append(cohereReq.Messages, cohereMsg)
+	}
+
+	// 转换 tools
+	for _, tool := range req.Tools {
+		if tool.Type != "" && tool.Type != "function" {
+			continue
+		}
+		cohereReq.Tools = append(cohereReq.Tools, Tool{
+			Type: "function",
+			Function: Function{
+				Name:        tool.Function.Name,
+				Description: tool.Function.Description,
+				Parameters:  tool.Function.Parameters,
+			},
+		})
+	}
+
+	// tool_choice: Cohere 只支持 REQUIRED / NONE，auto 为默认行为
+	if choice, ok := req.ToolChoice.(string); ok {
+		switch choice {
+		case "required":
+			cohereReq.ToolChoice = "REQUIRED"
+		case "none":
+			cohereReq.ToolChoice = "NONE"
+		}
+	} else if req.ToolChoice != nil {
+		// 指定具体函数时退化为 REQUIRED
+		cohereReq.ToolChoice = "REQUIRED"
+	}
+
+	if req.ResponseFormat != nil {
+		switch req.ResponseFormat.Type {
+		case "json_object":
+			cohereReq.ResponseFormat = &ResponseFormat{Type: "json_object"}
+		case "json_schema":
+			// Cohere 使用 json_object + json_schema 表达结构化输出
+			cohereReq.ResponseFormat = &ResponseFormat{Type: "json_object"}
+			if req.ResponseFormat.JSONSchema != nil {
+				cohereReq.ResponseFormat.JSONSchema = req.ResponseFormat.JSONSchema.Schema
+			}
+		}
+	}
+
+	return cohereReq, nil
+}
+
+// convertContent 转换 user/system 消息的 content，index 为消息序号，用于错误定位
+func convertContent(content interface{}, index int) (interface{}, error) {
+	switch v := content.(type) {
+	case string:
+		return v, nil
+	case []interface{}:
+		blocks := make([]ContentBlock, 0, len(v))
+		for j, part := range v {
+			partMap, ok := part.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			typeStr, _ := partMap["type"].(string)
+			switch typeStr {
+			case "text":
+				if text, ok := partMap["text"].(string); ok {
+					blocks = append(blocks, ContentBlock{Type: "text", Text: text})
+				}
+			case "image_url":
+				imageURL, ok := partMap["image_url"].(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if url, _ := imageURL["url"].(string); url != "" {
+					blocks = append(blocks, ContentBlock{Type: "image_url", ImageURL: &ImageURL{URL: url}})
+				}
+			case "file":
+				return nil, models.NewRequestError(fmt.Sprintf("messages[%d].content[%d].file", index, j), "file content is not supported by Cohere models")
+			case "input_audio":
+				return nil, models.NewRequestError(fmt.Sprintf("messages[%d].content[%d].input_audio", index, j), "audio input is not supported by Cohere models")
+			}
+		}
+		return blocks, nil
+	}
+	return "", nil
+}
+
+// extractText 从 content 中提取纯文本
+func extractText(content interface{}) string {
+	switch v := content.(type) {
+	case string:
+		return v
+	case []interface{}:
+		var texts []string
+		for _, part := range v {
+			if partMap, ok := part.(map[string]interface{}); ok {
+				if text, ok := partMap["text"].(string); ok {
+					texts = append(texts, text)
+				}
+			}
+		}
+		return strings.Join(texts, "\n")
+	}
+	return ""
+}
+
+// ConvertToOpenAI 将 Cohere 格式转换为 OpenAI 格式
+func ConvertToOpenAI(resp *ChatResponse, requestModel string) *models.ChatCompletionResponse {
+	var content strings.Builder
+	for _, block := range resp.Message.Content {
+		if block.Type == "text" {
+			content.WriteString(block.Text)
+		}
+	}
+
+	message := models.ResponseMessage{
+		Role:    "assistant",
+		Content: content.String(),
+	}
+	if message.Content == "" && len(resp.Message.ToolCalls) > 0 {
+		// 没有正文时，把 tool_plan 作为可见内容
+		message.Content = resp.Message.ToolPlan
+	}
+	for _, tc := range resp.Message.ToolCalls {
+		message.ToolCalls = append(message.ToolCalls, models.ToolCall{
+			ID:   tc.ID,
+			Type: "function",
+			Function: models.FunctionCall{
+				Name:      tc.Function.Name,
+				Arguments: tc.Function.Arguments,
+			},
+		})
+	}
+
+	openaiResp := &models.ChatCompletionResponse{
+		ID:      resp.ID,
+		Object:  "chat.completion",
+		Created: 0,
+		Model:   requestModel,
+		Choices: []models.Choice{
+			{
+				Index:        0,
+				Message:      message,
+				FinishReason: convertFinishReason(resp.FinishReason),
+			},
+		},
+	}
+
+	if usage := convertUsage(resp.Usage); usage != nil {
+		openaiResp.Usage = *usage
+	}
+
+	return openaiResp
+}
+
+// ConvertStreamEventToChunk 将 Cohere 流式事件转换为 OpenAI 流式块
+// 返回 nil 表示该事件不需要下发
+func ConvertStreamEventToChunk(event *StreamEvent, chunkID string, requestModel string) *models.ChatCompletionChunk {
+	chunk := &models.ChatCompletionChunk{
+		ID:      chunkID,
+		Object:  "chat.completion.chunk",
+		Created: 0,
+		Model:   requestModel,
+		Choices: []models.ChunkChoice{
+			{
+				Index: 0,
+				Delta: models.ChunkDelta{},
+			},
+		},
+	}
+
+	switch event.Type {
+	case "message-start":
+		chunk.Choices[0].Delta.Role = "assistant"
+
+	case "content-delta":
+		if event.Delta == nil || event.Delta.Message == nil {
+			return nil
+		}
+		var content struct {
+			Text string `json:"text"`
+		}
+		if err := json.Unmarshal(event.Delta.Message.Content, &content); err != nil || content.Text == "" {
+			return nil
+		}
+		chunk.Choices[0].Delta.Content = content.Text
+
+	case "tool-call-start", "tool-call-delta":
+		if event.Delta == nil || event.Delta.Message == nil {
+			return nil
+		}
+		var tc ToolCall
+		if err := json.Unmarshal(event.Delta.Message.ToolCalls, &tc); err != nil {
+			return nil
+		}
+		index := event.Index
+		toolCall := models.ToolCall{
+			Index: &index,
+			Function: models.FunctionCall{
+				Arguments: tc.Function.Arguments,
+			},
+		}
+		if event.Type == "tool-call-start" {
+			toolCall.ID = tc.ID
+			toolCall.Type = "function"
+			toolCall.Function.Name = tc.Function.Name
+		}
+		chunk.Choices[0].Delta.ToolCalls = []models.ToolCall{toolCall}
+
+	case "message-end":
+		if event.Delta == nil {
+			return nil
+		}
+		finishReason := convertFinishReason(event.Delta.FinishReason)
+		chunk.Choices[0].FinishReason = &finishReason
+		chunk.Usage = convertUsage(event.Delta.Usage)
+
+	default:
+		// content-start, content-end, tool-plan-delta, tool-call-end 等无需下发
+		return nil
+	}
+
+	return chunk
+}
+
+// convertUsage 将 Cohere usage 转换为 OpenAI usage，优先使用 billed_units
+func convertUsage(usage *Usage) *models.Usage {
+	if usage == nil {
+		return nil
+	}
+
+	counts := usage.BilledUnits
+	if counts == nil || (counts.InputTokens == 0 && counts.OutputTokens == 0) {
+		counts = usage.Tokens
+	}
+	if counts == nil {
+		return nil
+	}
+
+	input := int(counts.InputTokens)
+	output := int(counts.OutputTokens)
+	return &models.Usage{
+		PromptTokens:     input,
+		CompletionTokens: output,
+		TotalTokens:      input + output,
+	}
+}
+
+func convertFinishReason(cohereReason string) string {
+	switch cohereReason {
+	case "COMPLETE", "STOP_SEQUENCE":
+		return "stop"
+	case "MAX_TOKENS", "ERROR_LIMIT":
+		return "length"
+	case "TOOL_CALL":
+		return "tool_calls"
+	case "ERROR_TOXIC":
+		return "content_filter"
+	default:
+		return "stop"
+	}
+}
@@ -0,0 +1,133 @@
+package cohere
+
+import "encoding/json"
+
+// Cohere v2 Chat API 原生格式定义
+
+// ChatRequest Cohere v2 聊天请求
+type ChatRequest struct {
+	Model            string          `json:"model"`
+	Messages         []Message       `json:"messages"`
+	Tools            []Tool          `json:"tools,omitempty"`
+	ToolChoice       string          `json:"tool_choice,omitempty"` // REQUIRED 或 NONE
+	ResponseFormat   *ResponseFormat `json:"response_format,omitempty"`
+	Stream           bool            `json:"stream"`
+	MaxTokens        int             `json:"max_tokens,omitempty"`
+	Temperature      *float64        `json:"temperature,omitempty"`
+	P                *float64        `json:"p,omitempty"`
+	K                int             `json:"k,omitempty"`
+	StopSequences    []string        `json:"stop_sequences,omitempty"`
+	Seed             *int            `json:"seed,omitempty"`
+	FrequencyPenalty float64         `json:"frequency_penalty,omitempty"`
+	PresencePenalty  float64         `json:"presence_penalty,omitempty"`
+}
+
+type Message struct {
+	Role       string      `json:"role"`              // system, user, assistant, tool
+	Content    interface{} `json:"content,omitempty"` // 可以是 string 或 ContentBlock 数组
+	ToolCalls  []ToolCall  `json:"tool_calls,omitempty"`
+	ToolPlan   string      `json:"tool_plan,omitempty"`
+	ToolCallID string      `json:"tool_call_id,omitempty"`
+}
+
+type ContentBlock struct {
+	Type     string    `json:"type"` // text 或 image_url
+	Text     string    `json:"text,omitempty"`
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+}
+
+type ImageURL struct {
+	URL string `json:"url"`
+}
+
+type Tool struct {
+	Type     string   `json:"type"`
+	Function Function `json:"function"`
+}
+
+type Function struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters,omitempty"`
+}
+
+type ToolCall struct {
+	ID       string       `json:"id,omitempty"`
+	Type     string       `json:"type,omitempty"`
+	Function FunctionCall `json:"function"`
+}
+
+type FunctionCall struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+}
+
+type ResponseFormat struct {
+	Type       string         `json:"type"` // text 或 json_object
+	JSONSchema map[string]any `json:"json_schema,omitempty"`
+}
+
+// ChatResponse Cohere v2 聊天响应
+type ChatResponse struct {
+	ID           string          `json:"id"`
+	FinishReason string          `json:"finish_reason"`
+	Message      ResponseMessage `json:"message"`
+	Usage        *Usage          `json:"usage,omitempty"`
+}
+
+type ResponseMessage struct {
+	Role      string         `json:"role"`
+	Content   []ContentBlock `json:"content,omitempty"`
+	ToolCalls []ToolCall     `json:"tool_calls,omitempty"`
+	ToolPlan  string         `json:"tool_plan,omitempty"`
+}
+
+type Usage struct {
+	BilledUnits *TokenCounts `json:"billed_units,omitempty"`
+	Tokens      *TokenCounts `json:"tokens,omitempty"`
+}
+
+type TokenCounts struct {
+	InputTokens  float64 `json:"input_tokens"`
+	OutputTokens float64 `json:"output_tokens"`
+}
+
+// StreamEvent Cohere v2 流式事件
+// 事件类型: message-start, content-start, content-delta, content-end,
+// tool-plan-delta, tool-call-start, tool-call-delta, tool-call-end, message-end
+type StreamEvent struct {
+	Type  string       `json:"type"`
+	ID    string       `json:"id,omitempty"`
+	Index int          `json:"index"`
+	Delta *StreamDelta `json:"delta,omitempty"`
+}
+
+type StreamDelta struct {
+	Message      *StreamMessage `json:"message,omitempty"`
+	FinishReason string         `json:"finish_reason,omitempty"`
+	Usage        *Usage         `json:"usage,omitempty"`
+}
+
+type StreamMessage struct {
+	Role      string          `json:"role,omitempty"`
+	Content   json.RawMessage `json:"content,omitempty"`    // content-delta 时为 {"text": "..."}
+	ToolCalls json.RawMessage `json:"tool_calls,omitempty"` // tool-call-* 时为单个 ToolCall 对象
+	ToolPlan  string          `json:"tool_plan,omitempty"`
+}
+
+// ErrorResponse Cohere API 错误响应
+type ErrorResponse struct {
+	Message string `json:"message"`
+}
+
+// ModelsListResponse 模型列表响应
+type ModelsListResponse struct {
+	Models        []ModelInfo `json:"models"`
+	NextPageToken string      `json:"next_page_token,omitempty"`
+}
+
+type ModelInfo struct {
+	Name          string   `json:"name"`
+	Endpoints     []string `json:"endpoints"`
+	ContextLength float64  `json:"context_length"`
+}
@@ -0,0 +1,88 @@
+package provider
+
+import (
+	"fmt"
+	"log"
+	"openbridge/internal/cassette"
+	"openbridge/internal/config"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Factory 根据配置块创建 Provider
+// name 为配置中的 Provider 名称，cfg 为完整配置（包含 options 中的类型专属选项）
+type Factory func(name string, cfg config.ProviderConfig) (Provider, error)
+
+var (
+	factories   = make(map[string]Factory)
+	factoriesMu sync.RWMutex
+)
+
+// RegisterFactory 注册 Provider 类型的构造函数，一个构造函数可以对应多个类型别名
+// 各 Provider 包在 init() 中自行注册
+func RegisterFactory(factory Factory, types ...string) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	for _, t := range types {
+		t = strings.ToLower(t)
+		if _, exists := factories[t]; exists {
+			panic(fmt.Sprintf("provider: factory for type %q registered twice", t))
+		}
+		factories[t] = factory
+	}
+}
+
+// NewFromConfig 根据配置中的 type 创建 Provider，未知类型直接返回错误
+func NewFromConfig(name string, cfg config.ProviderConfig) (Provider, error) {
+	factoriesMu.RLock()
+	factory, ok := factories[strings.ToLower(cfg.Type)]
+	factoriesMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown provider type %q for provider %q (supported: %s)",
+			cfg.Type, name, strings.Join(SupportedTypes(), ", "))
+	}
+
+	p, err := factory(name, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider %q (%s): %w", name, cfg.Type, err)
+	}
+
+	if cfg.Cassette != nil {
+		setter, ok := p.(TransportSetter)
+		if !ok {
+			return nil, fmt.Errorf("provider %q (%s) does not support cassettes", name, cfg.Type)
+		}
+		rt, err := cassette.NewTransport(cfg.Cassette.Mode, cfg.Cassette.Dir, cfg.Cassette.ScrubFields, nil)
+		if err != nil {
+			return nil, fmt.Errorf("provider %q: %w", name, err)
+		}
+		setter.SetTransport(rt)
+		log.Printf("📼 Provider %s cassette: %s (%s)", name, cfg.Cassette.Mode, cfg.Cassette.Dir)
+	}
+
+	return p, nil
+}
+
+// IsSupportedType 判断 Provider 类型是否已注册
+func IsSupportedType(providerType string) bool {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	_, ok := factories[strings.ToLower(providerType)]
+	return ok
+}
+
+// SupportedTypes 列出所有已注册的 Provider 类型（含别名），按字母排序
+func SupportedTypes() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	types := make([]string, 0, len(factories))
+	for t := range factories {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
@@ -0,0 +1,301 @@
+package google
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 上下文缓存 (cachedContents)
+// 客户端用 cache_control 标记稳定的前缀（system 和前几条消息），网关为该前缀创建 cachedContents 资源，
+// 之后相同前缀的请求只发送剩余的消息并通过 cachedContent 引用缓存
+
+// defaultCacheTTL cachedContents 的默认有效期
+const defaultCacheTTL = 5 * time.Minute
+
+// cacheExpiryMargin 提前视为过期，避免请求到达时缓存刚好失效
+const cacheExpiryMargin = 30 * time.Second
+
+// CachedContent Gemini cachedContents 资源
+type CachedContent struct {
+	Name              string    `json:"name,omitempty"`
+	Model             string    `json:"model,omitempty"`
+	SystemInstruction *Content  `json:"systemInstruction,omitempty"`
+	Contents          []Content `json:"contents,omitempty"`
+	Tools             []Tool    `json:"tools,omitempty"`
+	TTL               string    `json:"ttl,omitempty"`
+	ExpireTime        string    `json:"expireTime,omitempty"`
+}
+
+// 创建缓存失败后在一段时间内不再尝试，避免每个请求都多一次注定失败的 cachedContents.create
+const (
+	cacheFailureTTL          = 10 * time.Minute // 请求本身导致的失败（如前缀低于最小缓存 token 数）
+	cacheTransientFailureTTL = 30 * time.Second // 限流、上游错误等暂时性失败
+)
+
+// contextCache 前缀哈希 -> cachedContents 资源名
+type contextCache struct {
+	mu       sync.Mutex
+	entries  map[string]cacheEntry
+	failures map[string]time.Time  // 创建失败的键 -> 下次允许尝试的时间
+	inflight map[string]*cacheCall // 正在创建的键，并发的请求等待同一次创建
+}
+
+type cacheEntry struct {
+	name    string
+	expires time.Time
+}
+
+// cacheCall 一次进行中的创建
+type cacheCall struct {
+	done chan struct{}
+	name string
+	err  error
+}
+
+func newContextCache() *contextCache {
+	return &contextCache{
+		entries:  make(map[string]cacheEntry),
+		failures: make(map[string]time.Time),
+		inflight: make(map[string]*cacheCall),
+	}
+}
+
+// getOrCreate 返回键对应的缓存资源名，不存在时调用 create 创建
+// 同一个键同时只会创建一次，其他请求等待并共享结果；失败会被记住，在 failureTTL 内直接返回失败
+func (c *contextCache) getOrCreate(key string, create func() (cacheEntry, error)) (string, error) {
+	c.mu.Lock()
+	now := time.Now()
+	if entry, ok := c.entries[key]; ok && now.Add(cacheExpiryMargin).Before(entry.expires) {
+		c.mu.Unlock()
+		return entry.name, nil
+	}
+	if until, ok := c.failures[key]; ok && now.Before(until) {
+		c.mu.Unlock()
+		return "", errCacheRecentlyFailed
+	}
+	if call, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+		<-call.done
+		return call.name, call.err
+	}
+	call := &cacheCall{done: make(chan struct{})}
+	c.inflight[key] = call
+	c.mu.Unlock()
+
+	entry, err := create()
+
+	c.mu.Lock()
+	delete(c.inflight, key)
+	if err != nil {
+		c.failures[key] = time.Now().Add(failureTTL(err))
+	} else {
+		c.put(key, entry)
+	}
+	c.mu.Unlock()
+
+	call.name, call.err = entry.name, err
+	close(call.done)
+	return call.name, call.err
+}
+
+// put 保存缓存条目，调用方需持有锁
+func (c *contextCache) put(key string, entry cacheEntry) {
+	// 顺便清理过期条目
+	now := time.Now()
+	for k, e := range c.entries {
+		if now.After(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	for k, until := range c.failures {
+		if now.After(until) {
+			delete(c.failures, k)
+		}
+	}
+	delete(c.failures, key)
+	c.entries[key] = entry
+}
+
+func (c *contextCache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+// errCacheRecentlyFailed 该前缀最近创建缓存失败，暂不重试
+var errCacheRecentlyFailed = errors.New("context cache creation failed recently, skipping")
+
+// failureTTL 4xx（限流除外）说明这个前缀本身无法缓存，较长时间内不再尝试；其他错误很快重试
+func failureTTL(err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode >= 400 && apiErr.StatusCode < 500 &&
+		apiErr.StatusCode != http.StatusTooManyRequests {
+		return cacheFailureTTL
+	}
+	return cacheTransientFailureTTL
+}
+
+// isCacheMissError 请求引用的 cachedContent 已过期或在上游被删除
+// Gemini 对不存在的缓存返回 403 "CachedContent not found (or permission denied)" 或 404
+func isCacheMissError(err *APIError) bool {
+	switch err.StatusCode {
+	case http.StatusNotFound, http.StatusForbidden, http.StatusBadRequest:
+		return strings.Contains(strings.ToLower(err.Message), "cachedcontent") ||
+			strings.Contains(strings.ToLower(err.Message), "cached content")
+	}
+	return false
+}
+
+// applyContextCache 将请求中标记了 cache_control 的前缀替换为 cachedContent 引用
+// 返回使用的缓存键，未使用缓存时返回空字符串；创建缓存失败时退回到完整请求
+func (p *Provider) applyContextCache(geminiReq *GenerateContentRequest, model, apiKey string) string {
+	if !geminiReq.cacheMarked {
+		return ""
+	}
+
+	// 缓存之外至少保留一条消息
+	prefix := geminiReq.cachePrefix
+	if prefix >= len(geminiReq.Contents) {
+		prefix = len(geminiReq.Contents) - 1
+	}
+	if prefix < 0 {
+		prefix = 0
+	}
+	if prefix == 0 && geminiReq.SystemInstruction == nil {
+		return ""
+	}
+
+	cached := &CachedContent{
+		Model:             "models/" + model,
+		SystemInstruction: geminiReq.SystemInstruction,
+		Contents:          geminiReq.Contents[:prefix],
+		Tools:             geminiReq.Tools,
+	}
+	key, err := cacheKey(apiKey, cached)
+	if err != nil {
+		return ""
+	}
+
+	name, err := p.cache.getOrCreate(key, func() (cacheEntry, error) {
+		ttl := p.cacheTTL
+		if geminiReq.cacheTTL > 0 {
+			ttl = geminiReq.cacheTTL
+		}
+
+		created, err := p.createCachedContent(cached, ttl, apiKey)
+		if err != nil {
+			return cacheEntry{}, err
+		}
+
+		expires, err := time.Parse(time.RFC3339Nano, created.ExpireTime)
+		if err != nil {
+			expires = time.Now().Add(ttl)
+		}
+		log.Printf("🗄️ Created Gemini context cache %s (%d messages, expires %s)", created.Name, prefix, expires.Format(time.RFC3339))
+		return cacheEntry{name: created.Name, expires: expires}, nil
+	})
+	if err != nil {
+		if !errors.Is(err, errCacheRecentlyFailed) {
+			log.Printf("⚠️ Gemini context cache unavailable, sending full request: %v", err)
+		}
+		return ""
+	}
+
+	geminiReq.CachedContent = name
+	// 使用 cachedContent 时 systemInstruction 和 tools 只能放在缓存中
+	geminiReq.SystemInstruction = nil
+	geminiReq.Tools = nil
+	geminiReq.Contents = geminiReq.Contents[prefix:]
+	return key
+}
+
+// sendWithContextCache 对请求应用上下文缓存后发送
+// 非 200 响应时移除使用的缓存条目（下次请求重新创建）；如果是引用的缓存已过期或在上游被删除，用完整请求重试一次
+func (p *Provider) sendWithContextCache(url string, geminiReq *GenerateContentRequest, model, apiKey string) (*http.Response, error) {
+	full := *geminiReq
+	cacheKey := p.applyContextCache(geminiReq, model, apiKey)
+
+	resp, err := p.postGenerate(url, geminiReq)
+	if err != nil || cacheKey == "" || resp.StatusCode == http.StatusOK {
+		return resp, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	p.cache.remove(cacheKey)
+
+	if apiErr := newAPIError(resp.StatusCode, body); isCacheMissError(apiErr) {
+		log.Printf("⚠️ Gemini context cache %s is gone, retrying with full request: %v", geminiReq.CachedContent, apiErr)
+		*geminiReq = full
+		return p.postGenerate(url, geminiReq)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// createCachedContent 调用 cachedContents.create
+func (p *Provider) createCachedContent(cached *CachedContent, ttl time.Duration, apiKey string) (*CachedContent, error) {
+	body := *cached
+	body.TTL = fmt.Sprintf("%ds", int(ttl.Seconds()))
+
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal cached content: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/cachedContents?key=%s", p.baseURL, apiKey)
+	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 60 * time.Second, Transport: p.transport}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp.StatusCode, respBody)
+	}
+
+	var created CachedContent
+	if err := json.Unmarshal(respBody, &created); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if created.Name == "" {
+		return nil, fmt.Errorf("cachedContents.create returned no name")
+	}
+	return &created, nil
+}
+
+// cacheKey 缓存资源属于 API Key 所在的项目，因此键包含 API Key
+func cacheKey(apiKey string, cached *CachedContent) (string, error) {
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write([]byte(apiKey + "\n"))
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,166 @@
+package google
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"openbridge/internal/models"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const okResponse = `{"candidates":[{"content":{"parts":[{"text":"ok"}],"role":"model"},"finishReason":"STOP"}],
+	"usageMetadata":{"promptTokenCount":1,"candidatesTokenCount":1,"totalTokenCount":2}}`
+
+// cacheUpstream 模拟 cachedContents.create 和 generateContent
+type cacheUpstream struct {
+	creates  atomic.Int32
+	create   func(w http.ResponseWriter) // cachedContents.create 的响应
+	generate func(w http.ResponseWriter, req *GenerateContentRequest)
+
+	mu       sync.Mutex
+	requests []*GenerateContentRequest // 收到的 generateContent 请求
+}
+
+func (u *cacheUpstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if strings.HasSuffix(r.URL.Path, "/cachedContents") {
+		u.creates.Add(1)
+		u.create(w)
+		return
+	}
+
+	var req GenerateContentRequest
+	json.Unmarshal(body, &req)
+	u.mu.Lock()
+	u.requests = append(u.requests, &req)
+	u.mu.Unlock()
+	if u.generate != nil {
+		u.generate(w, &req)
+		return
+	}
+	io.WriteString(w, okResponse)
+}
+
+func newCacheTestProvider(t *testing.T, u *cacheUpstream) *Provider {
+	t.Helper()
+	server := httptest.NewServer(u)
+	t.Cleanup(server.Close)
+	return New("gemini", server.URL)
+}
+
+func cachedRequest() *models.ChatCompletionRequest {
+	return &models.ChatCompletionRequest{
+		Model: "gemini-2.0-flash",
+		Messages: []models.Message{
+			{Role: "system", Content: "long stable instructions", CacheControl: &models.CacheControl{Type: "ephemeral"}},
+			{Role: "user", Content: "hi"},
+		},
+	}
+}
+
+func createdCache(name string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		json.NewEncoder(w).Encode(CachedContent{Name: name, ExpireTime: time.Now().Add(time.Hour).Format(time.RFC3339Nano)})
+	}
+}
+
+func TestContextCacheRemembersFailures(t *testing.T) {
+	u := &cacheUpstream{create: func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `{"error":{"code":400,"message":"Cached content is too small. total_token_count=12, min_total_token_count=4096","status":"INVALID_ARGUMENT"}}`)
+	}}
+	p := newCacheTestProvider(t, u)
+
+	for i := 0; i < 3; i++ {
+		if _, err := p.ChatCompletion(cachedRequest(), "key"); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+	if n := u.creates.Load(); n != 1 {
+		t.Errorf("cachedContents.create called %d times, want 1", n)
+	}
+	for _, req := range u.requests {
+		if req.CachedContent != "" || req.SystemInstruction == nil {
+			t.Errorf("request = %+v, want the full uncached request", req)
+		}
+	}
+}
+
+func TestContextCacheSingleFlight(t *testing.T) {
+	release := make(chan struct{})
+	u := &cacheUpstream{create: func(w http.ResponseWriter) {
+		<-release
+		createdCache("cachedContents/abc")(w)
+	}}
+	p := newCacheTestProvider(t, u)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := p.ChatCompletion(cachedRequest(), "key"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	// 等所有请求都在等待同一次创建后再放行
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := u.creates.Load(); n != 1 {
+		t.Errorf("cachedContents.create called %d times, want 1", n)
+	}
+	for _, req := range u.requests {
+		if req.CachedContent != "cachedContents/abc" {
+			t.Errorf("cachedContent = %q, want cachedContents/abc", req.CachedContent)
+		}
+	}
+}
+
+func TestContextCacheRetriesWhenCacheIsGone(t *testing.T) {
+	u := &cacheUpstream{create: createdCache("cachedContents/gone")}
+	u.generate = func(w http.ResponseWriter, req *GenerateContentRequest) {
+		if req.CachedContent != "" {
+			w.WriteHeader(http.StatusForbidden)
+			io.WriteString(w, `{"error":{"code":403,"message":"CachedContent not found (or permission denied)","status":"PERMISSION_DENIED"}}`)
+			return
+		}
+		io.WriteString(w, okResponse)
+	}
+	p := newCacheTestProvider(t, u)
+
+	resp, err := p.ChatCompletion(cachedRequest(), "key")
+	if err != nil {
+		t.Fatalf("ChatCompletion: %v", err)
+	}
+	if resp.Choices[0].Message.Content != "ok" {
+		t.Errorf("content = %v, want ok", resp.Choices[0].Message.Content)
+	}
+	if len(u.requests) != 2 {
+		t.Fatalf("generateContent called %d times, want 2", len(u.requests))
+	}
+	if retry := u.requests[1]; retry.CachedContent != "" || retry.SystemInstruction == nil || len(retry.Contents) != 1 {
+		t.Errorf("retry = %+v, want the full uncached request", retry)
+	}
+
+	// 流式请求同样重试；失效的条目已移除，会重新创建缓存
+	chunks, errs := p.ChatCompletionStream(cachedRequest(), "key")
+	for range chunks {
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("ChatCompletionStream: %v", err)
+	}
+	if n := u.creates.Load(); n != 2 {
+		t.Errorf("cachedContents.create called %d times, want 2", n)
+	}
+	if len(u.requests) != 4 {
+		t.Errorf("generateContent called %d times, want 4", len(u.requests))
+	}
+}
@@ -0,0 +1,105 @@
+package google
+
+import (
+	"encoding/json"
+	"openbridge/internal/cassette"
+	"openbridge/internal/models"
+	"strings"
+	"testing"
+)
+
+// 回放 testdata/cassettes 中录制的 generateContent / streamGenerateContent 交互，校验请求转换（匹配键包含转换后的请求体）和响应转换
+// 转换逻辑变化导致请求体不同时回放会失败，需要重新录制
+
+const chatRequest = `{
+	"model": "gemini-2.0-flash",
+	"messages": [
+		{"role": "system", "content": "You are a weather assistant."},
+		{"role": "user", "content": "Is it usually rainy in Paris in November?"}
+	],
+	"max_tokens": 256,
+	"temperature": 0.2
+}`
+
+const streamRequest = `{
+	"model": "gemini-2.0-flash",
+	"messages": [{"role": "user", "content": "What is 2+2? Answer briefly."}],
+	"max_tokens": 64,
+	"stream": true,
+	"stream_options": {"include_usage": true}
+}`
+
+func newReplayProvider(t *testing.T) *Provider {
+	t.Helper()
+	rt, err := cassette.NewTransport(cassette.ModeReplay, "testdata/cassettes", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := New("gemini", "")
+	p.SetTransport(rt)
+	return p
+}
+
+func parseRequest(t *testing.T, data string) *models.ChatCompletionRequest {
+	t.Helper()
+	var req models.ChatCompletionRequest
+	if err := json.Unmarshal([]byte(data), &req); err != nil {
+		t.Fatal(err)
+	}
+	return &req
+}
+
+func TestCassetteChat(t *testing.T) {
+	p := newReplayProvider(t)
+
+	resp, err := p.ChatCompletion(parseRequest(t, chatRequest), "test-key")
+	if err != nil {
+		t.Fatalf("ChatCompletion: %v", err)
+	}
+
+	choice := resp.Choices[0]
+	if choice.FinishReason != "stop" {
+		t.Errorf("finish_reason = %q, want stop", choice.FinishReason)
+	}
+	if want := "Yes, November is one of the wettest months in Paris."; choice.Message.Content != want {
+		t.Errorf("content = %q, want %q", choice.Message.Content, want)
+	}
+	if resp.Usage.PromptTokens != 41 || resp.Usage.CompletionTokens != 12 || resp.Usage.TotalTokens != 53 {
+		t.Errorf("usage = %+v, want 41 + 12", resp.Usage)
+	}
+}
+
+func TestCassetteStream(t *testing.T) {
+	p := newReplayProvider(t)
+
+	chunkChan, errChan := p.ChatCompletionStream(parseRequest(t, streamRequest), "test-key")
+	var (
+		content      strings.Builder
+		finishReason string
+		usage        *models.Usage
+	)
+	for chunk := range chunkChan {
+		for _, choice := range chunk.Choices {
+			content.WriteString(choice.Delta.Content)
+			if choice.FinishReason != nil {
+				finishReason = *choice.FinishReason
+			}
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+	}
+	if err := <-errChan; err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+
+	if content.String() != "2 + 2 = 4." {
+		t.Errorf("content = %q, want %q", content.String(), "2 + 2 = 4.")
+	}
+	if finishReason != "stop" {
+		t.Errorf("finish_reason = %q, want stop", finishReason)
+	}
+	if usage == nil || usage.PromptTokens != 9 || usage.CompletionTokens != 7 {
+		t.Errorf("usage = %+v, want 9 + 7", usage)
+	}
+}
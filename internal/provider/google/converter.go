@@ -1,120 +1,329 @@
 package google
 
 import (
+	"encoding/base64"
+	"fmt"
+	"openbridge/internal/media"
 	"openbridge/internal/models"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
+// maxStopSequences Gemini stopSequences 的数量上限
+const maxStopSequences = 5
+
+// maxCandidates Gemini candidateCount 的上限
+const maxCandidates = 8
+
+// maxTopLogprobs Gemini logprobs（每个位置的候选数）的上限
+const maxTopLogprobs = 20
+
+// ConvertOptions 转换选项（来自 Provider 配置）
+type ConvertOptions struct {
+	SafetySettings []SafetySetting // 为空时使用 defaultSafetySettings
+	GoogleSearch   bool            // 开启 Google Search grounding（search_models 中的模型别名）
+}
+
+// defaultSafetySettings 默认安全设置（较宽松）
+var defaultSafetySettings = []SafetySetting{
+	{Category: "HARM_CATEGORY_HARASSMENT", Threshold: "BLOCK_NONE"},
+	{Category: "HARM_CATEGORY_HATE_SPEECH", Threshold: "BLOCK_NONE"},
+	{Category: "HARM_CATEGORY_SEXUALLY_EXPLICIT", Threshold: "BLOCK_NONE"},
+	{Category: "HARM_CATEGORY_DANGEROUS_CONTENT", Threshold: "BLOCK_NONE"},
+}
+
 // ConvertFromOpenAI 将 OpenAI 格式转换为 Gemini 格式
-func ConvertFromOpenAI(req *models.ChatCompletionRequest) (*GenerateContentRequest, error) {
+func ConvertFromOpenAI(req *models.ChatCompletionRequest, opts ConvertOptions) (*GenerateContentRequest, error) {
 	geminiReq := &GenerateContentRequest{
 		Contents: make([]Content, 0),
 		GenerationConfig: &GenerationConfig{
 			Temperature:     req.Temperature,
 			TopP:            req.TopP,
-			MaxOutputTokens: req.MaxTokens,
+			TopK:            req.TopK,
+			MaxOutputTokens: req.MaxOutputTokens(),
+			Seed:            req.Seed,
 		},
 	}
 
-	// 默认安全设置（较宽松）
-	geminiReq.SafetySettings = []SafetySetting{
-		{Category: "HARM_CATEGORY_HARASSMENT", Threshold: "BLOCK_NONE"},
-		{Category: "HARM_CATEGORY_HATE_SPEECH", Threshold: "BLOCK_NONE"},
-		{Category: "HARM_CATEGORY_SEXUALLY_EXPLICIT", Threshold: "BLOCK_NONE"},
-		{Category: "HARM_CATEGORY_DANGEROUS_CONTENT", Threshold: "BLOCK_NONE"},
+	// Gemini 不支持 logit_bias 和音频输出；user / metadata 没有对应字段，直接忽略
+	if req.WantsAudio() {
+		return nil, models.NewRequestError("modalities", "audio output is not supported by Gemini chat models")
+	}
+	if len(req.LogitBias) > 0 {
+		return nil, models.NewRequestError("logit_bias", "logit_bias is not supported by Gemini models")
 	}
 
-	// 提取 system message
-	var systemParts []Part
-	for _, msg := range req.Messages {
-		if msg.Role == "system" {
-			if text, ok := msg.Content.(string); ok {
-				systemParts = append(systemParts, Part{Text: text})
+	// n -> candidateCount，部分模型不支持多个候选，由上游返回错误
+	if req.N > maxCandidates {
+		return nil, models.NewRequestError("n", "n must be at most %d for Gemini models, got %d", maxCandidates, req.N)
+	}
+	if req.N > 1 {
+		geminiReq.GenerationConfig.CandidateCount = req.N
+	}
+
+	// logprobs / top_logprobs -> responseLogprobs / logprobs
+	if req.TopLogprobs > 0 && !req.Logprobs {
+		return nil, models.NewRequestError("top_logprobs", "top_logprobs requires logprobs to be true")
+	}
+	if req.TopLogprobs > maxTopLogprobs {
+		return nil, models.NewRequestError("top_logprobs", "top_logprobs must be at most %d for Gemini models, got %d", maxTopLogprobs, req.TopLogprobs)
+	}
+	if req.Logprobs {
+		geminiReq.GenerationConfig.ResponseLogprobs = true
+		if req.TopLogprobs > 0 {
+			top := req.TopLogprobs
+			geminiReq.GenerationConfig.Logprobs = &top
+		}
+	}
+
+	stops, err := req.StopSequences()
+	if err != nil {
+		return nil, err
+	}
+	if len(stops) > maxStopSequences {
+		return nil, models.NewRequestError("stop", "Gemini supports at most %d stop sequences, got %d", maxStopSequences, len(stops))
+	}
+	geminiReq.GenerationConfig.StopSequences = stops
+
+	// thinking / reasoning_effort -> thinkingConfig，开启思考时返回思考摘要
+	budget, err := req.ThinkingBudget()
+	if err != nil {
+		return nil, err
+	}
+	if budget != nil {
+		geminiReq.GenerationConfig.ThinkingConfig = &ThinkingConfig{
+			ThinkingBudget:  budget,
+			IncludeThoughts: *budget > 0,
+		}
+	}
+
+	// response_format -> responseMimeType / responseSchema
+	if req.ResponseFormat != nil {
+		switch req.ResponseFormat.Type {
+		case "json_object":
+			geminiReq.GenerationConfig.ResponseMimeType = "application/json"
+		case "json_schema":
+			geminiReq.GenerationConfig.ResponseMimeType = "application/json"
+			if req.ResponseFormat.JSONSchema != nil && len(req.ResponseFormat.JSONSchema.Schema) > 0 {
+				geminiReq.GenerationConfig.ResponseSchema = ConvertSchema(req.ResponseFormat.JSONSchema.Schema)
 			}
 		}
 	}
+
+	// web_search_options 或模型别名 -> googleSearch grounding（Gemini 不支持 user_location，忽略）
+	if req.WebSearchOptions != nil || opts.GoogleSearch {
+		geminiReq.Tools = append(geminiReq.Tools, Tool{GoogleSearch: &GoogleSearch{}})
+	}
+
+	// 安全设置：Provider 配置（或默认值），请求中的 safety_settings 按类别覆盖
+	safety, err := safetySettings(opts.SafetySettings, req.SafetySettings)
+	if err != nil {
+		return nil, err
+	}
+	geminiReq.SafetySettings = safety
+
+	// 提取 system / developer 消息
+	var systemParts []Part
+	for i, msg := range req.Messages {
+		if !msg.IsSystem() {
+			continue
+		}
+		parts, err := convertParts(msg.Content, i)
+		if err != nil {
+			return nil, err
+		}
+		systemParts = append(systemParts, parts...)
+		if cc := findCacheControl(msg); cc != nil {
+			markCachePrefix(geminiReq, cc)
+		}
+	}
 	if len(systemParts) > 0 {
 		geminiReq.SystemInstruction = &Content{
 			Parts: systemParts,
 		}
 	}
 
-	// 转换 messages (跳过 system)
-	for _, msg := range req.Messages {
-		if msg.Role == "system" {
-			continue
+	// 转换其余消息并按 Gemini 的角色规则规范化
+	if err := convertContents(geminiReq, req.Messages); err != nil {
+		return nil, err
+	}
+
+	return geminiReq, nil
+}
+
+// safetySettings 合并安全设置，overrides 中的类别替换 base 中的同类别设置
+func safetySettings(base []SafetySetting, overrides []models.SafetySetting) ([]SafetySetting, error) {
+	if len(base) == 0 {
+		base = defaultSafetySettings
+	}
+	result := append([]SafetySetting(nil), base...)
+
+	for i, o := range overrides {
+		if o.Category == "" || o.Threshold == "" {
+			return nil, models.NewRequestError(fmt.Sprintf("safety_settings[%d]", i), "safety settings must have a category and a threshold")
+		}
+		setting := SafetySetting{
+			Category:  strings.ToUpper(o.Category),
+			Threshold: strings.ToUpper(o.Threshold),
 		}
 
-		// Gemini 使用 "user" 和 "model"
-		role := msg.Role
-		if role == "assistant" {
-			role = "model"
+		replaced := false
+		for j := range result {
+			if result[j].Category == setting.Category {
+				result[j] = setting
+				replaced = true
+			}
+		}
+		if !replaced {
+			result = append(result, setting)
 		}
+	}
+	return result, nil
+}
 
-		content := Content{
-			Role:  role,
-			Parts: make([]Part, 0),
+// checkPromptFeedback prompt 被安全过滤拦截时（没有任何候选）返回 content_filter 错误
+func checkPromptFeedback(resp *GenerateContentResponse) error {
+	if resp.PromptFeedback == nil || resp.PromptFeedback.BlockReason == "" || len(resp.Candidates) > 0 {
+		return nil
+	}
+
+	message := fmt.Sprintf("the prompt was blocked by Gemini (reason: %s)", resp.PromptFeedback.BlockReason)
+	if ratings := formatSafetyRatings(resp.PromptFeedback.SafetyRatings); ratings != "" {
+		message += ", safety ratings: " + ratings
+	}
+	return &models.RequestError{
+		Param:   "messages",
+		Message: message,
+		Code:    models.ErrorCodeContentFilter,
+	}
+}
+
+// formatSafetyRatings 格式化安全评级，例如 HARM_CATEGORY_HARASSMENT=HIGH (blocked)
+func formatSafetyRatings(ratings []SafetyRating) string {
+	items := make([]string, 0, len(ratings))
+	for _, r := range ratings {
+		item := r.Category + "=" + r.Probability
+		if r.Blocked {
+			item += " (blocked)"
 		}
+		items = append(items, item)
+	}
+	return strings.Join(items, ", ")
+}
 
-		// 处理 content
-		switch v := msg.Content.(type) {
-		case string:
-			content.Parts = append(content.Parts, Part{Text: v})
+// supportedImageTypes Gemini 支持的图片格式
+var supportedImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+	"image/heic": true,
+	"image/heif": true,
+}
 
-		case []interface{}:
-			// 多模态内容
-			for _, part := range v {
-				partMap, ok := part.(map[string]interface{})
-				if !ok {
-					continue
-				}
+// convertImage 将 image_url 转换为 inlineData，远程 URL 由网关下载
+func convertImage(url string) (*InlineData, error) {
+	var m *media.Media
+	var err error
+	if strings.HasPrefix(url, "data:") {
+		m, err = media.ParseDataURI(url)
+	} else {
+		m, err = media.Default().Fetch(url)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !supportedImageTypes[m.MimeType] {
+		return nil, fmt.Errorf("unsupported image type %q (supported: jpeg, png, webp, heic, heif)", m.MimeType)
+	}
+
+	return &InlineData{
+		MimeType: m.MimeType,
+		Data:     m.Base64(),
+	}, nil
+}
 
-				typeStr, _ := partMap["type"].(string)
-				switch typeStr {
-				case "text":
-					if text, ok := partMap["text"].(string); ok {
-						content.Parts = append(content.Parts, Part{Text: text})
-					}
-
-				case "image_url":
-					// 转换图片 URL 为 base64
-					imageURL, ok := partMap["image_url"].(map[string]interface{})
-					if !ok {
-						continue
-					}
-					url, _ := imageURL["url"].(string)
-
-					// 如果是 data URI，提取 base64 数据
-					if strings.HasPrefix(url, "data:") {
-						parts := strings.SplitN(url, ",", 2)
-						if len(parts) == 2 {
-							mimeType := "image/png"
-							if strings.Contains(parts[0], "image/jpeg") || strings.Contains(parts[0], "image/jpg") {
-								mimeType = "image/jpeg"
-							} else if strings.Contains(parts[0], "image/webp") {
-								mimeType = "image/webp"
-							} else if strings.Contains(parts[0], "image/png") {
-								mimeType = "image/png"
-							}
-
-							content.Parts = append(content.Parts, Part{
-								InlineData: &InlineData{
-									MimeType: mimeType,
-									Data:     parts[1],
-								},
-							})
-						}
-					}
+// findCacheControl 返回消息或其 content part 上的 cache_control
+func findCacheControl(msg models.Message) *models.CacheControl {
+	if msg.CacheControl != nil {
+		return msg.CacheControl
+	}
+	if parts, ok := msg.Content.([]interface{}); ok {
+		for _, part := range parts {
+			if partMap, ok := part.(map[string]interface{}); ok {
+				if cc := models.ParseCacheControl(partMap); cc != nil {
+					return cc
 				}
 			}
 		}
+	}
+	return nil
+}
 
-		if len(content.Parts) > 0 {
-			geminiReq.Contents = append(geminiReq.Contents, content)
-		}
+// markCachePrefix 记录请求包含可缓存前缀，cache_control.ttl 可覆盖默认有效期
+func markCachePrefix(geminiReq *GenerateContentRequest, cc *models.CacheControl) {
+	geminiReq.cacheMarked = true
+	if ttl, err := time.ParseDuration(cc.TTL); err == nil && ttl > 0 {
+		geminiReq.cacheTTL = ttl
 	}
+}
 
-	return geminiReq, nil
+// maxInlineBytes Gemini inlineData 的大小上限（整个请求不能超过 20MB）
+const maxInlineBytes = 20 << 20
+
+// convertDocument 将 file 内容块转换为 inlineData，支持 PDF、文本和图片
+func convertDocument(file *models.File) (*InlineData, error) {
+	if file == nil {
+		return nil, fmt.Errorf("file part is missing the file object")
+	}
+	if file.FileData == "" && file.FileID != "" {
+		return nil, fmt.Errorf("file_id is not supported by Gemini models, send the content as file_data")
+	}
+
+	m, err := media.DecodeFileData(file.FileData, file.Filename, file.MimeType)
+	if err != nil {
+		return nil, err
+	}
+	if len(m.Data) > maxInlineBytes {
+		return nil, fmt.Errorf("file is %d bytes, Gemini accepts inline files up to %d bytes", len(m.Data), maxInlineBytes)
+	}
+	if m.MimeType != "application/pdf" && !strings.HasPrefix(m.MimeType, "text/") && !supportedImageTypes[m.MimeType] {
+		return nil, fmt.Errorf("unsupported file type %q (supported: application/pdf, text/*, images)", m.MimeType)
+	}
+
+	return &InlineData{
+		MimeType: m.MimeType,
+		Data:     m.Base64(),
+	}, nil
+}
+
+// audioMimeTypes input_audio.format 对应的 MIME 类型
+var audioMimeTypes = map[string]string{
+	"wav":  "audio/wav",
+	"mp3":  "audio/mp3",
+	"aiff": "audio/aiff",
+	"aac":  "audio/aac",
+	"ogg":  "audio/ogg",
+	"flac": "audio/flac",
+}
+
+// convertAudio 将 input_audio 转换为 inlineData
+func convertAudio(audio *models.InputAudio) (*InlineData, error) {
+	if audio == nil || audio.Data == "" {
+		return nil, fmt.Errorf("input_audio.data is required")
+	}
+	mimeType, ok := audioMimeTypes[strings.ToLower(audio.Format)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported audio format %q (supported: wav, mp3, aiff, aac, ogg, flac)", audio.Format)
+	}
+	if base64.StdEncoding.DecodedLen(len(audio.Data)) > maxInlineBytes {
+		return nil, fmt.Errorf("audio is larger than the %d byte inline limit", maxInlineBytes)
+	}
+
+	return &InlineData{
+		MimeType: mimeType,
+		Data:     audio.Data,
+	}, nil
 }
 
 // ConvertToOpenAI 将 Gemini 格式转换为 OpenAI 格式
@@ -129,92 +338,251 @@ func ConvertToOpenAI(resp *GenerateContentResponse, requestID string, requestMod
 
 	// 转换 candidates
 	for _, candidate := range resp.Candidates {
-		// 提取文本内容
-		var content strings.Builder
-		for _, part := range candidate.Content.Parts {
-			if part.Text != "" {
-				content.WriteString(part.Text)
-			}
-		}
+		content, reasoning := splitThoughts(candidate.Content.Parts)
 
 		choice := models.Choice{
 			Index: candidate.Index,
 			Message: models.ResponseMessage{
-				Role:    "assistant",
-				Content: content.String(),
+				Role:             "assistant",
+				Content:          content,
+				ReasoningContent: reasoning,
 			},
+			Logprobs:     convertLogprobs(candidate.LogprobsResult),
 			FinishReason: convertFinishReason(candidate.FinishReason),
 		}
 
+		if md := candidate.GroundingMetadata; md != nil {
+			choice.Message.Annotations = groundingAnnotations(md, content, partOffsets(candidate.Content.Parts))
+			choice.Message.SearchQueries = md.WebSearchQueries
+		}
+
 		openaiResp.Choices = append(openaiResp.Choices, choice)
 	}
 
 	// 转换 usage
-	if resp.UsageMetadata != nil {
-		openaiResp.Usage = models.Usage{
-			PromptTokens:     resp.UsageMetadata.PromptTokenCount,
-			CompletionTokens: resp.UsageMetadata.CandidatesTokenCount,
-			TotalTokens:      resp.UsageMetadata.TotalTokenCount,
-		}
+	if usage := convertUsage(resp.UsageMetadata); usage != nil {
+		openaiResp.Usage = *usage
 	}
 
 	return openaiResp
 }
 
-// ConvertStreamResponseToChunk 将 Gemini 流式响应转换为 OpenAI 流式块
-func ConvertStreamResponseToChunk(resp *GenerateContentResponse, chunkID string, requestModel string, isFirst bool) *models.ChatCompletionChunk {
+// StreamConverter 将 Gemini 流式响应转换为 OpenAI 流式块
+// Gemini 每个响应都携带截至当前的 usageMetadata，只保留最后一次，在流结束时按需下发
+type StreamConverter struct {
+	chunkID      string
+	requestModel string
+	candidates   int          // 请求的候选数量
+	started      map[int]bool             // 已发送过 role 的候选
+	finished     map[int]bool             // 已结束的候选
+	text         map[int]*strings.Builder // 每个候选已发送的正文，用于计算引用位置
+	usage        *UsageMetadata
+}
+
+// NewStreamConverter 创建流式转换器，candidates 为请求的候选数量（0 表示 1 个）
+func NewStreamConverter(chunkID string, requestModel string, candidates int) *StreamConverter {
+	if candidates < 1 {
+		candidates = 1
+	}
+	return &StreamConverter{
+		chunkID:      chunkID,
+		requestModel: requestModel,
+		candidates:   candidates,
+		started:      make(map[int]bool),
+		finished:     make(map[int]bool),
+		text:         make(map[int]*strings.Builder),
+	}
+}
+
+// Convert 转换单个流式响应，返回 nil 表示该响应不需要下发
+func (c *StreamConverter) Convert(resp *GenerateContentResponse) *models.ChatCompletionChunk {
+	if resp.UsageMetadata != nil {
+		c.usage = resp.UsageMetadata
+	}
+	if len(resp.Candidates) == 0 {
+		return nil
+	}
+
 	chunk := &models.ChatCompletionChunk{
-		ID:      chunkID,
+		ID:      c.chunkID,
 		Object:  "chat.completion.chunk",
 		Created: 0,
-		Model:   requestModel,
-		Choices: make([]models.ChunkChoice, 0),
+		Model:   c.requestModel,
+		Choices: make([]models.ChunkChoice, 0, len(resp.Candidates)),
 	}
 
-	if len(resp.Candidates) > 0 {
-		candidate := resp.Candidates[0]
-
-		// 提取文本内容
-		var content strings.Builder
-		for _, part := range candidate.Content.Parts {
-			if part.Text != "" {
-				content.WriteString(part.Text)
-			}
-		}
+	for _, candidate := range resp.Candidates {
+		content, reasoning := splitThoughts(candidate.Content.Parts)
 
 		delta := models.ChunkDelta{
-			Content: content.String(),
+			Content:          content,
+			ReasoningContent: reasoning,
 		}
 
-		// 第一个 chunk 包含 role
-		if isFirst {
+		// 每个候选的第一个 chunk 包含 role
+		if !c.started[candidate.Index] {
 			delta.Role = "assistant"
+			c.started[candidate.Index] = true
+			c.text[candidate.Index] = &strings.Builder{}
+		}
+
+		// grounding 信息在最后的响应中返回，引用位置相对于该候选的完整正文
+		text := c.text[candidate.Index]
+		text.WriteString(content)
+		if md := candidate.GroundingMetadata; md != nil {
+			delta.Annotations = groundingAnnotations(md, text.String(), nil)
+			delta.SearchQueries = md.WebSearchQueries
 		}
 
 		chunkChoice := models.ChunkChoice{
-			Index: candidate.Index,
-			Delta: delta,
+			Index:    candidate.Index,
+			Delta:    delta,
+			Logprobs: convertLogprobs(candidate.LogprobsResult),
 		}
 
 		// 如果有 finishReason
 		if candidate.FinishReason != "" && candidate.FinishReason != "FINISH_REASON_UNSPECIFIED" {
 			finishReason := convertFinishReason(candidate.FinishReason)
 			chunkChoice.FinishReason = &finishReason
+			c.finished[candidate.Index] = true
 		}
 
 		chunk.Choices = append(chunk.Choices, chunkChoice)
 	}
 
-	// 添加 usage 信息
-	if resp.UsageMetadata != nil {
-		chunk.Usage = &models.Usage{
-			PromptTokens:     resp.UsageMetadata.PromptTokenCount,
-			CompletionTokens: resp.UsageMetadata.CandidatesTokenCount,
-			TotalTokens:      resp.UsageMetadata.TotalTokenCount,
+	return chunk
+}
+
+// Done 所有候选是否都已结束
+func (c *StreamConverter) Done() bool {
+	return len(c.finished) >= c.candidates
+}
+
+// UsageChunk 返回携带最终 usage 的最后一个 chunk（stream_options.include_usage），没有收到 usage 时返回 nil
+func (c *StreamConverter) UsageChunk() *models.ChatCompletionChunk {
+	usage := convertUsage(c.usage)
+	if usage == nil {
+		return nil
+	}
+	return models.NewUsageChunk(c.chunkID, c.requestModel, *usage)
+}
+
+// convertLogprobs 将 logprobsResult 转换为 OpenAI logprobs，没有结果时返回 nil
+func convertLogprobs(result *LogprobsResult) *models.Logprobs {
+	if result == nil || len(result.ChosenCandidates) == 0 {
+		return nil
+	}
+
+	logprobs := &models.Logprobs{
+		Content: make([]models.TokenLogprob, 0, len(result.ChosenCandidates)),
+	}
+	for i, chosen := range result.ChosenCandidates {
+		token := models.TokenLogprob{
+			Token:       chosen.Token,
+			Logprob:     chosen.LogProbability,
+			Bytes:       models.TokenBytes(chosen.Token),
+			TopLogprobs: []models.TopLogprob{},
 		}
+		if i < len(result.TopCandidates) {
+			for _, top := range result.TopCandidates[i].Candidates {
+				token.TopLogprobs = append(token.TopLogprobs, models.TopLogprob{
+					Token:   top.Token,
+					Logprob: top.LogProbability,
+					Bytes:   models.TokenBytes(top.Token),
+				})
+			}
+		}
+		logprobs.Content = append(logprobs.Content, token)
 	}
+	return logprobs
+}
 
-	return chunk
+// groundingAnnotations 将 groundingSupports 转换为 url_citation 标注
+// offsets 为每个 part 在 content 中的字节偏移，为 nil 时 segment 偏移直接相对于 content
+func groundingAnnotations(md *GroundingMetadata, content string, offsets []int) []models.Annotation {
+	var annotations []models.Annotation
+	for _, support := range md.GroundingSupports {
+		base := 0
+		if support.Segment.PartIndex < len(offsets) {
+			base = offsets[support.Segment.PartIndex]
+		}
+		// Gemini 使用字节偏移，OpenAI 使用字符偏移
+		start := runeIndex(content, base+support.Segment.StartIndex)
+		end := runeIndex(content, base+support.Segment.EndIndex)
+
+		for _, i := range support.GroundingChunkIndices {
+			if i < 0 || i >= len(md.GroundingChunks) || md.GroundingChunks[i].Web == nil {
+				continue
+			}
+			web := md.GroundingChunks[i].Web
+			annotations = append(annotations, models.NewURLCitation(start, end, web.URI, web.Title))
+		}
+	}
+	return annotations
+}
+
+// partOffsets 返回每个 part 的文本在 splitThoughts 拼接的正文中的起始字节偏移
+func partOffsets(parts []Part) []int {
+	offsets := make([]int, len(parts))
+	n := 0
+	for i, part := range parts {
+		offsets[i] = n
+		if !part.Thought {
+			n += len(part.Text)
+		}
+	}
+	return offsets
+}
+
+// runeIndex 将字节偏移转换为字符偏移
+func runeIndex(s string, byteOffset int) int {
+	if byteOffset > len(s) {
+		byteOffset = len(s)
+	}
+	if byteOffset < 0 {
+		byteOffset = 0
+	}
+	return utf8.RuneCountInString(s[:byteOffset])
+}
+
+// splitThoughts 分别拼接正文和思考摘要
+func splitThoughts(parts []Part) (content string, reasoning string) {
+	var text, thought strings.Builder
+	for _, part := range parts {
+		if part.Text == "" {
+			continue
+		}
+		if part.Thought {
+			thought.WriteString(part.Text)
+		} else {
+			text.WriteString(part.Text)
+		}
+	}
+	return text.String(), thought.String()
+}
+
+// convertUsage 转换 usage，思考 token 计入 completion_tokens
+func convertUsage(usage *UsageMetadata) *models.Usage {
+	if usage == nil {
+		return nil
+	}
+
+	result := &models.Usage{
+		PromptTokens:     usage.PromptTokenCount,
+		CompletionTokens: usage.CandidatesTokenCount + usage.ThoughtsTokenCount,
+		TotalTokens:      usage.TotalTokenCount,
+	}
+	if usage.CachedContentTokenCount > 0 {
+		result.PromptTokensDetails = &models.PromptTokensDetails{
+			CachedTokens: usage.CachedContentTokenCount,
+		}
+	}
+	if usage.ThoughtsTokenCount > 0 {
+		result.CompletionTokensDetails = &models.CompletionTokensDetails{
+			ReasoningTokens: usage.ThoughtsTokenCount,
+		}
+	}
+	return result
 }
 
 func convertFinishReason(geminiReason string) string {
@@ -223,9 +591,7 @@ func convertFinishReason(geminiReason string) string {
 		return "stop"
 	case "MAX_TOKENS":
 		return "length"
-	case "SAFETY":
-		return "content_filter"
-	case "RECITATION":
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII", "IMAGE_SAFETY":
 		return "content_filter"
 	case "OTHER":
 		return "stop"
@@ -0,0 +1,133 @@
+package google
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"openbridge/internal/models"
+	"openbridge/internal/tokenizer"
+	"strings"
+	"time"
+)
+
+// maxEmbeddingInputs batchEmbedContents 单次请求最多 100 个输入
+const maxEmbeddingInputs = 100
+
+// taskTypes Gemini 支持的 taskType
+var taskTypes = map[string]bool{
+	"TASK_TYPE_UNSPECIFIED": true,
+	"RETRIEVAL_QUERY":       true,
+	"RETRIEVAL_DOCUMENT":    true,
+	"SEMANTIC_SIMILARITY":   true,
+	"CLASSIFICATION":        true,
+	"CLUSTERING":            true,
+	"QUESTION_ANSWERING":    true,
+	"FACT_VERIFICATION":     true,
+	"CODE_RETRIEVAL_QUERY":  true,
+}
+
+func (p *Provider) MaxEmbeddingInputs() int {
+	return maxEmbeddingInputs
+}
+
+// Embeddings 通过 batchEmbedContents 生成向量
+func (p *Provider) Embeddings(req *models.EmbeddingRequest, apiKey string) (*models.EmbeddingResponse, error) {
+	batchReq, texts, err := convertEmbeddingRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	reqBody, err := json.Marshal(batchReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/models/%s:batchEmbedContents?key=%s", p.baseURL, req.Model, apiKey)
+	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 120 * time.Second, Transport: p.transport}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp.StatusCode, body)
+	}
+
+	var geminiResp BatchEmbedContentsResponse
+	if err := json.Unmarshal(body, &geminiResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(geminiResp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(geminiResp.Embeddings))
+	}
+
+	result := &models.EmbeddingResponse{
+		Object: "list",
+		Data:   make([]models.Embedding, 0, len(geminiResp.Embeddings)),
+		Model:  req.Model,
+	}
+	for i, e := range geminiResp.Embeddings {
+		result.Data = append(result.Data, models.NewEmbedding(i, e.Values, req.EncodingFormat))
+	}
+
+	// batchEmbedContents 不返回 token 数，用内置分词器估算，与 Gemini 的实际计费可能不同
+	for _, text := range texts {
+		count, _ := tokenizer.Default().Count(req.Model, text)
+		result.Usage.PromptTokens += count
+	}
+	result.Usage.TotalTokens = result.Usage.PromptTokens
+	result.Usage.Estimated = true
+
+	return result, nil
+}
+
+// convertEmbeddingRequest 转换为 batchEmbedContents 请求，dimensions 对应 outputDimensionality，task_type 对应 taskType
+func convertEmbeddingRequest(req *models.EmbeddingRequest) (*BatchEmbedContentsRequest, []string, error) {
+	inputs, err := req.Inputs()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if req.EncodingFormat != "" && req.EncodingFormat != "float" && req.EncodingFormat != "base64" {
+		return nil, nil, models.NewRequestError("encoding_format", "encoding_format must be float or base64")
+	}
+	if req.Dimensions < 0 {
+		return nil, nil, models.NewRequestError("dimensions", "dimensions must be positive")
+	}
+	taskType := strings.ToUpper(req.TaskType)
+	if taskType != "" && !taskTypes[taskType] {
+		return nil, nil, models.NewRequestError("task_type", "unknown task_type %q", req.TaskType)
+	}
+
+	batchReq := &BatchEmbedContentsRequest{Requests: make([]EmbedContentRequest, 0, len(inputs))}
+	texts := make([]string, 0, len(inputs))
+	for i, input := range inputs {
+		var text string
+		if err := json.Unmarshal(input, &text); err != nil {
+			return nil, nil, models.NewRequestError(fmt.Sprintf("input[%d]", i), "Gemini does not support token array input")
+		}
+		texts = append(texts, text)
+		batchReq.Requests = append(batchReq.Requests, EmbedContentRequest{
+			Model:                "models/" + req.Model,
+			Content:              Content{Parts: []Part{{Text: text}}},
+			TaskType:             taskType,
+			OutputDimensionality: req.Dimensions,
+		})
+	}
+	return batchReq, texts, nil
+}
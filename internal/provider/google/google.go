@@ -8,7 +8,9 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"openbridge/internal/config"
 	"openbridge/internal/models"
+	"openbridge/internal/provider"
 	"strings"
 	"time"
 
@@ -17,9 +19,16 @@ import (
 
 // Provider Google Gemini 原生 API 提供商实现
 type Provider struct {
-	name    string
-	baseURL string
-	apiKey  string // Google 使用 query parameter 传递 API key
+	name      string
+	baseURL   string
+	apiKey    string            // Google 使用 query parameter 传递 API key
+	transport http.RoundTripper // 为 nil 时使用 http.DefaultTransport
+
+	cache    *contextCache // cachedContents 资源
+	cacheTTL time.Duration
+	convert  ConvertOptions
+
+	searchModels map[string]string // 模型别名 -> 实际模型，别名请求自动开启 Google Search grounding
 }
 
 // New 创建新的 Google Provider
@@ -31,9 +40,50 @@ func New(name, baseURL string) *Provider {
 	baseURL = strings.TrimSuffix(baseURL, "/")
 
 	return &Provider{
-		name:    name,
-		baseURL: baseURL,
+		name:     name,
+		baseURL:  baseURL,
+		cache:    newContextCache(),
+		cacheTTL: defaultCacheTTL,
+	}
+}
+
+// Options Google Provider 专属选项
+type Options struct {
+	CacheTTL       time.Duration     `yaml:"cache_ttl"`       // cachedContents 默认有效期，默认 5m
+	SafetySettings []SafetySetting   `yaml:"safety_settings"` // 替换默认的安全设置（四个类别均为 BLOCK_NONE）
+	SearchModels   map[string]string `yaml:"search_models"`   // 开启 Google Search grounding 的模型别名 -> 实际模型
+}
+
+func init() {
+	provider.RegisterFactory(func(name string, cfg config.ProviderConfig) (provider.Provider, error) {
+		var opts Options
+		if err := cfg.DecodeOptions(&opts); err != nil {
+			return nil, err
+		}
+
+		p := New(name, cfg.BaseURL)
+		if opts.CacheTTL > 0 {
+			p.cacheTTL = opts.CacheTTL
+		}
+		p.convert.SafetySettings = opts.SafetySettings
+		p.searchModels = opts.SearchModels
+		return p, nil
+	}, "google", "gemini")
+}
+
+// resolveModel 将 search_models 中的别名解析为实际模型，别名请求开启 Google Search grounding
+func (p *Provider) resolveModel(model string) (string, ConvertOptions) {
+	opts := p.convert
+	if target, ok := p.searchModels[model]; ok {
+		opts.GoogleSearch = true
+		return target, opts
 	}
+	return model, opts
+}
+
+// SetTransport 替换访问上游使用的 http.RoundTripper
+func (p *Provider) SetTransport(rt http.RoundTripper) {
+	p.transport = rt
 }
 
 func (p *Provider) Name() string {
@@ -51,31 +101,17 @@ func (p *Provider) SupportsStreaming() bool {
 // ChatCompletion 发送非流式聊天请求
 func (p *Provider) ChatCompletion(req *models.ChatCompletionRequest, apiKey string) (*models.ChatCompletionResponse, error) {
 	// 转换为 Gemini 格式
-	geminiReq, err := ConvertFromOpenAI(req)
+	model, opts := p.resolveModel(req.Model)
+	geminiReq, err := ConvertFromOpenAI(req, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to convert request: %w", err)
 	}
 
-	reqBody, err := json.Marshal(geminiReq)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
 	// Google API 使用模型名称作为路径的一部分
-	modelName := req.Model
-	url := fmt.Sprintf("%s/models/%s:generateContent?key=%s", p.baseURL, modelName, apiKey)
-
-	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(reqBody))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{Timeout: 120 * time.Second}
-	resp, err := client.Do(httpReq)
+	url := fmt.Sprintf("%s/models/%s:generateContent?key=%s", p.baseURL, model, apiKey)
+	resp, err := p.sendWithContextCache(url, geminiReq, model, apiKey)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
@@ -85,26 +121,16 @@ func (p *Provider) ChatCompletion(req *models.ChatCompletionRequest, apiKey stri
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		// 尝试解析错误响应
-		var errResp ErrorResponse
-		if err := json.Unmarshal(body, &errResp); err == nil {
-			return nil, &APIError{
-				StatusCode: resp.StatusCode,
-				Message:    errResp.Error.Message,
-				Code:       errResp.Error.Code,
-				Status:     errResp.Error.Status,
-			}
-		}
-		return nil, &APIError{
-			StatusCode: resp.StatusCode,
-			Message:    string(body),
-		}
+		return nil, newAPIError(resp.StatusCode, body)
 	}
 
 	var geminiResp GenerateContentResponse
 	if err := json.Unmarshal(body, &geminiResp); err != nil {
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
+	if err := checkPromptFeedback(&geminiResp); err != nil {
+		return nil, err
+	}
 
 	// 转换为 OpenAI 格式
 	requestID := "chatcmpl-" + uuid.New().String()
@@ -124,60 +150,31 @@ func (p *Provider) ChatCompletionStream(req *models.ChatCompletionRequest, apiKe
 		defer close(errChan)
 
 		// 转换为 Gemini 格式
-		geminiReq, err := ConvertFromOpenAI(req)
+		model, opts := p.resolveModel(req.Model)
+		geminiReq, err := ConvertFromOpenAI(req, opts)
 		if err != nil {
 			errChan <- fmt.Errorf("failed to convert request: %w", err)
 			return
 		}
 
-		reqBody, err := json.Marshal(geminiReq)
-		if err != nil {
-			errChan <- fmt.Errorf("failed to marshal request: %w", err)
-			return
-		}
-
 		// Google 流式 API
-		modelName := req.Model
-		url := fmt.Sprintf("%s/models/%s:streamGenerateContent?key=%s&alt=sse", p.baseURL, modelName, apiKey)
-
-		httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(reqBody))
+		url := fmt.Sprintf("%s/models/%s:streamGenerateContent?key=%s&alt=sse", p.baseURL, model, apiKey)
+		resp, err := p.sendWithContextCache(url, geminiReq, model, apiKey)
 		if err != nil {
-			errChan <- fmt.Errorf("failed to create request: %w", err)
-			return
-		}
-
-		httpReq.Header.Set("Content-Type", "application/json")
-
-		client := &http.Client{Timeout: 120 * time.Second}
-		resp, err := client.Do(httpReq)
-		if err != nil {
-			errChan <- fmt.Errorf("failed to send request: %w", err)
+			errChan <- err
 			return
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode != http.StatusOK {
 			body, _ := io.ReadAll(resp.Body)
-			var errResp ErrorResponse
-			if err := json.Unmarshal(body, &errResp); err == nil {
-				errChan <- &APIError{
-					StatusCode: resp.StatusCode,
-					Message:    errResp.Error.Message,
-					Code:       errResp.Error.Code,
-					Status:     errResp.Error.Status,
-				}
-			} else {
-				errChan <- &APIError{
-					StatusCode: resp.StatusCode,
-					Message:    string(body),
-				}
-			}
+			errChan <- newAPIError(resp.StatusCode, body)
 			return
 		}
 
 		// 生成唯一的 chunk ID
 		chunkID := "chatcmpl-" + uuid.New().String()
-		isFirst := true
+		converter := NewStreamConverter(chunkID, req.Model, geminiReq.GenerationConfig.CandidateCount)
 
 		// 解析 SSE 流
 		scanner := bufio.NewScanner(resp.Body)
@@ -203,34 +200,60 @@ func (p *Provider) ChatCompletionStream(req *models.ChatCompletionRequest, apiKe
 				continue
 			}
 
-			// 转换为 OpenAI 格式的 chunk
-			chunk := ConvertStreamResponseToChunk(&geminiResp, chunkID, req.Model, isFirst)
-			chunk.Created = time.Now().Unix()
-
-			if isFirst {
-				isFirst = false
+			if err := checkPromptFeedback(&geminiResp); err != nil {
+				errChan <- err
+				return
 			}
 
-			// 发送 chunk
-			chunkChan <- chunk
+			// 转换为 OpenAI 格式的 chunk 并发送
+			if chunk := converter.Convert(&geminiResp); chunk != nil {
+				chunk.Created = time.Now().Unix()
+				chunkChan <- chunk
+			}
 
-			// 检查是否结束
-			if len(geminiResp.Candidates) > 0 {
-				finishReason := geminiResp.Candidates[0].FinishReason
-				if finishReason != "" && finishReason != "FINISH_REASON_UNSPECIFIED" {
-					return
-				}
+			// 所有候选都结束后退出
+			if converter.Done() {
+				break
 			}
 		}
 
 		if err := scanner.Err(); err != nil {
 			errChan <- fmt.Errorf("stream read error: %w", err)
+			return
+		}
+
+		if req.IncludeUsage() {
+			if chunk := converter.UsageChunk(); chunk != nil {
+				chunkChan <- chunk
+			}
 		}
 	}()
 
 	return chunkChan, errChan
 }
 
+// postGenerate 发送 generateContent / streamGenerateContent 请求
+func (p *Provider) postGenerate(url string, geminiReq *GenerateContentRequest) (*http.Response, error) {
+	reqBody, err := json.Marshal(geminiReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 120 * time.Second, Transport: p.transport}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	return resp, nil
+}
+
 // ListModels 获取模型列表
 func (p *Provider) ListModels(apiKey string) (*models.ModelList, error) {
 	// 调用 Google API 获取模型列表
@@ -241,7 +264,7 @@ func (p *Provider) ListModels(apiKey string) (*models.ModelList, error) {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := &http.Client{Timeout: 30 * time.Second, Transport: p.transport}
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
@@ -281,10 +304,10 @@ func (p *Provider) ListModels(apiKey string) (*models.ModelList, error) {
 	}
 
 	for _, geminiModel := range geminiModels.Models {
-		// 只包含支持 generateContent 的模型
+		// 只包含支持 generateContent、embedContent 或 predict（Imagen）的模型
 		supportsGenerate := false
 		for _, method := range geminiModel.SupportedGenerationMethods {
-			if method == "generateContent" {
+			if method == "generateContent" || method == "embedContent" || method == "predict" {
 				supportsGenerate = true
 				break
 			}
@@ -317,10 +340,31 @@ type APIError struct {
 	Status     string
 }
 
+// newAPIError 解析 Gemini 错误响应，无法解析时使用原始响应体
+func newAPIError(statusCode int, body []byte) *APIError {
+	var errResp ErrorResponse
+	if err := json.Unmarshal(body, &errResp); err == nil && errResp.Error.Message != "" {
+		return &APIError{
+			StatusCode: statusCode,
+			Message:    errResp.Error.Message,
+			Code:       errResp.Error.Code,
+			Status:     errResp.Error.Status,
+		}
+	}
+	return &APIError{
+		StatusCode: statusCode,
+		Message:    string(body),
+	}
+}
+
+// HTTPStatus 返回上游 HTTP 状态码，供 handler 原样返回给客户端
+func (e *APIError) HTTPStatus() int {
+	return e.StatusCode
+}
+
 func (e *APIError) Error() string {
 	if e.Status != "" {
 		return fmt.Sprintf("Google API error (status %d, code %d, %s): %s", e.StatusCode, e.Code, e.Status, e.Message)
 	}
 	return fmt.Sprintf("Google API error (status %d): %s", e.StatusCode, e.Message)
 }
-
@@ -0,0 +1,173 @@
+package google
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"openbridge/internal/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxImagenSamples Imagen 单次请求最多生成 4 张图片
+const maxImagenSamples = 4
+
+// imagenAspectRatios Imagen 支持的宽高比
+var imagenAspectRatios = []struct {
+	name  string
+	ratio float64
+}{
+	{"1:1", 1},
+	{"3:4", 3.0 / 4},
+	{"4:3", 4.0 / 3},
+	{"9:16", 9.0 / 16},
+	{"16:9", 16.0 / 9},
+}
+
+// GenerateImages 通过 Imagen predict 生成图片，总是返回 b64_json
+func (p *Provider) GenerateImages(req *models.ImageGenerationRequest, apiKey string) (*models.ImageResponse, error) {
+	predictReq, err := convertImageRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	reqBody, err := json.Marshal(predictReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/models/%s:predict?key=%s", p.baseURL, req.Model, apiKey)
+	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 120 * time.Second, Transport: p.transport}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp.StatusCode, body)
+	}
+
+	var predictResp PredictResponse
+	if err := json.Unmarshal(body, &predictResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	result := &models.ImageResponse{
+		Created: time.Now().Unix(),
+		Data:    make([]models.ImageData, 0, len(predictResp.Predictions)),
+	}
+	var filtered []string
+	for _, prediction := range predictResp.Predictions {
+		if prediction.BytesBase64Encoded == "" {
+			if prediction.RaiFilteredReason != "" {
+				filtered = append(filtered, prediction.RaiFilteredReason)
+			}
+			continue
+		}
+		result.Data = append(result.Data, models.ImageData{
+			B64JSON:       prediction.BytesBase64Encoded,
+			RevisedPrompt: prediction.Prompt,
+		})
+	}
+
+	// 所有图片都被安全过滤时按 content_filter 返回，部分被过滤时只返回剩余的图片
+	if len(result.Data) == 0 {
+		message := "no images were generated by Imagen"
+		if len(filtered) > 0 {
+			message = "all images were filtered by Imagen: " + strings.Join(filtered, "; ")
+		}
+		return nil, &models.RequestError{
+			Param:   "prompt",
+			Message: message,
+			Code:    models.ErrorCodeContentFilter,
+		}
+	}
+
+	return result, nil
+}
+
+// convertImageRequest 转换为 Imagen predict 请求
+// n 对应 sampleCount（省略时按 OpenAI 的默认值 1，Imagen 自己的默认值是 4），size 转换为最接近的 aspectRatio（长边超过 1024 时使用 2K），output_format 对应 outputOptions.mimeType
+func convertImageRequest(req *models.ImageGenerationRequest) (*PredictRequest, error) {
+	if req.N < 0 {
+		return nil, models.NewRequestError("n", "n must be at least 1, got %d", req.N)
+	}
+	if req.N > maxImagenSamples {
+		return nil, models.NewRequestError("n", "Imagen generates at most %d images per request", maxImagenSamples)
+	}
+	n := req.N
+	if n == 0 {
+		n = 1
+	}
+
+	params := ImagenParameters{
+		SampleCount:      n,
+		IncludeRaiReason: true,
+	}
+
+	if req.Size != "" && req.Size != "auto" {
+		width, height, ok := parseImageSize(req.Size)
+		if !ok {
+			return nil, models.NewRequestError("size", "invalid size %q, expected WIDTHxHEIGHT", req.Size)
+		}
+		params.AspectRatio = closestAspectRatio(float64(width) / float64(height))
+		if max(width, height) > 1024 {
+			params.SampleImageSize = "2K"
+		}
+	}
+
+	switch req.OutputFormat {
+	case "":
+	case "png", "jpeg":
+		params.OutputOptions = &ImagenOutputOptions{MimeType: "image/" + req.OutputFormat}
+	default:
+		return nil, models.NewRequestError("output_format", "Imagen supports png and jpeg output only")
+	}
+
+	return &PredictRequest{
+		Instances:  []ImagenInstance{{Prompt: req.Prompt}},
+		Parameters: params,
+	}, nil
+}
+
+// parseImageSize 解析 1024x1024 形式的尺寸
+func parseImageSize(size string) (width, height int, ok bool) {
+	w, h, found := strings.Cut(size, "x")
+	if !found {
+		return 0, 0, false
+	}
+	width, err1 := strconv.Atoi(w)
+	height, err2 := strconv.Atoi(h)
+	if err1 != nil || err2 != nil || width <= 0 || height <= 0 {
+		return 0, 0, false
+	}
+	return width, height, true
+}
+
+// closestAspectRatio 选择最接近的 Imagen 宽高比
+func closestAspectRatio(ratio float64) string {
+	best := imagenAspectRatios[0]
+	for _, r := range imagenAspectRatios[1:] {
+		if math.Abs(math.Log(r.ratio/ratio)) < math.Abs(math.Log(best.ratio/ratio)) {
+			best = r
+		}
+	}
+	return best.name
+}
@@ -0,0 +1,127 @@
+package google
+
+import (
+	"fmt"
+	"openbridge/internal/models"
+)
+
+// placeholderText Gemini 要求 contents 非空且以 user 开头，缺失时插入的占位内容
+const placeholderText = "..."
+
+// convertContents 转换 system 之外的消息，并按 Gemini 的规则规范化：
+//   - assistant 映射为 model，相邻的同角色消息合并为一个 turn（不跨越 cache_control 断点）
+//   - 跳过空消息，第一个 turn 是 model 或没有任何 turn 时插入占位 user turn
+//   - 不支持的角色返回明确的 invalid_request_error
+func convertContents(geminiReq *GenerateContentRequest, messages []models.Message) error {
+	for i, msg := range messages {
+		if msg.IsSystem() {
+			continue
+		}
+
+		var role string
+		switch msg.Role {
+		case "user":
+			role = "user"
+		case "assistant":
+			role = "model"
+			if len(msg.ToolCalls) > 0 {
+				return models.NewRequestError(fmt.Sprintf("messages[%d].tool_calls", i), "tool calls are not supported by the Gemini provider")
+			}
+		case "tool", "function":
+			return models.NewRequestError(fmt.Sprintf("messages[%d].role", i), "the %s role is not supported by the Gemini provider", msg.Role)
+		default:
+			return models.NewRequestError(fmt.Sprintf("messages[%d].role", i), "unknown message role %q", msg.Role)
+		}
+
+		parts, err := convertParts(msg.Content, i)
+		if err != nil {
+			return err
+		}
+
+		if len(parts) > 0 {
+			appendContent(geminiReq, role, parts)
+		}
+
+		// 标记了 cache_control 的消息及其之前的内容作为可缓存前缀
+		if cc := findCacheControl(msg); cc != nil {
+			markCachePrefix(geminiReq, cc)
+			geminiReq.cachePrefix = len(geminiReq.Contents)
+		}
+	}
+
+	if len(geminiReq.Contents) == 0 {
+		appendContent(geminiReq, "user", []Part{{Text: placeholderText}})
+	}
+	return nil
+}
+
+// appendContent 追加一个 turn，与上一个 turn 角色相同时合并
+// 不合并进缓存前缀内的 turn，否则断点之后的内容会被算进缓存
+func appendContent(geminiReq *GenerateContentRequest, role string, parts []Part) {
+	n := len(geminiReq.Contents)
+	if n > geminiReq.cachePrefix && geminiReq.Contents[n-1].Role == role {
+		geminiReq.Contents[n-1].Parts = append(geminiReq.Contents[n-1].Parts, parts...)
+		return
+	}
+	if n == 0 && role != "user" {
+		geminiReq.Contents = append(geminiReq.Contents, Content{Role: "user", Parts: []Part{{Text: placeholderText}}})
+	}
+	geminiReq.Contents = append(geminiReq.Contents, Content{Role: role, Parts: parts})
+}
+
+// convertParts 将 OpenAI content（字符串或数组）转换为 Gemini parts，index 为消息序号
+func convertParts(content interface{}, index int) ([]Part, error) {
+	switch v := content.(type) {
+	case string:
+		if v == "" {
+			return nil, nil
+		}
+		return []Part{{Text: v}}, nil
+
+	case []interface{}:
+		parts := make([]Part, 0, len(v))
+		for j, part := range v {
+			partMap, ok := part.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			typeStr, _ := partMap["type"].(string)
+			switch typeStr {
+			case "text":
+				if text, _ := partMap["text"].(string); text != "" {
+					parts = append(parts, Part{Text: text})
+				}
+
+			case "image_url":
+				imageURL, ok := partMap["image_url"].(map[string]interface{})
+				if !ok {
+					continue
+				}
+				url, _ := imageURL["url"].(string)
+
+				inline, err := convertImage(url)
+				if err != nil {
+					return nil, models.NewRequestError(fmt.Sprintf("messages[%d].content[%d].image_url", index, j), "cannot load image: %v", err)
+				}
+				parts = append(parts, Part{InlineData: inline})
+
+			case "file":
+				inline, err := convertDocument(models.ParseFilePart(partMap))
+				if err != nil {
+					return nil, models.NewRequestError(fmt.Sprintf("messages[%d].content[%d].file", index, j), "%v", err)
+				}
+				parts = append(parts, Part{InlineData: inline})
+
+			case "input_audio":
+				inline, err := convertAudio(models.ParseInputAudioPart(partMap))
+				if err != nil {
+					return nil, models.NewRequestError(fmt.Sprintf("messages[%d].content[%d].input_audio", index, j), "%v", err)
+				}
+				parts = append(parts, Part{InlineData: inline})
+			}
+		}
+		return parts, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,71 @@
+package google
+
+import (
+	"openbridge/internal/models"
+	"testing"
+)
+
+// 同角色消息不跨越 cache_control 断点合并，缓存前缀只包含断点及之前的内容
+func TestConvertContentsCacheBreakpoint(t *testing.T) {
+	ephemeral := &models.CacheControl{Type: "ephemeral"}
+	tests := []struct {
+		name     string
+		messages []models.Message
+		roles    []string
+		parts    []int
+		prefix   int
+	}{
+		{
+			name: "same role after breakpoint",
+			messages: []models.Message{
+				{Role: "user", Content: "document", CacheControl: ephemeral},
+				{Role: "user", Content: "question"},
+			},
+			roles:  []string{"user", "user"},
+			parts:  []int{1, 1},
+			prefix: 1,
+		},
+		{
+			name: "merge before breakpoint",
+			messages: []models.Message{
+				{Role: "user", Content: "a"},
+				{Role: "user", Content: "b", CacheControl: ephemeral},
+				{Role: "assistant", Content: "c"},
+				{Role: "assistant", Content: "d"},
+			},
+			roles:  []string{"user", "model"},
+			parts:  []int{2, 2},
+			prefix: 1,
+		},
+		{
+			name: "no breakpoint",
+			messages: []models.Message{
+				{Role: "user", Content: "a"},
+				{Role: "user", Content: "b"},
+			},
+			roles:  []string{"user"},
+			parts:  []int{2},
+			prefix: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			geminiReq := &GenerateContentRequest{}
+			if err := convertContents(geminiReq, tt.messages); err != nil {
+				t.Fatal(err)
+			}
+			if len(geminiReq.Contents) != len(tt.roles) {
+				t.Fatalf("contents = %+v, want roles %v", geminiReq.Contents, tt.roles)
+			}
+			for i, content := range geminiReq.Contents {
+				if content.Role != tt.roles[i] || len(content.Parts) != tt.parts[i] {
+					t.Errorf("contents[%d] = %s with %d parts, want %s with %d", i, content.Role, len(content.Parts), tt.roles[i], tt.parts[i])
+				}
+			}
+			if geminiReq.cachePrefix != tt.prefix {
+				t.Errorf("cachePrefix = %d, want %d", geminiReq.cachePrefix, tt.prefix)
+			}
+		})
+	}
+}
@@ -1,13 +1,22 @@
 package google
 
+import "time"
+
 // Google Gemini API 原生格式定义
 
 // GenerateContentRequest Gemini API 请求
 type GenerateContentRequest struct {
-	Contents         []Content               `json:"contents"`
-	SystemInstruction *Content               `json:"systemInstruction,omitempty"`
-	GenerationConfig *GenerationConfig       `json:"generationConfig,omitempty"`
-	SafetySettings   []SafetySetting         `json:"safetySettings,omitempty"`
+	Contents          []Content         `json:"contents"`
+	SystemInstruction *Content          `json:"systemInstruction,omitempty"`
+	GenerationConfig  *GenerationConfig `json:"generationConfig,omitempty"`
+	SafetySettings    []SafetySetting   `json:"safetySettings,omitempty"`
+	Tools             []Tool            `json:"tools,omitempty"`
+	CachedContent     string            `json:"cachedContent,omitempty"` // cachedContents/{id}
+
+	// 客户端用 cache_control 标记的可缓存前缀：前 cachePrefix 条 contents（以及 systemInstruction）
+	cacheMarked bool
+	cachePrefix int
+	cacheTTL    time.Duration
 }
 
 type Content struct {
@@ -18,6 +27,10 @@ type Content struct {
 type Part struct {
 	Text       string      `json:"text,omitempty"`
 	InlineData *InlineData `json:"inlineData,omitempty"`
+
+	// thinking: thought 为 true 时 text 是思考摘要
+	Thought          bool   `json:"thought,omitempty"`
+	ThoughtSignature string `json:"thoughtSignature,omitempty"`
 }
 
 type InlineData struct {
@@ -26,23 +39,48 @@ type InlineData struct {
 }
 
 type GenerationConfig struct {
-	Temperature     float64  `json:"temperature,omitempty"`
-	TopP            float64  `json:"topP,omitempty"`
+	Temperature     *float64 `json:"temperature,omitempty"`
+	TopP            *float64 `json:"topP,omitempty"`
 	TopK            int      `json:"topK,omitempty"`
 	MaxOutputTokens int      `json:"maxOutputTokens,omitempty"`
 	StopSequences   []string `json:"stopSequences,omitempty"`
+	Seed            *int     `json:"seed,omitempty"`
+	CandidateCount  int      `json:"candidateCount,omitempty"`
+
+	// logprobs: responseLogprobs 返回所选 token 的对数概率，logprobs 为每个位置返回的候选数
+	ResponseLogprobs bool `json:"responseLogprobs,omitempty"`
+	Logprobs         *int `json:"logprobs,omitempty"`
+
+	ThinkingConfig *ThinkingConfig `json:"thinkingConfig,omitempty"`
+
+	// 结构化输出
+	ResponseMimeType string         `json:"responseMimeType,omitempty"`
+	ResponseSchema   map[string]any `json:"responseSchema,omitempty"`
+}
+
+// ThinkingConfig 思考配置，thinkingBudget 为 0 时关闭思考
+type ThinkingConfig struct {
+	ThinkingBudget  *int `json:"thinkingBudget,omitempty"`
+	IncludeThoughts bool `json:"includeThoughts,omitempty"`
+}
+
+// Tool Gemini 工具，目前只使用 Google Search grounding
+type Tool struct {
+	GoogleSearch *GoogleSearch `json:"googleSearch,omitempty"`
 }
 
+type GoogleSearch struct{}
+
 type SafetySetting struct {
-	Category  string `json:"category"`
-	Threshold string `json:"threshold"`
+	Category  string `json:"category" yaml:"category"`
+	Threshold string `json:"threshold" yaml:"threshold"`
 }
 
 // GenerateContentResponse Gemini API 响应
 type GenerateContentResponse struct {
-	Candidates     []Candidate    `json:"candidates"`
+	Candidates     []Candidate     `json:"candidates"`
 	PromptFeedback *PromptFeedback `json:"promptFeedback,omitempty"`
-	UsageMetadata  *UsageMetadata `json:"usageMetadata,omitempty"`
+	UsageMetadata  *UsageMetadata  `json:"usageMetadata,omitempty"`
 }
 
 type Candidate struct {
@@ -50,11 +88,64 @@ type Candidate struct {
 	FinishReason  string         `json:"finishReason"`
 	Index         int            `json:"index"`
 	SafetyRatings []SafetyRating `json:"safetyRatings,omitempty"`
+
+	LogprobsResult *LogprobsResult `json:"logprobsResult,omitempty"`
+	AvgLogprobs    float64         `json:"avgLogprobs,omitempty"`
+
+	GroundingMetadata *GroundingMetadata `json:"groundingMetadata,omitempty"`
+}
+
+// GroundingMetadata Google Search grounding 的搜索词、来源和引用片段
+type GroundingMetadata struct {
+	WebSearchQueries  []string           `json:"webSearchQueries,omitempty"`
+	GroundingChunks   []GroundingChunk   `json:"groundingChunks,omitempty"`
+	GroundingSupports []GroundingSupport `json:"groundingSupports,omitempty"`
+}
+
+type GroundingChunk struct {
+	Web *WebChunk `json:"web,omitempty"`
+}
+
+type WebChunk struct {
+	URI   string `json:"uri"`
+	Title string `json:"title,omitempty"`
+}
+
+// GroundingSupport 一段回答文本及支持它的来源（groundingChunks 的下标）
+type GroundingSupport struct {
+	Segment               Segment   `json:"segment"`
+	GroundingChunkIndices []int     `json:"groundingChunkIndices,omitempty"`
+	ConfidenceScores      []float64 `json:"confidenceScores,omitempty"`
+}
+
+// Segment startIndex / endIndex 为 part 文本中的字节偏移
+type Segment struct {
+	PartIndex  int    `json:"partIndex,omitempty"`
+	StartIndex int    `json:"startIndex,omitempty"`
+	EndIndex   int    `json:"endIndex,omitempty"`
+	Text       string `json:"text,omitempty"`
+}
+
+// LogprobsResult 所选 token 及每个位置的候选 token，两个数组按位置一一对应
+type LogprobsResult struct {
+	TopCandidates    []TopCandidates    `json:"topCandidates,omitempty"`
+	ChosenCandidates []LogprobCandidate `json:"chosenCandidates,omitempty"`
+}
+
+type TopCandidates struct {
+	Candidates []LogprobCandidate `json:"candidates,omitempty"`
+}
+
+type LogprobCandidate struct {
+	Token          string  `json:"token"`
+	TokenID        int     `json:"tokenId,omitempty"`
+	LogProbability float64 `json:"logProbability"`
 }
 
 type SafetyRating struct {
 	Category    string `json:"category"`
 	Probability string `json:"probability"`
+	Blocked     bool   `json:"blocked,omitempty"`
 }
 
 type PromptFeedback struct {
@@ -63,9 +154,11 @@ type PromptFeedback struct {
 }
 
 type UsageMetadata struct {
-	PromptTokenCount     int `json:"promptTokenCount"`
-	CandidatesTokenCount int `json:"candidatesTokenCount"`
-	TotalTokenCount      int `json:"totalTokenCount"`
+	PromptTokenCount        int `json:"promptTokenCount"`
+	CandidatesTokenCount    int `json:"candidatesTokenCount"`
+	ThoughtsTokenCount      int `json:"thoughtsTokenCount,omitempty"`
+	CachedContentTokenCount int `json:"cachedContentTokenCount,omitempty"`
+	TotalTokenCount         int `json:"totalTokenCount"`
 }
 
 // StreamResponse Gemini 流式响应（与非流式相同）
@@ -90,15 +183,84 @@ type ModelsListResponse struct {
 
 type ModelInfo struct {
 	Name                       string   `json:"name"`
-	BaseModelID               string   `json:"baseModelId,omitempty"`
+	BaseModelID                string   `json:"baseModelId,omitempty"`
 	Version                    string   `json:"version"`
 	DisplayName                string   `json:"displayName"`
 	Description                string   `json:"description"`
-	InputTokenLimit           int      `json:"inputTokenLimit"`
-	OutputTokenLimit          int      `json:"outputTokenLimit"`
+	InputTokenLimit            int      `json:"inputTokenLimit"`
+	OutputTokenLimit           int      `json:"outputTokenLimit"`
 	SupportedGenerationMethods []string `json:"supportedGenerationMethods"`
 	Temperature                float64  `json:"temperature,omitempty"`
 	TopP                       float64  `json:"topP,omitempty"`
 	TopK                       int      `json:"topK,omitempty"`
 }
 
+// BatchEmbedContentsRequest batchEmbedContents 请求
+type BatchEmbedContentsRequest struct {
+	Requests []EmbedContentRequest `json:"requests"`
+}
+
+type EmbedContentRequest struct {
+	Model                string  `json:"model"` // models/{model}
+	Content              Content `json:"content"`
+	TaskType             string  `json:"taskType,omitempty"`
+	OutputDimensionality int     `json:"outputDimensionality,omitempty"`
+}
+
+// BatchEmbedContentsResponse batchEmbedContents 响应，顺序与请求相同
+type BatchEmbedContentsResponse struct {
+	Embeddings []ContentEmbedding `json:"embeddings"`
+}
+
+type ContentEmbedding struct {
+	Values []float64 `json:"values"`
+}
+
+// PredictRequest Imagen predict 请求
+type PredictRequest struct {
+	Instances  []ImagenInstance `json:"instances"`
+	Parameters ImagenParameters `json:"parameters"`
+}
+
+type ImagenInstance struct {
+	Prompt string `json:"prompt"`
+}
+
+type ImagenParameters struct {
+	SampleCount      int                  `json:"sampleCount,omitempty"`
+	AspectRatio      string               `json:"aspectRatio,omitempty"`     // 1:1、3:4、4:3、9:16、16:9
+	SampleImageSize  string               `json:"sampleImageSize,omitempty"` // 1K、2K
+	IncludeRaiReason bool                 `json:"includeRaiReason,omitempty"`
+	OutputOptions    *ImagenOutputOptions `json:"outputOptions,omitempty"`
+}
+
+type ImagenOutputOptions struct {
+	MimeType string `json:"mimeType,omitempty"` // image/png、image/jpeg
+}
+
+// PredictResponse Imagen predict 响应，被安全过滤的图片只有 raiFilteredReason
+type PredictResponse struct {
+	Predictions []ImagenPrediction `json:"predictions"`
+}
+
+type ImagenPrediction struct {
+	BytesBase64Encoded string `json:"bytesBase64Encoded,omitempty"`
+	MimeType           string `json:"mimeType,omitempty"`
+	Prompt             string `json:"prompt,omitempty"` // 开启 prompt 改写时的实际 prompt
+	RaiFilteredReason  string `json:"raiFilteredReason,omitempty"`
+}
+
+// CountTokensRequest countTokens 请求，按完整的 generateContent 请求计算（包含 systemInstruction 和 tools）
+type CountTokensRequest struct {
+	GenerateContentRequest CountTokensContentRequest `json:"generateContentRequest"`
+}
+
+type CountTokensContentRequest struct {
+	Model string `json:"model"` // models/{model}
+	*GenerateContentRequest
+}
+
+// CountTokensResponse countTokens 响应
+type CountTokensResponse struct {
+	TotalTokens int `json:"totalTokens"`
+}
@@ -0,0 +1,154 @@
+package google
+
+import "strings"
+
+// Gemini responseSchema 只支持 OpenAPI Schema 的一个子集，类型使用大写枚举值
+// 参考: https://ai.google.dev/api/caching#Schema
+
+// schemaFields Gemini Schema 支持的字段
+var schemaFields = map[string]bool{
+	"type":             true,
+	"format":           true,
+	"title":            true,
+	"description":      true,
+	"nullable":         true,
+	"enum":             true,
+	"maxItems":         true,
+	"minItems":         true,
+	"properties":       true,
+	"required":         true,
+	"propertyOrdering": true,
+	"items":            true,
+	"anyOf":            true,
+	"minimum":          true,
+	"maximum":          true,
+	"minLength":        true,
+	"maxLength":        true,
+	"pattern":          true,
+	"minProperties":    true,
+	"maxProperties":    true,
+}
+
+// maxSchemaDepth 展开 $ref 的最大深度，防止递归 schema 无限展开
+const maxSchemaDepth = 16
+
+// ConvertSchema 将 JSON Schema 转换为 Gemini Schema：
+// 展开本地 $ref，类型转为大写，["string", "null"] 转为 nullable，丢弃不支持的字段
+func ConvertSchema(schema map[string]any) map[string]any {
+	defs, _ := schema["$defs"].(map[string]any)
+	if defs == nil {
+		defs, _ = schema["definitions"].(map[string]any)
+	}
+	return convertSchemaNode(schema, defs, 0)
+}
+
+func convertSchemaNode(node map[string]any, defs map[string]any, depth int) map[string]any {
+	if depth > maxSchemaDepth {
+		return map[string]any{"type": "OBJECT"}
+	}
+
+	// 展开 $ref: "#/$defs/Name" 或 "#/definitions/Name"
+	if ref, ok := node["$ref"].(string); ok {
+		name := ref[strings.LastIndex(ref, "/")+1:]
+		if def, ok := defs[name].(map[string]any); ok {
+			return convertSchemaNode(def, defs, depth+1)
+		}
+		return map[string]any{"type": "OBJECT"}
+	}
+
+	out := make(map[string]any)
+	for key, value := range node {
+		if !schemaFields[key] {
+			continue
+		}
+
+		switch key {
+		case "type":
+			switch t := value.(type) {
+			case string:
+				out["type"] = strings.ToUpper(t)
+			case []interface{}:
+				// ["string", "null"] -> STRING + nullable
+				for _, item := range t {
+					if s, ok := item.(string); ok {
+						if s == "null" {
+							out["nullable"] = true
+						} else if _, exists := out["type"]; !exists {
+							out["type"] = strings.ToUpper(s)
+						}
+					}
+				}
+			}
+
+		case "properties":
+			props, ok := value.(map[string]any)
+			if !ok {
+				continue
+			}
+			converted := make(map[string]any, len(props))
+			for name, prop := range props {
+				if propMap, ok := prop.(map[string]any); ok {
+					converted[name] = convertSchemaNode(propMap, defs, depth+1)
+				}
+			}
+			out["properties"] = converted
+
+		case "items":
+			if items, ok := value.(map[string]any); ok {
+				out["items"] = convertSchemaNode(items, defs, depth+1)
+			}
+
+		case "anyOf":
+			variants, ok := value.([]interface{})
+			if !ok {
+				continue
+			}
+			converted := make([]interface{}, 0, len(variants))
+			for _, v := range variants {
+				if vm, ok := v.(map[string]any); ok {
+					// {"type": "null"} 分支转为 nullable
+					if t, _ := vm["type"].(string); t == "null" {
+						out["nullable"] = true
+						continue
+					}
+					converted = append(converted, convertSchemaNode(vm, defs, depth+1))
+				}
+			}
+			if len(converted) == 1 {
+				for k, v := range converted[0].(map[string]any) {
+					out[k] = v
+				}
+			} else if len(converted) > 1 {
+				out["anyOf"] = converted
+			}
+
+		default:
+			out[key] = value
+		}
+	}
+
+	// const 转为单值 enum
+	if c, ok := node["const"]; ok {
+		out["enum"] = []interface{}{c}
+	}
+	// Gemini 的 enum 只允许字符串
+	if enum, ok := out["enum"].([]interface{}); ok {
+		if _, hasType := out["type"]; !hasType {
+			out["type"] = "STRING"
+		}
+		if out["type"] == "STRING" {
+			strEnum := make([]interface{}, 0, len(enum))
+			for _, e := range enum {
+				if s, ok := e.(string); ok {
+					strEnum = append(strEnum, s)
+				}
+			}
+			out["enum"] = strEnum
+			out["format"] = "enum"
+		} else {
+			delete(out, "enum")
+		}
+	}
+
+	return out
+}
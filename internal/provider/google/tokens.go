@@ -0,0 +1,62 @@
+package google
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"openbridge/internal/models"
+	"time"
+)
+
+// CountTokens 通过 countTokens 计算输入 token 数
+// 请求与 ChatCompletion 使用相同的转换，但不使用 cachedContents，结果为完整输入的 token 数
+func (p *Provider) CountTokens(req *models.ChatCompletionRequest, apiKey string) (*models.TokenCountResponse, error) {
+	model, opts := p.resolveModel(req.Model)
+	geminiReq, err := ConvertFromOpenAI(req, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert request: %w", err)
+	}
+
+	reqBody, err := json.Marshal(CountTokensRequest{
+		GenerateContentRequest: CountTokensContentRequest{
+			Model:                  "models/" + model,
+			GenerateContentRequest: geminiReq,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/models/%s:countTokens?key=%s", p.baseURL, model, apiKey)
+	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 30 * time.Second, Transport: p.transport}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp.StatusCode, body)
+	}
+
+	var countResp CountTokensResponse
+	if err := json.Unmarshal(body, &countResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return &models.TokenCountResponse{InputTokens: countResp.TotalTokens}, nil
+}
@@ -0,0 +1,94 @@
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"openbridge/internal/models"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Fixture 一条脚本化响应
+// 按顺序匹配，Match 为空的 fixture 作为兜底
+type Fixture struct {
+	Match        string            `yaml:"match" json:"match"`                 // 最后一条 user 消息包含该文本时命中（不区分大小写）
+	Model        string            `yaml:"model" json:"model"`                 // 可选，限定模型
+	Content      string            `yaml:"content" json:"content"`             // 回复文本
+	ToolCalls    []FixtureToolCall `yaml:"tool_calls" json:"tool_calls"`       // 生成的工具调用
+	FinishReason string            `yaml:"finish_reason" json:"finish_reason"` // 默认 stop，有工具调用时为 tool_calls
+	Error        *FixtureError     `yaml:"error" json:"error"`                 // 返回错误而不是响应
+}
+
+type FixtureToolCall struct {
+	ID        string      `yaml:"id" json:"id"`
+	Name      string      `yaml:"name" json:"name"`
+	Arguments interface{} `yaml:"arguments" json:"arguments"` // 可以是对象或 JSON 字符串
+}
+
+type FixtureError struct {
+	Status  int    `yaml:"status" json:"status"`
+	Message string `yaml:"message" json:"message"`
+}
+
+// loadFixtures 从 YAML 或 JSON 文件加载 fixtures（YAML 是 JSON 的超集）
+func loadFixtures(path string) ([]Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+
+	var fixtures []Fixture
+	if err := yaml.Unmarshal(data, &fixtures); err != nil {
+		return nil, fmt.Errorf("failed to parse fixtures %s: %w", path, err)
+	}
+	return fixtures, nil
+}
+
+// findFixture 找到第一条匹配的 fixture
+func findFixture(fixtures []Fixture, model, prompt string) *Fixture {
+	prompt = strings.ToLower(prompt)
+	for i := range fixtures {
+		f := &fixtures[i]
+		if f.Model != "" && f.Model != model {
+			continue
+		}
+		if f.Match == "" || strings.Contains(prompt, strings.ToLower(f.Match)) {
+			return f
+		}
+	}
+	return nil
+}
+
+// toolCalls 将 fixture 中的工具调用转换为 OpenAI 格式，ID 按序号确定性生成
+func (f *Fixture) toolCalls() []models.ToolCall {
+	calls := make([]models.ToolCall, 0, len(f.ToolCalls))
+	for i, tc := range f.ToolCalls {
+		id := tc.ID
+		if id == "" {
+			id = fmt.Sprintf("call_mock_%d", i)
+		}
+
+		var args string
+		switch v := tc.Arguments.(type) {
+		case nil:
+			args = "{}"
+		case string:
+			args = v
+		default:
+			data, _ := json.Marshal(v)
+			args = string(data)
+		}
+
+		calls = append(calls, models.ToolCall{
+			ID:   id,
+			Type: "function",
+			Function: models.FunctionCall{
+				Name:      tc.Name,
+				Arguments: args,
+			},
+		})
+	}
+	return calls
+}
@@ -0,0 +1,361 @@
+package mock
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"openbridge/internal/config"
+	"openbridge/internal/models"
+	"openbridge/internal/provider"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Options Mock Provider 专属选项
+type Options struct {
+	Mode          string        `yaml:"mode"`            // echo（默认）、fixed、fixtures
+	Text          string        `yaml:"text"`            // fixed 模式的回复文本
+	FixturesFile  string        `yaml:"fixtures_file"`   // fixtures 模式的脚本文件 (YAML/JSON)
+	ChunkSize     int           `yaml:"chunk_size"`      // 流式每个 chunk 的字符数，默认 8
+	Latency       time.Duration `yaml:"latency"`         // 首个响应前的延迟
+	ChunkDelay    time.Duration `yaml:"chunk_delay"`     // 流式 chunk 之间的延迟
+	ErrorRate     float64       `yaml:"error_rate"`      // 注入 500 错误的概率 (0-1)
+	RateLimitRate float64       `yaml:"rate_limit_rate"` // 注入 429 错误的概率 (0-1)
+	Seed          int64         `yaml:"seed"`            // 错误注入的随机种子，默认 1
+	Models        []string      `yaml:"models"`          // ListModels 返回的模型，默认 mock-1
+}
+
+// Provider 不访问任何上游、返回确定性结果的离线测试提供商
+type Provider struct {
+	name     string
+	opts     Options
+	fixtures []Fixture
+
+	counter uint64
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// New 创建新的 Mock Provider
+func New(name string, opts Options) (*Provider, error) {
+	if opts.Mode == "" {
+		opts.Mode = "echo"
+	}
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = 8
+	}
+	if opts.Seed == 0 {
+		opts.Seed = 1
+	}
+	if len(opts.Models) == 0 {
+		opts.Models = []string{"mock-1"}
+	}
+
+	p := &Provider{
+		name: name,
+		opts: opts,
+		rng:  rand.New(rand.NewSource(opts.Seed)),
+	}
+
+	switch opts.Mode {
+	case "echo", "fixed":
+	case "fixtures":
+		fixtures, err := loadFixtures(opts.FixturesFile)
+		if err != nil {
+			return nil, err
+		}
+		p.fixtures = fixtures
+	default:
+		return nil, fmt.Errorf("unknown mock mode %q (supported: echo, fixed, fixtures)", opts.Mode)
+	}
+
+	return p, nil
+}
+
+func init() {
+	provider.RegisterFactory(func(name string, cfg config.ProviderConfig) (provider.Provider, error) {
+		var opts Options
+		if err := cfg.DecodeOptions(&opts); err != nil {
+			return nil, err
+		}
+		return New(name, opts)
+	}, "mock")
+}
+
+func (p *Provider) Name() string {
+	return p.name
+}
+
+func (p *Provider) Type() string {
+	return "mock"
+}
+
+func (p *Provider) SupportsStreaming() bool {
+	return true
+}
+
+// reply 一次请求的完整结果
+type reply struct {
+	content      string
+	toolCalls    []models.ToolCall
+	finishReason string
+}
+
+// respond 根据模式生成回复，或返回注入的错误
+func (p *Provider) respond(req *models.ChatCompletionRequest) (*reply, error) {
+	if err := p.injectError(); err != nil {
+		return nil, err
+	}
+
+	prompt := lastUserText(req.Messages)
+
+	switch p.opts.Mode {
+	case "fixed":
+		return &reply{content: p.opts.Text, finishReason: "stop"}, nil
+
+	case "fixtures":
+		f := findFixture(p.fixtures, req.Model, prompt)
+		if f == nil {
+			return nil, &APIError{
+				StatusCode: http.StatusNotFound,
+				Message:    "no mock fixture matches the request",
+			}
+		}
+		if f.Error != nil {
+			status := f.Error.Status
+			if status == 0 {
+				status = http.StatusInternalServerError
+			}
+			return nil, &APIError{StatusCode: status, Message: f.Error.Message}
+		}
+
+		r := &reply{
+			content:      f.Content,
+			toolCalls:    f.toolCalls(),
+			finishReason: f.FinishReason,
+		}
+		if r.finishReason == "" {
+			r.finishReason = "stop"
+			if len(r.toolCalls) > 0 {
+				r.finishReason = "tool_calls"
+			}
+		}
+		return r, nil
+
+	default:
+		return &reply{content: prompt, finishReason: "stop"}, nil
+	}
+}
+
+// injectError 按配置的概率注入 429 / 500 错误
+func (p *Provider) injectError() error {
+	if p.opts.ErrorRate <= 0 && p.opts.RateLimitRate <= 0 {
+		return nil
+	}
+
+	p.mu.Lock()
+	roll := p.rng.Float64()
+	p.mu.Unlock()
+
+	if roll < p.opts.RateLimitRate {
+		return &APIError{StatusCode: http.StatusTooManyRequests, Message: "mock rate limit exceeded"}
+	}
+	if roll < p.opts.RateLimitRate+p.opts.ErrorRate {
+		return &APIError{StatusCode: http.StatusInternalServerError, Message: "mock upstream error"}
+	}
+	return nil
+}
+
+// nextID 生成确定性的响应 ID
+func (p *Provider) nextID() string {
+	return fmt.Sprintf("chatcmpl-mock-%d", atomic.AddUint64(&p.counter, 1))
+}
+
+// ChatCompletion 发送非流式聊天请求
+func (p *Provider) ChatCompletion(req *models.ChatCompletionRequest, apiKey string) (*models.ChatCompletionResponse, error) {
+	time.Sleep(p.opts.Latency)
+
+	r, err := p.respond(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ChatCompletionResponse{
+		ID:      p.nextID(),
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   req.Model,
+		Choices: []models.Choice{
+			{
+				Index: 0,
+				Message: models.ResponseMessage{
+					Role:      "assistant",
+					Content:   r.content,
+					ToolCalls: r.toolCalls,
+				},
+				FinishReason: r.finishReason,
+			},
+		},
+		Usage: usageFor(req, r),
+	}, nil
+}
+
+// ChatCompletionStream 发送流式聊天请求，按 chunk_size 切分内容模拟流式输出
+func (p *Provider) ChatCompletionStream(req *models.ChatCompletionRequest, apiKey string) (<-chan *models.ChatCompletionChunk, <-chan error) {
+	chunkChan := make(chan *models.ChatCompletionChunk, 100)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(chunkChan)
+		defer close(errChan)
+
+		time.Sleep(p.opts.Latency)
+
+		r, err := p.respond(req)
+		if err != nil {
+			errChan <- err
+			return
+		}
+
+		chunkID := p.nextID()
+		send := func(delta models.ChunkDelta, finishReason *string) {
+			chunkChan <- &models.ChatCompletionChunk{
+				ID:      chunkID,
+				Object:  "chat.completion.chunk",
+				Created: time.Now().Unix(),
+				Model:   req.Model,
+				Choices: []models.ChunkChoice{
+					{Index: 0, Delta: delta, FinishReason: finishReason},
+				},
+			}
+		}
+
+		send(models.ChunkDelta{Role: "assistant"}, nil)
+
+		for _, piece := range splitRunes(r.content, p.opts.ChunkSize) {
+			time.Sleep(p.opts.ChunkDelay)
+			send(models.ChunkDelta{Content: piece}, nil)
+		}
+
+		for i, tc := range r.toolCalls {
+			index := i
+			// 第一个 chunk 携带 id 和函数名，之后只发送参数增量
+			pieces := splitRunes(tc.Function.Arguments, p.opts.ChunkSize)
+			if len(pieces) == 0 {
+				pieces = []string{""}
+			}
+			for j, piece := range pieces {
+				time.Sleep(p.opts.ChunkDelay)
+				call := models.ToolCall{Index: &index, Function: models.FunctionCall{Arguments: piece}}
+				if j == 0 {
+					call.ID = tc.ID
+					call.Type = tc.Type
+					call.Function.Name = tc.Function.Name
+				}
+				send(models.ChunkDelta{ToolCalls: []models.ToolCall{call}}, nil)
+			}
+		}
+
+		finishReason := r.finishReason
+		send(models.ChunkDelta{}, &finishReason)
+
+		if req.IncludeUsage() {
+			chunkChan <- models.NewUsageChunk(chunkID, req.Model, usageFor(req, r))
+		}
+	}()
+
+	return chunkChan, errChan
+}
+
+// ListModels 获取模型列表
+func (p *Provider) ListModels(apiKey string) (*models.ModelList, error) {
+	list := &models.ModelList{
+		Object: "list",
+		Data:   make([]models.Model, 0, len(p.opts.Models)),
+	}
+	for _, id := range p.opts.Models {
+		list.Data = append(list.Data, models.Model{
+			ID:      id,
+			Object:  "model",
+			OwnedBy: "mock",
+		})
+	}
+	return list, nil
+}
+
+// lastUserText 提取最后一条 user 消息的文本
+func lastUserText(messages []models.Message) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role != "user" {
+			continue
+		}
+		switch v := messages[i].Content.(type) {
+		case string:
+			return v
+		case []interface{}:
+			var texts []string
+			for _, part := range v {
+				if partMap, ok := part.(map[string]interface{}); ok {
+					if text, ok := partMap["text"].(string); ok {
+						texts = append(texts, text)
+					}
+				}
+			}
+			return strings.Join(texts, "\n")
+		}
+	}
+	return ""
+}
+
+// usageFor 以空白分隔的单词数作为确定性的 token 数
+func usageFor(req *models.ChatCompletionRequest, r *reply) models.Usage {
+	prompt := 0
+	for _, msg := range req.Messages {
+		if text, ok := msg.Content.(string); ok {
+			prompt += len(strings.Fields(text))
+		}
+	}
+
+	completion := len(strings.Fields(r.content))
+	for _, tc := range r.toolCalls {
+		completion += len(strings.Fields(tc.Function.Arguments))
+	}
+
+	return models.Usage{
+		PromptTokens:     prompt,
+		CompletionTokens: completion,
+		TotalTokens:      prompt + completion,
+	}
+}
+
+// splitRunes 按字符数切分字符串
+func splitRunes(s string, size int) []string {
+	runes := []rune(s)
+	pieces := make([]string, 0, len(runes)/size+1)
+	for i := 0; i < len(runes); i += size {
+		end := i + size
+		if end > len(runes) {
+			end = len(runes)
+		}
+		pieces = append(pieces, string(runes[i:end]))
+	}
+	return pieces
+}
+
+// APIError 注入的模拟错误
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+// HTTPStatus 返回上游 HTTP 状态码，供 handler 原样返回给客户端
+func (e *APIError) HTTPStatus() int {
+	return e.StatusCode
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("Mock error (status %d): %s", e.StatusCode, e.Message)
+}
@@ -8,14 +8,18 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"openbridge/internal/config"
 	"openbridge/internal/models"
+	"openbridge/internal/provider"
+	"openbridge/internal/tokenizer"
 	"strings"
 )
 
 // Provider OpenAI 格式的提供商实现
 type Provider struct {
-	name    string
-	baseURL string
+	name      string
+	baseURL   string
+	transport http.RoundTripper // 为 nil 时使用 http.DefaultTransport
 }
 
 // New 创建新的 OpenAI Provider
@@ -28,6 +32,17 @@ func New(name, baseURL string) *Provider {
 	}
 }
 
+func init() {
+	provider.RegisterFactory(func(name string, cfg config.ProviderConfig) (provider.Provider, error) {
+		return New(name, cfg.BaseURL), nil
+	}, "openai")
+}
+
+// SetTransport 替换访问上游使用的 http.RoundTripper
+func (p *Provider) SetTransport(rt http.RoundTripper) {
+	p.transport = rt
+}
+
 func (p *Provider) Name() string {
 	return p.name
 }
@@ -40,10 +55,71 @@ func (p *Provider) SupportsStreaming() bool {
 	return true
 }
 
+// upstreamRequest 返回去掉网关扩展字段的请求副本
+// top_k、safety_settings、thinking、cache_control、回传的 reasoning_content / thinking_blocks 等
+// 只供其他 Provider 的转换使用，OpenAI 会以 "Unrecognized request argument" 拒绝；Extra 中的未知字段原样保留
+func upstreamRequest(req *models.ChatCompletionRequest) *models.ChatCompletionRequest {
+	out := *req
+	out.TopK = 0
+	out.SafetySettings = nil
+	out.Thinking = nil
+
+	out.Messages = make([]models.Message, len(req.Messages))
+	for i, msg := range req.Messages {
+		msg.CacheControl = nil
+		msg.ReasoningContent = ""
+		msg.ThinkingBlocks = nil
+		if parts, ok := msg.Content.([]interface{}); ok {
+			msg.Content = stripContentParts(parts)
+		}
+		out.Messages[i] = msg
+	}
+
+	if req.Tools != nil {
+		out.Tools = make([]models.Tool, len(req.Tools))
+		for i, tool := range req.Tools {
+			tool.CacheControl = nil
+			out.Tools[i] = tool
+		}
+	}
+	return &out
+}
+
+// stripContentParts 去掉 content 数组元素上的 cache_control 和 file.mime_type
+func stripContentParts(parts []interface{}) []interface{} {
+	stripped := make([]interface{}, len(parts))
+	for i, item := range parts {
+		part, ok := item.(map[string]interface{})
+		if !ok {
+			stripped[i] = item
+			continue
+		}
+		copied := make(map[string]interface{}, len(part))
+		for k, v := range part {
+			if k != "cache_control" {
+				copied[k] = v
+			}
+		}
+		if file, ok := part["file"].(map[string]interface{}); ok {
+			if _, has := file["mime_type"]; has {
+				fileCopy := make(map[string]interface{}, len(file))
+				for k, v := range file {
+					if k != "mime_type" {
+						fileCopy[k] = v
+					}
+				}
+				copied["file"] = fileCopy
+			}
+		}
+		stripped[i] = copied
+	}
+	return stripped
+}
+
 // ChatCompletion 发送非流式聊天请求
 func (p *Provider) ChatCompletion(req *models.ChatCompletionRequest, apiKey string) (*models.ChatCompletionResponse, error) {
-	// OpenAI 格式直接透传，不需要转换
-	reqBody, err := json.Marshal(req)
+	// OpenAI 格式直接透传，只去掉网关扩展字段
+	reqBody, err := json.Marshal(upstreamRequest(req))
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
@@ -57,7 +133,7 @@ func (p *Provider) ChatCompletion(req *models.ChatCompletionRequest, apiKey stri
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
 
-	client := &http.Client{}
+	client := &http.Client{Transport: p.transport}
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
@@ -93,8 +169,9 @@ func (p *Provider) ChatCompletionStream(req *models.ChatCompletionRequest, apiKe
 		defer close(chunkChan)
 		defer close(errChan)
 
-		req.Stream = true
-		reqBody, err := json.Marshal(req)
+		upstreamReq := upstreamRequest(req)
+		upstreamReq.Stream = true
+		reqBody, err := json.Marshal(upstreamReq)
 		if err != nil {
 			errChan <- fmt.Errorf("failed to marshal request: %w", err)
 			return
@@ -111,7 +188,7 @@ func (p *Provider) ChatCompletionStream(req *models.ChatCompletionRequest, apiKe
 		httpReq.Header.Set("Authorization", "Bearer "+apiKey)
 		httpReq.Header.Set("Accept", "text/event-stream")
 
-		client := &http.Client{}
+		client := &http.Client{Transport: p.transport}
 		resp, err := client.Do(httpReq)
 		if err != nil {
 			errChan <- fmt.Errorf("failed to send request: %w", err)
@@ -130,6 +207,8 @@ func (p *Provider) ChatCompletionStream(req *models.ChatCompletionRequest, apiKe
 
 		// 解析 SSE 流
 		scanner := bufio.NewScanner(resp.Body)
+		buf := make([]byte, 0, 64*1024)
+		scanner.Buffer(buf, 16*1024*1024) // 音频输出的 delta 可能很大
 		for scanner.Scan() {
 			line := scanner.Text()
 
@@ -174,7 +253,7 @@ func (p *Provider) ListModels(apiKey string) (*models.ModelList, error) {
 
 	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
 
-	client := &http.Client{}
+	client := &http.Client{Transport: p.transport}
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
@@ -201,12 +280,243 @@ func (p *Provider) ListModels(apiKey string) (*models.ModelList, error) {
 	return &result, nil
 }
 
+// Completion 发送旧版文本补全请求，直接透传
+func (p *Provider) Completion(req *models.CompletionRequest, apiKey string) (*models.CompletionResponse, error) {
+	reqBody, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := p.baseURL + "/completions"
+	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+
+	client := &http.Client{Transport: p.transport}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &APIError{
+			StatusCode: resp.StatusCode,
+			Message:    string(body),
+		}
+	}
+
+	var result models.CompletionResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return &result, nil
+}
+
+// CompletionStream 发送流式旧版文本补全请求
+func (p *Provider) CompletionStream(req *models.CompletionRequest, apiKey string) (<-chan *models.CompletionResponse, <-chan error) {
+	chunkChan := make(chan *models.CompletionResponse, 100)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(chunkChan)
+		defer close(errChan)
+
+		req.Stream = true
+		reqBody, err := json.Marshal(req)
+		if err != nil {
+			errChan <- fmt.Errorf("failed to marshal request: %w", err)
+			return
+		}
+
+		url := p.baseURL + "/completions"
+		httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(reqBody))
+		if err != nil {
+			errChan <- fmt.Errorf("failed to create request: %w", err)
+			return
+		}
+
+		httpReq.Header.Set("Content-Type", "application/json")
+		httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+		httpReq.Header.Set("Accept", "text/event-stream")
+
+		client := &http.Client{Transport: p.transport}
+		resp, err := client.Do(httpReq)
+		if err != nil {
+			errChan <- fmt.Errorf("failed to send request: %w", err)
+			return
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			errChan <- &APIError{
+				StatusCode: resp.StatusCode,
+				Message:    string(body),
+			}
+			return
+		}
+
+		// 解析 SSE 流
+		scanner := bufio.NewScanner(resp.Body)
+		buf := make([]byte, 0, 64*1024)
+		scanner.Buffer(buf, 1024*1024)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if !strings.HasPrefix(line, "data: ") {
+				continue
+			}
+
+			data := strings.TrimPrefix(line, "data: ")
+			if data == "[DONE]" {
+				return
+			}
+
+			var chunk models.CompletionResponse
+			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+				log.Printf("Failed to parse chunk: %v", err)
+				continue
+			}
+
+			chunkChan <- &chunk
+		}
+
+		if err := scanner.Err(); err != nil {
+			errChan <- fmt.Errorf("stream read error: %w", err)
+		}
+	}()
+
+	return chunkChan, errChan
+}
+
+// GenerateImages 生成图片，直接透传（response_format 由上游处理）
+func (p *Provider) GenerateImages(req *models.ImageGenerationRequest, apiKey string) (*models.ImageResponse, error) {
+	reqBody, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := p.baseURL + "/images/generations"
+	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+
+	client := &http.Client{Transport: p.transport}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &APIError{
+			StatusCode: resp.StatusCode,
+			Message:    string(body),
+		}
+	}
+
+	var result models.ImageResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return &result, nil
+}
+
+// CountTokens 使用本地 BPE 分词器计算输入 token 数（OpenAI 没有计数接口）
+// 未加载对应词表或不是已知的 OpenAI 模型时结果为估算
+func (p *Provider) CountTokens(req *models.ChatCompletionRequest, apiKey string) (*models.TokenCountResponse, error) {
+	count, estimated := tokenizer.Default().CountChat(req)
+	return &models.TokenCountResponse{
+		InputTokens: count,
+		Estimated:   estimated,
+	}, nil
+}
+
+// maxEmbeddingInputs OpenAI /embeddings 单次请求最多 2048 个输入
+const maxEmbeddingInputs = 2048
+
+func (p *Provider) MaxEmbeddingInputs() int {
+	return maxEmbeddingInputs
+}
+
+// Embeddings 生成向量，直接透传
+func (p *Provider) Embeddings(req *models.EmbeddingRequest, apiKey string) (*models.EmbeddingResponse, error) {
+	// task_type 是 Gemini 专用的非标准字段，OpenAI 会拒绝未知参数
+	upstreamReq := *req
+	upstreamReq.TaskType = ""
+
+	reqBody, err := json.Marshal(upstreamReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := p.baseURL + "/embeddings"
+	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+
+	client := &http.Client{Transport: p.transport}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &APIError{
+			StatusCode: resp.StatusCode,
+			Message:    string(body),
+		}
+	}
+
+	var result models.EmbeddingResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return &result, nil
+}
+
 // APIError API 错误
 type APIError struct {
 	StatusCode int
 	Message    string
 }
 
+// HTTPStatus 返回上游 HTTP 状态码，供 handler 原样返回给客户端
+func (e *APIError) HTTPStatus() int {
+	return e.StatusCode
+}
+
 func (e *APIError) Error() string {
 	return fmt.Sprintf("API error (status %d): %s", e.StatusCode, e.Message)
 }
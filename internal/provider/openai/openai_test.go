@@ -0,0 +1,72 @@
+package openai
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"openbridge/internal/models"
+	"strings"
+	"testing"
+)
+
+const chatResponse = `{"id":"chatcmpl-1","object":"chat.completion","created":1,"model":"gpt-4o",
+	"choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}],
+	"usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}`
+
+// 网关扩展字段不发送给 OpenAI，未知字段原样透传
+func TestChatCompletionStripsGatewayFields(t *testing.T) {
+	var received map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+		io.WriteString(w, chatResponse)
+	}))
+	defer server.Close()
+
+	var req models.ChatCompletionRequest
+	err := json.Unmarshal([]byte(`{
+		"model": "gpt-4o",
+		"top_k": 40,
+		"safety_settings": [{"category": "HARM_CATEGORY_HARASSMENT", "threshold": "BLOCK_NONE"}],
+		"thinking": {"type": "enabled", "budget_tokens": 1024},
+		"prompt_cache_key": "conversation-1",
+		"messages": [
+			{"role": "system", "content": "be brief", "cache_control": {"type": "ephemeral"}},
+			{"role": "user", "content": [
+				{"type": "text", "text": "hi", "cache_control": {"type": "ephemeral"}},
+				{"type": "file", "file": {"file_data": "data:application/pdf;base64,AA==", "mime_type": "application/pdf"}}
+			]},
+			{"role": "assistant", "content": "hello", "reasoning_content": "thinking...",
+			 "thinking_blocks": [{"type": "thinking", "thinking": "thinking...", "signature": "sig"}]},
+			{"role": "user", "content": "again"}
+		],
+		"tools": [{"type": "function", "function": {"name": "f", "parameters": {"type": "object"}}, "cache_control": {"type": "ephemeral"}}]
+	}`), &req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := New("openai", server.URL)
+	if _, err := p.ChatCompletion(&req, "key"); err != nil {
+		t.Fatalf("ChatCompletion: %v", err)
+	}
+
+	data, _ := json.Marshal(received)
+	for _, field := range []string{"top_k", "safety_settings", "thinking", "cache_control", "reasoning_content", "thinking_blocks", "mime_type"} {
+		if strings.Contains(string(data), `"`+field+`"`) {
+			t.Errorf("upstream request contains %q: %s", field, data)
+		}
+	}
+	if received["prompt_cache_key"] != "conversation-1" {
+		t.Errorf("prompt_cache_key = %v, want the unknown field passed through", received["prompt_cache_key"])
+	}
+	if !strings.Contains(string(data), `"file_data"`) || !strings.Contains(string(data), `"text":"hi"`) {
+		t.Errorf("upstream request lost content: %s", data)
+	}
+
+	// 原请求不被修改（其他逻辑如 token 计数仍可能使用）
+	if req.TopK != 40 || req.Messages[0].CacheControl == nil || req.Tools[0].CacheControl == nil {
+		t.Error("original request was modified")
+	}
+}
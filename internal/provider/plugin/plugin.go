@@ -0,0 +1,298 @@
+package plugin
+
+import (
+	"fmt"
+	"net/http"
+	"openbridge/internal/config"
+	"openbridge/internal/models"
+	"openbridge/internal/provider"
+	"os/exec"
+	"sync/atomic"
+	"time"
+)
+
+// Options 插件 Provider 专属选项
+type Options struct {
+	Command           string            `yaml:"command"` // 插件可执行文件
+	Args              []string          `yaml:"args"`
+	Env               map[string]string `yaml:"env"`
+	Dir               string            `yaml:"dir"`
+	RequestTimeout    time.Duration     `yaml:"request_timeout"`     // 单次调用超时，默认 120s
+	RestartBackoff    time.Duration     `yaml:"restart_backoff"`     // 首次重启等待时间，默认 1s
+	MaxRestartBackoff time.Duration     `yaml:"max_restart_backoff"` // 最大重启等待时间，默认 30s
+}
+
+// Provider 通过 stdio JSON-RPC 与外部插件进程通信的提供商实现
+type Provider struct {
+	name      string
+	proc      *process
+	timeout   time.Duration
+	streaming atomic.Bool
+}
+
+// New 创建插件 Provider 并启动插件进程
+func New(name string, opts Options) (*Provider, error) {
+	if opts.Command == "" {
+		return nil, fmt.Errorf("plugin provider requires options.command")
+	}
+	if _, err := exec.LookPath(opts.Command); err != nil {
+		return nil, fmt.Errorf("plugin command not found: %w", err)
+	}
+
+	if opts.RequestTimeout <= 0 {
+		opts.RequestTimeout = 120 * time.Second
+	}
+	if opts.RestartBackoff <= 0 {
+		opts.RestartBackoff = time.Second
+	}
+	if opts.MaxRestartBackoff < opts.RestartBackoff {
+		opts.MaxRestartBackoff = 30 * time.Second
+	}
+
+	p := &Provider{
+		name:    name,
+		proc:    newProcess(name, opts),
+		timeout: opts.RequestTimeout,
+	}
+	p.streaming.Store(true)
+
+	go p.proc.supervise(p.initialize)
+
+	return p, nil
+}
+
+func init() {
+	provider.RegisterFactory(func(name string, cfg config.ProviderConfig) (provider.Provider, error) {
+		var opts Options
+		if err := cfg.DecodeOptions(&opts); err != nil {
+			return nil, err
+		}
+		return New(name, opts)
+	}, "plugin")
+}
+
+// initialize 每次插件（重新）启动后协商能力，插件可以不实现该方法
+func (p *Provider) initialize() {
+	var result InitializeResult
+	err := p.proc.invoke(MethodInitialize, InitializeParams{Provider: p.name}, &result, p.timeout)
+	if err != nil {
+		return
+	}
+	if result.Streaming != nil {
+		p.streaming.Store(*result.Streaming)
+	}
+}
+
+func (p *Provider) Name() string {
+	return p.name
+}
+
+func (p *Provider) Type() string {
+	return "plugin"
+}
+
+func (p *Provider) SupportsStreaming() bool {
+	return p.streaming.Load()
+}
+
+// ChatCompletion 发送非流式聊天请求
+func (p *Provider) ChatCompletion(req *models.ChatCompletionRequest, apiKey string) (*models.ChatCompletionResponse, error) {
+	var resp models.ChatCompletionResponse
+	if err := p.proc.invoke(MethodChatCompletion, ChatParams{Request: req, APIKey: apiKey}, &resp, p.timeout); err != nil {
+		return nil, err
+	}
+
+	if resp.Object == "" {
+		resp.Object = "chat.completion"
+	}
+	if resp.Created == 0 {
+		resp.Created = time.Now().Unix()
+	}
+	return &resp, nil
+}
+
+// ChatCompletionStream 发送流式聊天请求
+func (p *Provider) ChatCompletionStream(req *models.ChatCompletionRequest, apiKey string) (<-chan *models.ChatCompletionChunk, <-chan error) {
+	chunkChan := make(chan *models.ChatCompletionChunk, 100)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(chunkChan)
+		defer close(errChan)
+
+		// 插件声明不支持流式时，发送非流式请求并将完整响应拆成 chunk
+		if !p.streaming.Load() {
+			resp, err := p.ChatCompletion(req, apiKey)
+			if err != nil {
+				errChan <- err
+				return
+			}
+			for _, chunk := range responseChunks(resp, req.IncludeUsage()) {
+				chunkChan <- chunk
+			}
+			return
+		}
+
+		streamReq := *req
+		streamReq.Stream = true
+
+		id, c, err := p.proc.start(MethodChatCompletionStream, ChatParams{Request: &streamReq, APIKey: apiKey}, true, p.timeout)
+		if err != nil {
+			errChan <- err
+			return
+		}
+
+		// 两次 chunk 之间超过 timeout 视为插件失去响应
+		timer := time.NewTimer(p.timeout)
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-c.chunks.notify:
+				for _, params := range c.chunks.take() {
+					if chunk := streamChunk(params); chunk != nil {
+						chunkChan <- chunk
+					}
+				}
+
+				if !timer.Stop() {
+					<-timer.C
+				}
+				timer.Reset(p.timeout)
+
+			case msg := <-c.done:
+				// 先把已到达的 chunk 发完（readLoop 按顺序处理，响应之前的 chunk 都已入队）
+				for _, params := range c.chunks.take() {
+					if chunk := streamChunk(params); chunk != nil {
+						chunkChan <- chunk
+					}
+				}
+				if msg.Error != nil {
+					errChan <- newAPIError(msg.Error)
+				}
+				return
+
+			case <-timer.C:
+				p.proc.cancel(id)
+				errChan <- fmt.Errorf("plugin %s stream timed out after %s", p.name, p.timeout)
+				return
+			}
+		}
+	}()
+
+	return chunkChan, errChan
+}
+
+// streamChunk 补全插件省略的 chunk 字段，没有 chunk 时返回 nil
+func streamChunk(params *StreamChunkParams) *models.ChatCompletionChunk {
+	if params.Chunk == nil {
+		return nil
+	}
+	if params.Chunk.Object == "" {
+		params.Chunk.Object = "chat.completion.chunk"
+	}
+	if params.Chunk.Created == 0 {
+		params.Chunk.Created = time.Now().Unix()
+	}
+	return params.Chunk
+}
+
+// responseChunks 将非流式响应转换为流式 chunk：每个 choice 的完整内容、finish_reason，includeUsage 时最后附加 usage
+func responseChunks(resp *models.ChatCompletionResponse, includeUsage bool) []*models.ChatCompletionChunk {
+	newChunk := func(choices []models.ChunkChoice) *models.ChatCompletionChunk {
+		return &models.ChatCompletionChunk{
+			ID:      resp.ID,
+			Object:  "chat.completion.chunk",
+			Created: resp.Created,
+			Model:   resp.Model,
+			Choices: choices,
+		}
+	}
+
+	var content, finish []models.ChunkChoice
+	for _, choice := range resp.Choices {
+		msg := choice.Message
+		toolCalls := make([]models.ToolCall, len(msg.ToolCalls))
+		for i, call := range msg.ToolCalls {
+			index := i
+			call.Index = &index
+			toolCalls[i] = call
+		}
+		content = append(content, models.ChunkChoice{
+			Index: choice.Index,
+			Delta: models.ChunkDelta{
+				Role:             "assistant",
+				Content:          msg.Content,
+				ToolCalls:        toolCalls,
+				ReasoningContent: msg.ReasoningContent,
+				ThinkingBlocks:   msg.ThinkingBlocks,
+				Audio:            msg.Audio,
+				Annotations:      msg.Annotations,
+				SearchQueries:    msg.SearchQueries,
+			},
+			Logprobs: choice.Logprobs,
+		})
+
+		finishReason := choice.FinishReason
+		finish = append(finish, models.ChunkChoice{
+			Index:        choice.Index,
+			FinishReason: &finishReason,
+		})
+	}
+
+	chunks := []*models.ChatCompletionChunk{newChunk(content), newChunk(finish)}
+	if includeUsage {
+		usage := resp.Usage
+		chunk := newChunk([]models.ChunkChoice{})
+		chunk.Usage = &usage
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// ListModels 获取模型列表
+func (p *Provider) ListModels(apiKey string) (*models.ModelList, error) {
+	var list models.ModelList
+	if err := p.proc.invoke(MethodListModels, ListModelsParams{APIKey: apiKey}, &list, p.timeout); err != nil {
+		return nil, err
+	}
+
+	list.Object = "list"
+	for i := range list.Data {
+		if list.Data[i].Object == "" {
+			list.Data[i].Object = "model"
+		}
+		if list.Data[i].OwnedBy == "" {
+			list.Data[i].OwnedBy = p.name
+		}
+	}
+	return &list, nil
+}
+
+// APIError 插件返回的错误
+type APIError struct {
+	StatusCode int
+	Code       int
+	Message    string
+}
+
+func newAPIError(rpcErr *RPCError) *APIError {
+	status := http.StatusBadGateway
+	if rpcErr.Data != nil && rpcErr.Data.Status != 0 {
+		status = rpcErr.Data.Status
+	}
+	return &APIError{
+		StatusCode: status,
+		Code:       rpcErr.Code,
+		Message:    rpcErr.Message,
+	}
+}
+
+// HTTPStatus 返回上游 HTTP 状态码，供 handler 原样返回给客户端
+func (e *APIError) HTTPStatus() int {
+	return e.StatusCode
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("Plugin error (status %d, code %d): %s", e.StatusCode, e.Code, e.Message)
+}
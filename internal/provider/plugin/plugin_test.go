@@ -0,0 +1,302 @@
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"openbridge/internal/models"
+)
+
+// 测试通过 os.Args[0] 重新启动测试二进制作为插件进程，环境变量 helperEnv 指定插件的行为：
+//
+//	stream   支持流式，chat_completion_stream 按空格拆分最后一条消息逐词发送
+//	nostream initialize 返回 streaming:false，收到 chat_completion_stream 时报错
+//
+// 插件单线程处理请求；最后一条消息为 "crash" 时退出，为 "hang" 时不响应，
+// 为 "late" 时在 300ms 后才响应（调用方此时已超时）
+const helperEnv = "OPENBRIDGE_TEST_PLUGIN"
+
+func TestMain(m *testing.M) {
+	if mode := os.Getenv(helperEnv); mode != "" {
+		runHelper(mode)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func runHelper(mode string) {
+	var mu sync.Mutex
+	out := bufio.NewWriter(os.Stdout)
+	write := func(v interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		data, _ := json.Marshal(v)
+		out.Write(append(data, '\n'))
+		out.Flush()
+	}
+	respond := func(id int64, result interface{}) {
+		write(map[string]interface{}{"jsonrpc": jsonRPCVersion, "id": id, "result": result})
+	}
+	fail := func(id int64, message string) {
+		write(map[string]interface{}{"jsonrpc": jsonRPCVersion, "id": id, "error": RPCError{Code: -32000, Message: message}})
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var req struct {
+			ID     int64           `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			continue
+		}
+		var params struct {
+			Request *models.ChatCompletionRequest `json:"request"`
+		}
+		json.Unmarshal(req.Params, &params)
+		text := ""
+		if params.Request != nil && len(params.Request.Messages) > 0 {
+			text, _ = params.Request.Messages[len(params.Request.Messages)-1].Content.(string)
+		}
+
+		switch text {
+		case "crash":
+			os.Exit(1)
+		case "hang":
+			continue
+		case "late":
+			go func(id int64) {
+				time.Sleep(300 * time.Millisecond)
+				write(map[string]interface{}{"jsonrpc": jsonRPCVersion, "method": MethodStreamChunk,
+					"params": StreamChunkParams{ID: id, Chunk: &models.ChatCompletionChunk{}}})
+				respond(id, nil)
+			}(req.ID)
+			continue
+		}
+
+		switch req.Method {
+		case MethodInitialize:
+			respond(req.ID, InitializeResult{Streaming: boolPtr(mode != "nostream")})
+		case MethodChatCompletion:
+			respond(req.ID, models.ChatCompletionResponse{
+				ID:    "chatcmpl-test",
+				Model: params.Request.Model,
+				Choices: []models.Choice{{
+					Message:      models.ResponseMessage{Role: "assistant", Content: text},
+					FinishReason: "stop",
+				}},
+				Usage: models.Usage{PromptTokens: 1, CompletionTokens: 2, TotalTokens: 3},
+			})
+		case MethodChatCompletionStream:
+			if mode == "nostream" {
+				fail(req.ID, "streaming not supported")
+				continue
+			}
+			for _, word := range strings.Fields(text) {
+				write(map[string]interface{}{"jsonrpc": jsonRPCVersion, "method": MethodStreamChunk,
+					"params": StreamChunkParams{ID: req.ID, Chunk: &models.ChatCompletionChunk{
+						Choices: []models.ChunkChoice{{Delta: models.ChunkDelta{Content: word}}},
+					}}})
+			}
+			respond(req.ID, nil)
+		default:
+			write(map[string]interface{}{"jsonrpc": jsonRPCVersion, "id": req.ID,
+				"error": RPCError{Code: ErrCodeMethodNotFound, Message: "method not found"}})
+		}
+	}
+}
+
+func boolPtr(b bool) *bool { return &b }
+
+func newTestPlugin(t *testing.T, mode string, timeout time.Duration) *Provider {
+	t.Helper()
+	p, err := New("test", Options{
+		Command:           os.Args[0],
+		Env:               map[string]string{helperEnv: mode},
+		RequestTimeout:    timeout,
+		RestartBackoff:    10 * time.Millisecond,
+		MaxRestartBackoff: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 等待 initialize 完成
+	deadline := time.Now().Add(5 * time.Second)
+	for p.SupportsStreaming() != (mode != "nostream") {
+		if time.Now().After(deadline) {
+			t.Fatal("plugin did not initialize")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return p
+}
+
+func chatRequest(content string) *models.ChatCompletionRequest {
+	return &models.ChatCompletionRequest{
+		Model:    "plugin-model",
+		Messages: []models.Message{{Role: "user", Content: content}},
+	}
+}
+
+// collect 读取整个流，返回所有 chunk 和错误
+func collect(chunks <-chan *models.ChatCompletionChunk, errs <-chan error) ([]*models.ChatCompletionChunk, error) {
+	var all []*models.ChatCompletionChunk
+	for chunk := range chunks {
+		all = append(all, chunk)
+	}
+	return all, <-errs
+}
+
+func streamContent(chunks []*models.ChatCompletionChunk) string {
+	var b strings.Builder
+	for _, chunk := range chunks {
+		for _, choice := range chunk.Choices {
+			b.WriteString(choice.Delta.Content)
+		}
+	}
+	return b.String()
+}
+
+func TestPluginChatCompletionAndStream(t *testing.T) {
+	p := newTestPlugin(t, "stream", 5*time.Second)
+
+	resp, err := p.ChatCompletion(chatRequest("hello"), "key")
+	if err != nil {
+		t.Fatalf("ChatCompletion: %v", err)
+	}
+	if resp.Choices[0].Message.Content != "hello" || resp.Object != "chat.completion" || resp.Created == 0 {
+		t.Errorf("response = %+v", resp)
+	}
+
+	chunks, err := collect(p.ChatCompletionStream(chatRequest("a b c"), "key"))
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	if got := streamContent(chunks); got != "abc" {
+		t.Errorf("stream content = %q, want abc", got)
+	}
+	for _, chunk := range chunks {
+		if chunk.Object != "chat.completion.chunk" || chunk.Created == 0 {
+			t.Errorf("chunk defaults missing: %+v", chunk)
+		}
+	}
+}
+
+func TestPluginNonStreamingFallback(t *testing.T) {
+	p := newTestPlugin(t, "nostream", 5*time.Second)
+
+	req := chatRequest("hello")
+	req.Stream = true
+	req.StreamOptions = &models.StreamOptions{IncludeUsage: true}
+	chunks, err := collect(p.ChatCompletionStream(req, "key"))
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	if len(chunks) != 3 {
+		t.Fatalf("got %d chunks, want content, finish_reason and usage", len(chunks))
+	}
+	if delta := chunks[0].Choices[0].Delta; delta.Role != "assistant" || delta.Content != "hello" {
+		t.Errorf("first delta = %+v", delta)
+	}
+	if fr := chunks[1].Choices[0].FinishReason; fr == nil || *fr != "stop" {
+		t.Errorf("finish_reason = %v, want stop", fr)
+	}
+	if usage := chunks[2].Usage; usage == nil || usage.TotalTokens != 3 || len(chunks[2].Choices) != 0 {
+		t.Errorf("usage chunk = %+v", chunks[2])
+	}
+}
+
+// 插件阻塞在写 stdout（大量流式输出）时，向 stdin 写入超过管道缓冲区的请求不能导致死锁
+func TestPluginLargeRequestDuringStream(t *testing.T) {
+	p := newTestPlugin(t, "stream", 10*time.Second)
+
+	words := make([]string, 4000)
+	for i := range words {
+		words[i] = strings.Repeat("x", 256)
+	}
+	large := strings.Repeat("y", 1<<20)
+
+	done := make(chan error, 2)
+	go func() {
+		chunks, err := collect(p.ChatCompletionStream(chatRequest(strings.Join(words, " ")), "key"))
+		if err == nil && len(streamContent(chunks)) != 4000*256 {
+			err = errors.New("stream content truncated")
+		}
+		done <- err
+	}()
+	go func() {
+		resp, err := p.ChatCompletion(chatRequest(large), "key")
+		if err == nil && resp.Choices[0].Message.Content != large {
+			err = errors.New("large response mismatch")
+		}
+		done <- err
+	}()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(15 * time.Second):
+			t.Fatal("deadlock: calls did not complete")
+		}
+	}
+}
+
+func TestPluginRestartsAfterCrash(t *testing.T) {
+	p := newTestPlugin(t, "stream", 5*time.Second)
+
+	if _, err := p.ChatCompletion(chatRequest("crash"), "key"); err == nil || !strings.Contains(err.Error(), errPluginExited.Error()) {
+		t.Fatalf("err = %v, want %v", err, errPluginExited)
+	}
+
+	// 重启后恢复可用
+	resp, err := p.ChatCompletion(chatRequest("back"), "key")
+	if err != nil {
+		t.Fatalf("ChatCompletion after restart: %v", err)
+	}
+	if resp.Choices[0].Message.Content != "back" {
+		t.Errorf("content = %q, want back", resp.Choices[0].Message.Content)
+	}
+}
+
+func TestPluginTimeoutAndCancel(t *testing.T) {
+	p := newTestPlugin(t, "stream", 200*time.Millisecond)
+
+	if _, err := p.ChatCompletion(chatRequest("hang"), "key"); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("ChatCompletion err = %v, want timeout", err)
+	}
+	if _, err := collect(p.ChatCompletionStream(chatRequest("hang"), "key")); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("stream err = %v, want timeout", err)
+	}
+
+	// 已取消调用的迟到 chunk 和响应被丢弃，不影响后续调用
+	if _, err := collect(p.ChatCompletionStream(chatRequest("late"), "key")); err == nil {
+		t.Error("late stream: want timeout")
+	}
+	time.Sleep(400 * time.Millisecond)
+
+	p.proc.mu.Lock()
+	pending := len(p.proc.pending)
+	p.proc.mu.Unlock()
+	if pending != 0 {
+		t.Errorf("%d calls still pending after timeout", pending)
+	}
+
+	resp, err := p.ChatCompletion(chatRequest("ok"), "key")
+	if err != nil {
+		t.Fatalf("ChatCompletion after cancel: %v", err)
+	}
+	if resp.Choices[0].Message.Content != "ok" {
+		t.Errorf("content = %q, want ok", resp.Choices[0].Message.Content)
+	}
+}
@@ -0,0 +1,305 @@
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// errPluginExited 插件进程退出时，所有未完成的调用都会收到该错误
+var errPluginExited = errors.New("plugin process exited")
+
+// call 一个进行中的 JSON-RPC 调用
+type call struct {
+	done   chan *Message // 最终响应
+	chunks *chunkQueue   // 流式通知，仅流式调用使用
+}
+
+// chunkQueue 流式调用的 chunk 队列，不限长度
+// readLoop 写入时从不阻塞，消费慢的调用（如慢速 SSE 客户端）不会影响同一插件的其他调用
+type chunkQueue struct {
+	mu     sync.Mutex
+	chunks []*StreamChunkParams
+	notify chan struct{} // 有新 chunk 时发送信号，容量为 1
+}
+
+func newChunkQueue() *chunkQueue {
+	return &chunkQueue{notify: make(chan struct{}, 1)}
+}
+
+func (q *chunkQueue) push(chunk *StreamChunkParams) {
+	q.mu.Lock()
+	q.chunks = append(q.chunks, chunk)
+	q.mu.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// take 取出所有已到达的 chunk
+func (q *chunkQueue) take() []*StreamChunkParams {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	chunks := q.chunks
+	q.chunks = nil
+	return chunks
+}
+
+// process 监管插件子进程：启动、转发 stdio、崩溃后按退避策略重启
+type process struct {
+	name    string
+	command string
+	args    []string
+	env     []string
+	dir     string
+
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	nextID int64
+
+	mu      sync.Mutex
+	stdin   io.WriteCloser
+	pending map[int64]*call
+	ready   chan struct{} // 进程可用时关闭，重启时替换
+
+	// writeMu 串行化对 stdin 的写入；写入可能因管道已满而阻塞，不能持有 mu，
+	// 否则插件阻塞在写 stdout 时 readLoop 拿不到 mu，三方互相等待
+	writeMu sync.Mutex
+}
+
+func newProcess(name string, opts Options) *process {
+	env := os.Environ()
+	for k, v := range opts.Env {
+		env = append(env, k+"="+v)
+	}
+
+	return &process{
+		name:       name,
+		command:    opts.Command,
+		args:       opts.Args,
+		env:        env,
+		dir:        opts.Dir,
+		minBackoff: opts.RestartBackoff,
+		maxBackoff: opts.MaxRestartBackoff,
+		pending:    make(map[int64]*call),
+		ready:      make(chan struct{}),
+	}
+}
+
+// supervise 启动插件并在其退出后自动重启，永不返回
+func (p *process) supervise(onStart func()) {
+	backoff := p.minBackoff
+	for {
+		startedAt := time.Now()
+		err := p.run(onStart)
+
+		// 稳定运行一段时间后重置退避时间
+		if time.Since(startedAt) > p.maxBackoff {
+			backoff = p.minBackoff
+		}
+
+		log.Printf("⚠️ Plugin %s exited: %v, restarting in %s", p.name, err, backoff)
+		time.Sleep(backoff)
+
+		backoff *= 2
+		if backoff > p.maxBackoff {
+			backoff = p.maxBackoff
+		}
+	}
+}
+
+// run 启动一次插件进程并阻塞直到其退出
+func (p *process) run(onStart func()) error {
+	cmd := exec.Command(p.command, p.args...)
+	cmd.Env = p.env
+	cmd.Dir = p.dir
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	log.Printf("🔌 Plugin %s started (pid %d)", p.name, cmd.Process.Pid)
+
+	p.mu.Lock()
+	p.stdin = stdin
+	close(p.ready)
+	p.mu.Unlock()
+
+	go p.forwardStderr(stderr)
+	if onStart != nil {
+		go onStart()
+	}
+
+	p.readLoop(stdout)
+	waitErr := cmd.Wait()
+
+	// 进程已退出：标记不可用并让所有未完成的调用失败
+	p.mu.Lock()
+	p.stdin = nil
+	p.ready = make(chan struct{})
+	pending := p.pending
+	p.pending = make(map[int64]*call)
+	p.mu.Unlock()
+
+	for _, c := range pending {
+		c.done <- &Message{Error: &RPCError{Message: errPluginExited.Error()}}
+	}
+
+	if waitErr == nil {
+		waitErr = errPluginExited
+	}
+	return waitErr
+}
+
+// readLoop 逐行读取插件输出并分发到对应调用
+func (p *process) readLoop(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	buf := make([]byte, 0, 64*1024)
+	scanner.Buffer(buf, 16*1024*1024)
+
+	for scanner.Scan() {
+		var msg Message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			log.Printf("Failed to parse plugin %s message: %v", p.name, err)
+			continue
+		}
+
+		// 通知
+		if msg.ID == nil {
+			if msg.Method == MethodStreamChunk {
+				var params StreamChunkParams
+				if err := json.Unmarshal(msg.Params, &params); err != nil {
+					log.Printf("Failed to parse plugin %s stream chunk: %v", p.name, err)
+					continue
+				}
+				// 持锁检查调用是否仍在进行，已取消（如超时）的调用的 chunk 直接丢弃
+				p.mu.Lock()
+				if c := p.pending[params.ID]; c != nil && c.chunks != nil {
+					c.chunks.push(&params)
+				}
+				p.mu.Unlock()
+			}
+			continue
+		}
+
+		// 响应
+		p.mu.Lock()
+		c := p.pending[*msg.ID]
+		delete(p.pending, *msg.ID)
+		p.mu.Unlock()
+		if c != nil {
+			m := msg
+			c.done <- &m
+		}
+	}
+}
+
+func (p *process) forwardStderr(stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		log.Printf("[plugin %s] %s", p.name, scanner.Text())
+	}
+}
+
+// start 发起一次调用，等待插件可用后写入请求
+func (p *process) start(method string, params interface{}, stream bool, timeout time.Duration) (int64, *call, error) {
+	id := atomic.AddInt64(&p.nextID, 1)
+	c := &call{done: make(chan *Message, 1)}
+	if stream {
+		c.chunks = newChunkQueue()
+	}
+
+	data, err := json.Marshal(Request{
+		JSONRPC: jsonRPCVersion,
+		ID:      id,
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	data = append(data, '\n')
+
+	// 等待插件进程就绪（可能正在重启）
+	p.mu.Lock()
+	ready := p.ready
+	p.mu.Unlock()
+	select {
+	case <-ready:
+	case <-time.After(timeout):
+		return 0, nil, fmt.Errorf("plugin %s is not running", p.name)
+	}
+
+	p.mu.Lock()
+	stdin := p.stdin
+	if stdin == nil {
+		p.mu.Unlock()
+		return 0, nil, errPluginExited
+	}
+	p.pending[id] = c
+	p.mu.Unlock()
+
+	p.writeMu.Lock()
+	_, err = stdin.Write(data)
+	p.writeMu.Unlock()
+	if err != nil {
+		p.cancel(id)
+		return 0, nil, fmt.Errorf("failed to write to plugin: %w", err)
+	}
+
+	return id, c, nil
+}
+
+// cancel 放弃一个调用（如超时），之后到达的响应会被丢弃
+func (p *process) cancel(id int64) {
+	p.mu.Lock()
+	delete(p.pending, id)
+	p.mu.Unlock()
+}
+
+// invoke 发起调用并等待结果
+func (p *process) invoke(method string, params interface{}, result interface{}, timeout time.Duration) error {
+	id, c, err := p.start(method, params, false, timeout)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case msg := <-c.done:
+		if msg.Error != nil {
+			return newAPIError(msg.Error)
+		}
+		if result != nil && len(msg.Result) > 0 {
+			if err := json.Unmarshal(msg.Result, result); err != nil {
+				return fmt.Errorf("failed to parse plugin result: %w", err)
+			}
+		}
+		return nil
+	case <-time.After(timeout):
+		p.cancel(id)
+		return fmt.Errorf("plugin %s timed out after %s", p.name, timeout)
+	}
+}
@@ -0,0 +1,94 @@
+package plugin
+
+import (
+	"encoding/json"
+	"openbridge/internal/models"
+)
+
+// 插件协议: 基于 stdio 的 JSON-RPC 2.0
+//
+// OpenBridge 启动插件进程后，通过 stdin 逐行写入请求，插件通过 stdout 逐行写回
+// 响应或通知（每行一个 JSON 对象），stderr 会被转发到 OpenBridge 日志。
+//
+// 方法:
+//   initialize             {provider}             -> {streaming}（false 时流式请求改用 chat_completion）
+//   chat_completion        {request, api_key}     -> ChatCompletionResponse
+//   chat_completion_stream {request, api_key}     -> null（流结束），期间发送 stream_chunk 通知
+//   list_models            {api_key}              -> ModelList
+//
+// 通知（插件 -> OpenBridge）:
+//   stream_chunk           {id, chunk}            id 为对应 chat_completion_stream 请求的 id
+//
+// 错误使用标准 JSON-RPC error 对象，data.status 可携带上游 HTTP 状态码。
+
+const jsonRPCVersion = "2.0"
+
+// 方法名
+const (
+	MethodInitialize           = "initialize"
+	MethodChatCompletion       = "chat_completion"
+	MethodChatCompletionStream = "chat_completion_stream"
+	MethodListModels           = "list_models"
+	MethodStreamChunk          = "stream_chunk"
+)
+
+// JSON-RPC 标准错误码
+const (
+	ErrCodeMethodNotFound = -32601
+)
+
+// Request JSON-RPC 请求
+type Request struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      int64       `json:"id"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// Message 插件写回的消息，可能是响应（带 id）或通知（带 method）
+type Message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// RPCError JSON-RPC 错误对象
+type RPCError struct {
+	Code    int           `json:"code"`
+	Message string        `json:"message"`
+	Data    *RPCErrorData `json:"data,omitempty"`
+}
+
+type RPCErrorData struct {
+	Status int `json:"status,omitempty"` // 对应的 HTTP 状态码
+}
+
+// InitializeParams initialize 参数
+type InitializeParams struct {
+	Provider string `json:"provider"`
+}
+
+// InitializeResult initialize 结果
+type InitializeResult struct {
+	Streaming *bool `json:"streaming,omitempty"`
+}
+
+// ChatParams chat_completion / chat_completion_stream 参数
+type ChatParams struct {
+	Request *models.ChatCompletionRequest `json:"request"`
+	APIKey  string                        `json:"api_key"`
+}
+
+// ListModelsParams list_models 参数
+type ListModelsParams struct {
+	APIKey string `json:"api_key"`
+}
+
+// StreamChunkParams stream_chunk 通知参数
+type StreamChunkParams struct {
+	ID    int64                       `json:"id"`
+	Chunk *models.ChatCompletionChunk `json:"chunk"`
+}
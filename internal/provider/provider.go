@@ -1,6 +1,7 @@
 package provider
 
 import (
+	"net/http"
 	"openbridge/internal/models"
 )
 
@@ -25,11 +26,36 @@ type Provider interface {
 	SupportsStreaming() bool
 }
 
-// ProviderConfig 提供商配置
-type ProviderConfig struct {
-	Name     string   `yaml:"name"`
-	Type     string   `yaml:"type"`     // openai, anthropic, google
-	BaseURL  string   `yaml:"base_url"`
-	APIKeys  []string `yaml:"api_keys"`
-	Models   []string `yaml:"models"`   // 该提供商支持的模型列表
+// TransportSetter 可选接口：允许替换 Provider 访问上游时使用的 http.RoundTripper
+// 用于录制/回放上游流量
+type TransportSetter interface {
+	SetTransport(rt http.RoundTripper)
+}
+
+// EmbeddingProvider 可选接口：支持 /v1/embeddings 的 Provider
+type EmbeddingProvider interface {
+	// Embeddings 生成向量，input 数量不超过 MaxEmbeddingInputs
+	Embeddings(req *models.EmbeddingRequest, apiKey string) (*models.EmbeddingResponse, error)
+
+	// MaxEmbeddingInputs 单次请求最多的 input 数，超出时由 handler 分批请求
+	MaxEmbeddingInputs() int
+}
+
+// CompletionProvider 可选接口：原生支持旧版 /v1/completions 的 Provider
+// 其他 Provider 由 handler 通过 Chat Completions 模拟
+type CompletionProvider interface {
+	Completion(req *models.CompletionRequest, apiKey string) (*models.CompletionResponse, error)
+	CompletionStream(req *models.CompletionRequest, apiKey string) (<-chan *models.CompletionResponse, <-chan error)
+}
+
+// ImageProvider 可选接口：支持 /v1/images/generations 的 Provider
+// 只能返回 b64_json 的 Provider 由 handler 按 response_format 转换为临时 URL
+type ImageProvider interface {
+	GenerateImages(req *models.ImageGenerationRequest, apiKey string) (*models.ImageResponse, error)
+}
+
+// TokenCounter 可选接口：能计算 Chat Completions 请求输入 token 数的 Provider
+// 其他 Provider 由 handler 使用本地分词器估算
+type TokenCounter interface {
+	CountTokens(req *models.ChatCompletionRequest, apiKey string) (*models.TokenCountResponse, error)
 }
@@ -0,0 +1,380 @@
+// Package responses 在 OpenAI Responses API 与内部使用的 Chat Completions 格式之间转换，
+// 并保存响应以支持 previous_response_id 续接对话
+package responses
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"openbridge/internal/models"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NewID 生成带前缀的 ID，例如 resp_xxx、msg_xxx
+func NewID(prefix string) string {
+	return prefix + "_" + strings.ReplaceAll(uuid.New().String(), "-", "")
+}
+
+// ToChatRequest 将 Responses 请求转换为 Chat Completions 请求
+// history 为 previous_response_id 对应的历史消息；返回值 input 为本次请求新增的消息（不含 instructions），用于保存
+func ToChatRequest(req *models.ResponsesRequest, history []models.Message) (chatReq *models.ChatCompletionRequest, input []models.Message, err error) {
+	input, err = convertInput(req.Input)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	chatReq = &models.ChatCompletionRequest{
+		Model:               req.Model,
+		Temperature:         req.Temperature,
+		TopP:                req.TopP,
+		MaxCompletionTokens: req.MaxOutputTokens,
+		Stream:              req.Stream,
+		ParallelToolCalls:   req.ParallelToolCalls,
+		User:                req.User,
+	}
+	if req.Stream {
+		// response.completed 需要 usage
+		chatReq.StreamOptions = &models.StreamOptions{IncludeUsage: true}
+	}
+
+	// instructions 只作用于本次请求，不会随 previous_response_id 继承
+	if req.Instructions != "" {
+		chatReq.Messages = append(chatReq.Messages, models.Message{Role: "system", Content: req.Instructions})
+	}
+	chatReq.Messages = append(chatReq.Messages, history...)
+	chatReq.Messages = append(chatReq.Messages, input...)
+
+	// tools
+	for i, tool := range req.Tools {
+		switch tool.Type {
+		case "function":
+			if tool.Name == "" {
+				return nil, nil, models.NewRequestError(fmt.Sprintf("tools[%d].name", i), "function tools must have a name")
+			}
+			def := models.FunctionDefinition{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+			}
+			if tool.Strict != nil {
+				strict, _ := json.Marshal(*tool.Strict)
+				def.Extra = map[string]json.RawMessage{"strict": strict}
+			}
+			chatReq.Tools = append(chatReq.Tools, models.Tool{Type: "function", Function: def})
+		case "web_search", "web_search_preview":
+			chatReq.WebSearchOptions = &models.WebSearchOptions{UserLocation: tool.UserLocation}
+		default:
+			return nil, nil, models.NewRequestError(fmt.Sprintf("tools[%d].type", i), "tool type %q is not supported", tool.Type)
+		}
+	}
+	chatReq.ToolChoice = convertToolChoice(req.ToolChoice)
+
+	// text.format -> response_format
+	if req.Text != nil && req.Text.Format != nil {
+		switch format := req.Text.Format; format.Type {
+		case "text":
+		case "json_object":
+			chatReq.ResponseFormat = &models.ResponseFormat{Type: "json_object"}
+		case "json_schema":
+			chatReq.ResponseFormat = &models.ResponseFormat{
+				Type: "json_schema",
+				JSONSchema: &models.JSONSchema{
+					Name:        format.Name,
+					Description: format.Description,
+					Schema:      format.Schema,
+					Strict:      format.Strict,
+				},
+			}
+		default:
+			return nil, nil, models.NewRequestError("text.format.type", "unknown text format %q", format.Type)
+		}
+	}
+
+	if req.Reasoning != nil {
+		chatReq.ReasoningEffort = req.Reasoning.Effort
+	}
+
+	return chatReq, input, nil
+}
+
+// convertInput 将 input（字符串或 item 数组）转换为 Chat Completions 消息
+func convertInput(raw json.RawMessage) ([]models.Message, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil, models.NewRequestError("input", "input is required")
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return []models.Message{{Role: "user", Content: text}}, nil
+	}
+
+	var items []models.ResponseInputItem
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, models.NewRequestError("input", "input must be a string or an array of input items")
+	}
+
+	var messages []models.Message
+	for i, item := range items {
+		switch item.Type {
+		case "", "message":
+			content, err := convertContent(item.Content, fmt.Sprintf("input[%d].content", i))
+			if err != nil {
+				return nil, err
+			}
+			switch item.Role {
+			case "user", "assistant", "system", "developer":
+			default:
+				return nil, models.NewRequestError(fmt.Sprintf("input[%d].role", i), "unknown message role %q", item.Role)
+			}
+			messages = append(messages, models.Message{Role: item.Role, Content: content})
+
+		case "function_call":
+			call := models.ToolCall{
+				ID:       item.CallID,
+				Type:     "function",
+				Function: models.FunctionCall{Name: item.Name, Arguments: item.Arguments},
+			}
+			// 连续的 function_call 属于同一条 assistant 消息（并行调用）
+			if n := len(messages); n > 0 && messages[n-1].Role == "assistant" {
+				messages[n-1].ToolCalls = append(messages[n-1].ToolCalls, call)
+			} else {
+				messages = append(messages, models.Message{Role: "assistant", Content: "", ToolCalls: []models.ToolCall{call}})
+			}
+
+		case "function_call_output":
+			if item.CallID == "" {
+				return nil, models.NewRequestError(fmt.Sprintf("input[%d].call_id", i), "function_call_output must have a call_id")
+			}
+			output, err := outputText(item.Output)
+			if err != nil {
+				return nil, models.NewRequestError(fmt.Sprintf("input[%d].output", i), "%v", err)
+			}
+			messages = append(messages, models.Message{Role: "tool", ToolCallID: item.CallID, Content: output})
+
+		case "reasoning":
+			// 推理项依赖具体上游的签名，无法跨 Provider 回放，直接跳过
+
+		default:
+			return nil, models.NewRequestError(fmt.Sprintf("input[%d].type", i), "input item type %q is not supported", item.Type)
+		}
+	}
+	return messages, nil
+}
+
+// convertContent 将 message content 转换为 Chat Completions content（字符串或 content part 数组）
+func convertContent(raw json.RawMessage, param string) (interface{}, error) {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text, nil
+	}
+
+	var parts []models.ResponseInputContent
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return nil, models.NewRequestError(param, "content must be a string or an array of content parts")
+	}
+
+	result := make([]interface{}, 0, len(parts))
+	for j, part := range parts {
+		switch part.Type {
+		case "input_text", "output_text", "text":
+			result = append(result, map[string]interface{}{"type": "text", "text": part.Text})
+		case "input_image":
+			if part.ImageURL == "" {
+				return nil, models.NewRequestError(fmt.Sprintf("%s[%d].image_url", param, j), "input_image requires image_url, file_id is not supported")
+			}
+			imageURL := map[string]interface{}{"url": part.ImageURL}
+			if part.Detail != "" {
+				imageURL["detail"] = part.Detail
+			}
+			result = append(result, map[string]interface{}{"type": "image_url", "image_url": imageURL})
+		case "input_file":
+			file := map[string]interface{}{}
+			if part.FileData != "" {
+				file["file_data"] = part.FileData
+			}
+			if part.FileID != "" {
+				file["file_id"] = part.FileID
+			}
+			if part.Filename != "" {
+				file["filename"] = part.Filename
+			}
+			result = append(result, map[string]interface{}{"type": "file", "file": file})
+		default:
+			return nil, models.NewRequestError(fmt.Sprintf("%s[%d].type", param, j), "content type %q is not supported", part.Type)
+		}
+	}
+	return result, nil
+}
+
+// outputText function_call_output 的 output 可以是字符串或文本内容块数组
+func outputText(raw json.RawMessage) (string, error) {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text, nil
+	}
+	var parts []models.ResponseInputContent
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return "", fmt.Errorf("output must be a string or an array of text parts")
+	}
+	var b strings.Builder
+	for _, part := range parts {
+		b.WriteString(part.Text)
+	}
+	return b.String(), nil
+}
+
+// convertToolChoice {"type": "function", "name": ...} -> {"type": "function", "function": {"name": ...}}
+func convertToolChoice(toolChoice any) any {
+	choice, ok := toolChoice.(map[string]interface{})
+	if !ok {
+		return toolChoice
+	}
+	if choice["type"] == "function" {
+		return map[string]interface{}{
+			"type":     "function",
+			"function": map[string]interface{}{"name": choice["name"]},
+		}
+	}
+	// 指定内置工具（如 web_search_preview）时由模型决定
+	return "auto"
+}
+
+// NewResponse 根据请求创建 status 为 in_progress 的 Response
+func NewResponse(req *models.ResponsesRequest) *models.Response {
+	resp := &models.Response{
+		ID:                 NewID("resp"),
+		Object:             "response",
+		CreatedAt:          time.Now().Unix(),
+		Status:             "in_progress",
+		Model:              req.Model,
+		Output:             []models.ResponseOutputItem{},
+		Instructions:       req.Instructions,
+		PreviousResponseID: req.PreviousResponseID,
+		Metadata:           req.Metadata,
+		Tools:              req.Tools,
+		ToolChoice:         req.ToolChoice,
+		ParallelToolCalls:  req.ParallelToolCalls == nil || *req.ParallelToolCalls,
+		Temperature:        req.Temperature,
+		TopP:               req.TopP,
+		MaxOutputTokens:    req.MaxOutputTokens,
+		Text:               req.Text,
+		Store:              req.ShouldStore(),
+	}
+	if resp.Metadata == nil {
+		resp.Metadata = map[string]string{}
+	}
+	if resp.Tools == nil {
+		resp.Tools = []models.ResponsesTool{}
+	}
+	if resp.ToolChoice == nil {
+		resp.ToolChoice = "auto"
+	}
+	return resp
+}
+
+// ApplyChatResponse 将 Chat Completions 响应的第一个 choice 填入 Response
+func ApplyChatResponse(resp *models.Response, chatResp *models.ChatCompletionResponse) {
+	if len(chatResp.Choices) > 0 {
+		choice := chatResp.Choices[0]
+		msg := choice.Message
+
+		if msg.ReasoningContent != "" {
+			resp.Output = append(resp.Output, models.ResponseOutputItem{
+				Type:    "reasoning",
+				ID:      NewID("rs"),
+				Summary: []models.ResponseContentPart{{Type: "summary_text", Text: msg.ReasoningContent}},
+			})
+		}
+		if msg.Content != "" || len(msg.ToolCalls) == 0 {
+			resp.Output = append(resp.Output, models.ResponseOutputItem{
+				Type:   "message",
+				ID:     NewID("msg"),
+				Status: "completed",
+				Role:   "assistant",
+				Content: []models.ResponseContentPart{{
+					Type:        "output_text",
+					Text:        msg.Content,
+					Annotations: convertAnnotations(msg.Annotations),
+				}},
+			})
+		}
+		for _, tc := range msg.ToolCalls {
+			resp.Output = append(resp.Output, models.ResponseOutputItem{
+				Type:      "function_call",
+				ID:        NewID("fc"),
+				Status:    "completed",
+				CallID:    tc.ID,
+				Name:      tc.Function.Name,
+				Arguments: tc.Function.Arguments,
+			})
+		}
+		finish(resp, choice.FinishReason)
+	} else {
+		finish(resp, "stop")
+	}
+
+	resp.Usage = ConvertUsage(chatResp.Usage)
+}
+
+// finish 根据 finish_reason 设置最终状态
+func finish(resp *models.Response, finishReason string) {
+	switch finishReason {
+	case "length":
+		resp.Status = "incomplete"
+		resp.IncompleteDetails = &models.IncompleteDetails{Reason: "max_output_tokens"}
+	case "content_filter":
+		resp.Status = "incomplete"
+		resp.IncompleteDetails = &models.IncompleteDetails{Reason: "content_filter"}
+	default:
+		resp.Status = "completed"
+	}
+}
+
+// convertAnnotations url_citation 从嵌套结构展开到顶层
+func convertAnnotations(annotations []models.Annotation) []models.ResponseAnnotation {
+	var result []models.ResponseAnnotation
+	for _, a := range annotations {
+		if a.Type != "url_citation" || a.URLCitation == nil {
+			continue
+		}
+		result = append(result, models.ResponseAnnotation{
+			Type:       "url_citation",
+			StartIndex: a.URLCitation.StartIndex,
+			EndIndex:   a.URLCitation.EndIndex,
+			URL:        a.URLCitation.URL,
+			Title:      a.URLCitation.Title,
+		})
+	}
+	return result
+}
+
+// ConvertUsage 转换 token 用量
+func ConvertUsage(usage models.Usage) *models.ResponseUsage {
+	result := &models.ResponseUsage{
+		InputTokens:  usage.PromptTokens,
+		OutputTokens: usage.CompletionTokens,
+		TotalTokens:  usage.TotalTokens,
+	}
+	if usage.PromptTokensDetails != nil {
+		result.InputTokensDetails.CachedTokens = usage.PromptTokensDetails.CachedTokens
+	}
+	if usage.CompletionTokensDetails != nil {
+		result.OutputTokensDetails.ReasoningTokens = usage.CompletionTokensDetails.ReasoningTokens
+	}
+	return result
+}
+
+// AssistantMessage 将模型输出转换为 assistant 消息，与输入一起保存供续接使用
+// 保留 thinking_blocks，Claude 在思考模式下续接工具调用时需要回传
+func AssistantMessage(msg models.ResponseMessage) models.Message {
+	return models.Message{
+		Role:           "assistant",
+		Content:        msg.Content,
+		ToolCalls:      msg.ToolCalls,
+		ThinkingBlocks: msg.ThinkingBlocks,
+	}
+}
@@ -0,0 +1,103 @@
+package responses
+
+import (
+	"encoding/json"
+	"errors"
+	"openbridge/internal/models"
+	"reflect"
+	"testing"
+)
+
+func TestConvertInput(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []models.Message
+	}{
+		{
+			name:  "string",
+			input: `"hello"`,
+			want:  []models.Message{{Role: "user", Content: "hello"}},
+		},
+		{
+			name: "messages with roles",
+			input: `[
+				{"role": "developer", "content": "be brief"},
+				{"type": "message", "role": "user", "content": "hi"},
+				{"role": "assistant", "content": [{"type": "output_text", "text": "hello"}]}
+			]`,
+			want: []models.Message{
+				{Role: "developer", Content: "be brief"},
+				{Role: "user", Content: "hi"},
+				{Role: "assistant", Content: []interface{}{map[string]interface{}{"type": "text", "text": "hello"}}},
+			},
+		},
+		{
+			name: "content parts",
+			input: `[{"role": "user", "content": [
+				{"type": "input_text", "text": "what is this?"},
+				{"type": "input_image", "image_url": "https://example.com/a.png", "detail": "low"},
+				{"type": "input_file", "file_data": "data:application/pdf;base64,AA==", "filename": "a.pdf"}
+			]}]`,
+			want: []models.Message{{Role: "user", Content: []interface{}{
+				map[string]interface{}{"type": "text", "text": "what is this?"},
+				map[string]interface{}{"type": "image_url", "image_url": map[string]interface{}{"url": "https://example.com/a.png", "detail": "low"}},
+				map[string]interface{}{"type": "file", "file": map[string]interface{}{"file_data": "data:application/pdf;base64,AA==", "filename": "a.pdf"}},
+			}}},
+		},
+		{
+			name: "parallel function calls and outputs",
+			input: `[
+				{"role": "user", "content": "weather?"},
+				{"type": "reasoning", "summary": []},
+				{"type": "function_call", "call_id": "call_1", "name": "get_weather", "arguments": "{\"city\":\"Paris\"}"},
+				{"type": "function_call", "call_id": "call_2", "name": "get_weather", "arguments": "{\"city\":\"Rome\"}"},
+				{"type": "function_call_output", "call_id": "call_1", "output": "sunny"},
+				{"type": "function_call_output", "call_id": "call_2", "output": [{"type": "input_text", "text": "rain"}]}
+			]`,
+			want: []models.Message{
+				{Role: "user", Content: "weather?"},
+				{Role: "assistant", Content: "", ToolCalls: []models.ToolCall{
+					{ID: "call_1", Type: "function", Function: models.FunctionCall{Name: "get_weather", Arguments: `{"city":"Paris"}`}},
+					{ID: "call_2", Type: "function", Function: models.FunctionCall{Name: "get_weather", Arguments: `{"city":"Rome"}`}},
+				}},
+				{Role: "tool", ToolCallID: "call_1", Content: "sunny"},
+				{Role: "tool", ToolCallID: "call_2", Content: "rain"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		got, err := convertInput(json.RawMessage(tt.input))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\n got %#v\nwant %#v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestConvertInputErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		param string
+	}{
+		{``, "input"},
+		{`null`, "input"},
+		{`42`, "input"},
+		{`[{"role": "tool", "content": "x"}]`, "input[0].role"},
+		{`[{"role": "user", "content": 1}]`, "input[0].content"},
+		{`[{"role": "user", "content": [{"type": "input_audio"}]}]`, "input[0].content[0].type"},
+		{`[{"role": "user", "content": [{"type": "input_image", "file_id": "file_1"}]}]`, "input[0].content[0].image_url"},
+		{`[{"role": "user", "content": "hi"}, {"type": "function_call_output", "output": "x"}]`, "input[1].call_id"},
+		{`[{"type": "item_reference", "id": "msg_1"}]`, "input[0].type"},
+	}
+	for _, tt := range tests {
+		_, err := convertInput(json.RawMessage(tt.input))
+		var reqErr *models.RequestError
+		if !errors.As(err, &reqErr) || reqErr.Param != tt.param {
+			t.Errorf("convertInput(%s) = %v, want a RequestError for %s", tt.input, err, tt.param)
+		}
+	}
+}
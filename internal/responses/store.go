@@ -0,0 +1,117 @@
+package responses
+
+import (
+	"container/list"
+	"openbridge/internal/models"
+	"sync"
+	"time"
+)
+
+// StoreOptions 响应存储配置
+type StoreOptions struct {
+	MaxEntries int           // 最多保存的响应数，默认 1000，负数关闭存储
+	TTL        time.Duration // 保存时间，默认 1h
+}
+
+// Stored 保存的响应及续接所需的完整对话（不含 instructions）
+type Stored struct {
+	Owner    string // 创建者（用户名或客户端 Key 的摘要），只有创建者可以读取、删除和续接
+	Response *models.Response
+	Messages []models.Message
+}
+
+// Store 内存中的响应存储，超出容量时淘汰最久未使用的响应
+// 进程重启后保存的响应会丢失
+type Store struct {
+	mu      sync.Mutex
+	opts    StoreOptions
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type storeEntry struct {
+	id      string
+	stored  *Stored
+	expires time.Time
+}
+
+// NewStore 创建响应存储，MaxEntries 为负数时返回 nil（不保存任何响应）
+func NewStore(opts StoreOptions) *Store {
+	if opts.MaxEntries < 0 {
+		return nil
+	}
+	if opts.MaxEntries == 0 {
+		opts.MaxEntries = 1000
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = time.Hour
+	}
+	return &Store{
+		opts:    opts,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Get 返回 owner 保存的响应，属于其他调用方的响应视为不存在
+func (s *Store) Get(id, owner string) (*Stored, bool) {
+	if s == nil {
+		return nil, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.entries[id]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*storeEntry)
+	if time.Now().After(entry.expires) {
+		s.order.Remove(el)
+		delete(s.entries, id)
+		return nil, false
+	}
+	if entry.stored.Owner != owner {
+		return nil, false
+	}
+	s.order.MoveToFront(el)
+	return entry.stored, true
+}
+
+// Put 保存响应
+func (s *Store) Put(stored *Stored) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := stored.Response.ID
+	if el, ok := s.entries[id]; ok {
+		s.order.Remove(el)
+	}
+	s.entries[id] = s.order.PushFront(&storeEntry{id: id, stored: stored, expires: time.Now().Add(s.opts.TTL)})
+
+	for s.order.Len() > s.opts.MaxEntries {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*storeEntry).id)
+	}
+}
+
+// Delete 删除 owner 保存的响应，返回是否存在
+func (s *Store) Delete(id, owner string) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.entries[id]
+	if !ok || el.Value.(*storeEntry).stored.Owner != owner {
+		return false
+	}
+	s.order.Remove(el)
+	delete(s.entries, id)
+	return true
+}
@@ -0,0 +1,28 @@
+package responses
+
+import (
+	"openbridge/internal/models"
+	"testing"
+)
+
+// 保存的响应只对创建者可见
+func TestStoreOwner(t *testing.T) {
+	s := NewStore(StoreOptions{})
+	s.Put(&Stored{Owner: "user:alice", Response: &models.Response{ID: "resp_1"}})
+
+	if _, ok := s.Get("resp_1", "user:bob"); ok {
+		t.Error("another user can read the response")
+	}
+	if s.Delete("resp_1", "user:bob") {
+		t.Error("another user can delete the response")
+	}
+	if stored, ok := s.Get("resp_1", "user:alice"); !ok || stored.Response.ID != "resp_1" {
+		t.Error("owner cannot read the response")
+	}
+	if !s.Delete("resp_1", "user:alice") {
+		t.Error("owner cannot delete the response")
+	}
+	if _, ok := s.Get("resp_1", "user:alice"); ok {
+		t.Error("response still present after delete")
+	}
+}
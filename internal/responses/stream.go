@@ -0,0 +1,319 @@
+package responses
+
+import (
+	"openbridge/internal/models"
+	"strings"
+)
+
+// StreamConverter 将 Chat Completions 流式块转换为 Responses API 的语义事件
+// 每个输出项（reasoning、message、function_call）依次经历 added -> delta -> done，新的输出项开始时结束上一个
+type StreamConverter struct {
+	resp    *models.Response
+	seq     int
+	started bool
+
+	items   []*streamItem       // 按 output_index 排列
+	current *streamItem         // 正在输出的项
+	tools   map[int]*streamItem // tool_calls 序号 -> function_call 项
+
+	message      models.ResponseMessage // 累积的完整输出，用于保存对话
+	finishReason string
+	usage        *models.Usage
+}
+
+type streamItem struct {
+	index       int
+	item        models.ResponseOutputItem
+	text        strings.Builder // message 的文本、reasoning 的摘要或 function_call 的参数
+	annotations []models.ResponseAnnotation
+	toolCall    int // function_call 在 message.ToolCalls 中的位置
+	done        bool
+}
+
+// NewStreamConverter 创建流式转换器，resp 为 NewResponse 创建的 in_progress 响应
+func NewStreamConverter(resp *models.Response) *StreamConverter {
+	return &StreamConverter{
+		resp:    resp,
+		tools:   make(map[int]*streamItem),
+		message: models.ResponseMessage{Role: "assistant"},
+	}
+}
+
+// Response 返回当前的响应
+func (c *StreamConverter) Response() *models.Response {
+	return c.resp
+}
+
+// Message 返回累积的完整输出
+func (c *StreamConverter) Message() models.ResponseMessage {
+	return c.message
+}
+
+// Convert 转换一个流式块，第一次调用时先输出 response.created 和 response.in_progress
+func (c *StreamConverter) Convert(chunk *models.ChatCompletionChunk) []models.ResponseStreamEvent {
+	var events []models.ResponseStreamEvent
+	if !c.started {
+		c.started = true
+		events = append(events,
+			c.event(models.ResponseStreamEvent{Type: "response.created", Response: c.snapshot()}),
+			c.event(models.ResponseStreamEvent{Type: "response.in_progress", Response: c.snapshot()}),
+		)
+	}
+
+	if chunk.Usage != nil {
+		c.usage = chunk.Usage
+	}
+
+	// Responses API 只有一个输出，忽略 n > 1 时的其他 choice
+	for _, choice := range chunk.Choices {
+		if choice.Index != 0 {
+			continue
+		}
+		delta := choice.Delta
+
+		if delta.ReasoningContent != "" {
+			item, opened := c.open(models.ResponseOutputItem{Type: "reasoning"}, false, &events)
+			if opened {
+				events = append(events, c.event(models.ResponseStreamEvent{
+					Type:         "response.reasoning_summary_part.added",
+					ItemID:       item.item.ID,
+					OutputIndex:  intPtr(item.index),
+					SummaryIndex: intPtr(0),
+					Part:         &models.ResponseContentPart{Type: "summary_text"},
+				}))
+			}
+			item.text.WriteString(delta.ReasoningContent)
+			c.message.ReasoningContent += delta.ReasoningContent
+			events = append(events, c.event(models.ResponseStreamEvent{
+				Type:         "response.reasoning_summary_text.delta",
+				ItemID:       item.item.ID,
+				OutputIndex:  intPtr(item.index),
+				SummaryIndex: intPtr(0),
+				Delta:        delta.ReasoningContent,
+			}))
+		}
+		c.message.ThinkingBlocks = append(c.message.ThinkingBlocks, delta.ThinkingBlocks...)
+
+		if delta.Content != "" {
+			item := c.openMessage(&events)
+			item.text.WriteString(delta.Content)
+			c.message.Content += delta.Content
+			events = append(events, c.event(models.ResponseStreamEvent{
+				Type:         "response.output_text.delta",
+				ItemID:       item.item.ID,
+				OutputIndex:  intPtr(item.index),
+				ContentIndex: intPtr(0),
+				Delta:        delta.Content,
+			}))
+		}
+
+		for _, a := range convertAnnotations(delta.Annotations) {
+			a := a
+			item := c.openMessage(&events)
+			item.annotations = append(item.annotations, a)
+			c.message.Annotations = append(c.message.Annotations, models.NewURLCitation(a.StartIndex, a.EndIndex, a.URL, a.Title))
+			events = append(events, c.event(models.ResponseStreamEvent{
+				Type:            "response.output_text.annotation.added",
+				ItemID:          item.item.ID,
+				OutputIndex:     intPtr(item.index),
+				ContentIndex:    intPtr(0),
+				AnnotationIndex: intPtr(len(item.annotations) - 1),
+				Annotation:      &a,
+			}))
+		}
+
+		for _, tc := range delta.ToolCalls {
+			index := 0
+			if tc.Index != nil {
+				index = *tc.Index
+			}
+			item, ok := c.tools[index]
+			if !ok {
+				item, _ = c.open(models.ResponseOutputItem{Type: "function_call", CallID: tc.ID, Name: tc.Function.Name}, true, &events)
+				item.toolCall = len(c.message.ToolCalls)
+				c.tools[index] = item
+				c.message.ToolCalls = append(c.message.ToolCalls, models.ToolCall{ID: tc.ID, Type: "function", Function: models.FunctionCall{Name: tc.Function.Name}})
+			}
+			if tc.Function.Arguments == "" {
+				continue
+			}
+			item.text.WriteString(tc.Function.Arguments)
+			c.message.ToolCalls[item.toolCall].Function.Arguments += tc.Function.Arguments
+			events = append(events, c.event(models.ResponseStreamEvent{
+				Type:        "response.function_call_arguments.delta",
+				ItemID:      item.item.ID,
+				OutputIndex: intPtr(item.index),
+				Delta:       tc.Function.Arguments,
+			}))
+		}
+
+		if choice.FinishReason != nil {
+			c.finishReason = *choice.FinishReason
+		}
+	}
+	return events
+}
+
+// Finish 结束所有输出项，返回 response.completed（或 response.incomplete）
+func (c *StreamConverter) Finish() []models.ResponseStreamEvent {
+	var events []models.ResponseStreamEvent
+	if !c.started {
+		c.started = true
+		events = append(events, c.event(models.ResponseStreamEvent{Type: "response.created", Response: c.snapshot()}))
+	}
+	for _, item := range c.items {
+		events = append(events, c.close(item)...)
+	}
+
+	c.resp.Output = make([]models.ResponseOutputItem, 0, len(c.items))
+	for _, item := range c.items {
+		c.resp.Output = append(c.resp.Output, item.item)
+	}
+	finish(c.resp, c.finishReason)
+	if c.usage != nil {
+		c.resp.Usage = ConvertUsage(*c.usage)
+	}
+
+	eventType := "response.completed"
+	if c.resp.Status == "incomplete" {
+		eventType = "response.incomplete"
+	}
+	return append(events, c.event(models.ResponseStreamEvent{Type: eventType, Response: c.snapshot()}))
+}
+
+// Fail 输出中途出错时返回 response.failed
+func (c *StreamConverter) Fail(code, message string) []models.ResponseStreamEvent {
+	c.resp.Status = "failed"
+	c.resp.Error = &models.ResponseError{Code: code, Message: message}
+	return []models.ResponseStreamEvent{c.event(models.ResponseStreamEvent{Type: "response.failed", Response: c.snapshot()})}
+}
+
+// openMessage 返回正在输出的 message 项，没有时新建
+func (c *StreamConverter) openMessage(events *[]models.ResponseStreamEvent) *streamItem {
+	item, opened := c.open(models.ResponseOutputItem{Type: "message", Role: "assistant"}, false, events)
+	if opened {
+		*events = append(*events, c.event(models.ResponseStreamEvent{
+			Type:         "response.content_part.added",
+			ItemID:       item.item.ID,
+			OutputIndex:  intPtr(item.index),
+			ContentIndex: intPtr(0),
+			Part:         &models.ResponseContentPart{Type: "output_text"},
+		}))
+	}
+	return item
+}
+
+// open 返回正在输出的同类型的项；类型不同或 always 为 true（新的工具调用）时结束当前项并按 template 新建，opened 表示是否新建
+func (c *StreamConverter) open(template models.ResponseOutputItem, always bool, events *[]models.ResponseStreamEvent) (item *streamItem, opened bool) {
+	if c.current != nil && c.current.item.Type == template.Type && !always {
+		return c.current, false
+	}
+	if c.current != nil {
+		*events = append(*events, c.close(c.current)...)
+	}
+
+	prefix := map[string]string{"reasoning": "rs", "message": "msg", "function_call": "fc"}[template.Type]
+	template.ID = NewID(prefix)
+	if template.Type != "reasoning" {
+		template.Status = "in_progress"
+	}
+	item = &streamItem{index: len(c.items), item: template}
+	c.items = append(c.items, item)
+	c.current = item
+
+	added := item.item
+	*events = append(*events, c.event(models.ResponseStreamEvent{
+		Type:        "response.output_item.added",
+		OutputIndex: intPtr(item.index),
+		Item:        &added,
+	}))
+	return item, true
+}
+
+// close 结束一个输出项，填入完整内容
+func (c *StreamConverter) close(item *streamItem) []models.ResponseStreamEvent {
+	if item.done {
+		return nil
+	}
+	item.done = true
+	if c.current == item {
+		c.current = nil
+	}
+
+	var events []models.ResponseStreamEvent
+	text := item.text.String()
+	switch item.item.Type {
+	case "reasoning":
+		part := models.ResponseContentPart{Type: "summary_text", Text: text}
+		item.item.Summary = []models.ResponseContentPart{part}
+		events = append(events,
+			c.event(models.ResponseStreamEvent{
+				Type:         "response.reasoning_summary_text.done",
+				ItemID:       item.item.ID,
+				OutputIndex:  intPtr(item.index),
+				SummaryIndex: intPtr(0),
+				Text:         text,
+			}),
+			c.event(models.ResponseStreamEvent{
+				Type:         "response.reasoning_summary_part.done",
+				ItemID:       item.item.ID,
+				OutputIndex:  intPtr(item.index),
+				SummaryIndex: intPtr(0),
+				Part:         &part,
+			}),
+		)
+	case "message":
+		part := models.ResponseContentPart{Type: "output_text", Text: text, Annotations: item.annotations}
+		item.item.Content = []models.ResponseContentPart{part}
+		item.item.Status = "completed"
+		events = append(events,
+			c.event(models.ResponseStreamEvent{
+				Type:         "response.output_text.done",
+				ItemID:       item.item.ID,
+				OutputIndex:  intPtr(item.index),
+				ContentIndex: intPtr(0),
+				Text:         text,
+			}),
+			c.event(models.ResponseStreamEvent{
+				Type:         "response.content_part.done",
+				ItemID:       item.item.ID,
+				OutputIndex:  intPtr(item.index),
+				ContentIndex: intPtr(0),
+				Part:         &part,
+			}),
+		)
+	case "function_call":
+		item.item.Arguments = text
+		item.item.Status = "completed"
+		events = append(events, c.event(models.ResponseStreamEvent{
+			Type:        "response.function_call_arguments.done",
+			ItemID:      item.item.ID,
+			OutputIndex: intPtr(item.index),
+			Arguments:   text,
+		}))
+	}
+
+	done := item.item
+	return append(events, c.event(models.ResponseStreamEvent{
+		Type:        "response.output_item.done",
+		OutputIndex: intPtr(item.index),
+		Item:        &done,
+	}))
+}
+
+// event 填入递增的 sequence_number
+func (c *StreamConverter) event(e models.ResponseStreamEvent) models.ResponseStreamEvent {
+	e.SequenceNumber = c.seq
+	c.seq++
+	return e
+}
+
+// snapshot 返回当前响应的副本，避免之后的修改影响已生成的事件
+func (c *StreamConverter) snapshot() *models.Response {
+	resp := *c.resp
+	return &resp
+}
+
+func intPtr(i int) *int {
+	return &i
+}
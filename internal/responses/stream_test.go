@@ -0,0 +1,124 @@
+package responses
+
+import (
+	"openbridge/internal/models"
+	"reflect"
+	"testing"
+)
+
+func intp(i int) *int { return &i }
+
+func TestStreamConverterEventOrder(t *testing.T) {
+	stop := "tool_calls"
+	chunks := []*models.ChatCompletionChunk{
+		{Choices: []models.ChunkChoice{{Delta: models.ChunkDelta{Role: "assistant"}}}},
+		{Choices: []models.ChunkChoice{{Delta: models.ChunkDelta{ReasoningContent: "think"}}}},
+		{Choices: []models.ChunkChoice{{Delta: models.ChunkDelta{Content: "Hel"}}}},
+		{Choices: []models.ChunkChoice{{Delta: models.ChunkDelta{Content: "lo"}}}},
+		{Choices: []models.ChunkChoice{{Delta: models.ChunkDelta{ToolCalls: []models.ToolCall{
+			{Index: intp(0), ID: "call_1", Type: "function", Function: models.FunctionCall{Name: "f"}},
+		}}}}},
+		{Choices: []models.ChunkChoice{{Delta: models.ChunkDelta{ToolCalls: []models.ToolCall{
+			{Index: intp(0), Function: models.FunctionCall{Arguments: `{"a":1}`}},
+		}}}}},
+		{Choices: []models.ChunkChoice{{Delta: models.ChunkDelta{ToolCalls: []models.ToolCall{
+			{Index: intp(1), ID: "call_2", Type: "function", Function: models.FunctionCall{Name: "g", Arguments: `{}`}},
+		}}}}},
+		{Choices: []models.ChunkChoice{{Delta: models.ChunkDelta{}, FinishReason: &stop}}},
+		{Choices: []models.ChunkChoice{}, Usage: &models.Usage{PromptTokens: 3, CompletionTokens: 4, TotalTokens: 7}},
+	}
+
+	c := NewStreamConverter(NewResponse(&models.ResponsesRequest{Model: "m"}))
+	var events []models.ResponseStreamEvent
+	for _, chunk := range chunks {
+		events = append(events, c.Convert(chunk)...)
+	}
+	events = append(events, c.Finish()...)
+
+	type step struct {
+		typ         string
+		outputIndex int // -1 表示没有 output_index
+	}
+	want := []step{
+		{"response.created", -1},
+		{"response.in_progress", -1},
+		{"response.output_item.added", 0},
+		{"response.reasoning_summary_part.added", 0},
+		{"response.reasoning_summary_text.delta", 0},
+		{"response.reasoning_summary_text.done", 0},
+		{"response.reasoning_summary_part.done", 0},
+		{"response.output_item.done", 0},
+		{"response.output_item.added", 1},
+		{"response.content_part.added", 1},
+		{"response.output_text.delta", 1},
+		{"response.output_text.delta", 1},
+		{"response.output_text.done", 1},
+		{"response.content_part.done", 1},
+		{"response.output_item.done", 1},
+		{"response.output_item.added", 2},
+		{"response.function_call_arguments.delta", 2},
+		{"response.function_call_arguments.done", 2},
+		{"response.output_item.done", 2},
+		{"response.output_item.added", 3},
+		{"response.function_call_arguments.delta", 3},
+		{"response.function_call_arguments.done", 3},
+		{"response.output_item.done", 3},
+		{"response.completed", -1},
+	}
+	var got []step
+	for i, e := range events {
+		if e.SequenceNumber != i {
+			t.Errorf("event %d (%s) has sequence_number %d", i, e.Type, e.SequenceNumber)
+		}
+		index := -1
+		if e.OutputIndex != nil {
+			index = *e.OutputIndex
+		}
+		got = append(got, step{e.Type, index})
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("events:\n got %v\nwant %v", got, want)
+	}
+
+	// 各项的 added 与 done 使用同一个 ID，done 事件携带完整内容
+	if events[8].Item.ID != events[14].Item.ID || events[12].Text != "Hello" {
+		t.Errorf("message item = %+v / %+v, text %q", events[8].Item, events[14].Item, events[12].Text)
+	}
+	if events[17].Arguments != `{"a":1}` || events[21].Arguments != `{}` {
+		t.Errorf("arguments = %q, %q", events[17].Arguments, events[21].Arguments)
+	}
+
+	resp := events[len(events)-1].Response
+	if resp.Status != "completed" || len(resp.Output) != 4 || resp.Usage == nil || resp.Usage.TotalTokens != 7 {
+		t.Errorf("completed response = %+v", resp)
+	}
+	if out := resp.Output[3]; out.Type != "function_call" || out.CallID != "call_2" || out.Name != "g" || out.Status != "completed" {
+		t.Errorf("output[3] = %+v", out)
+	}
+
+	msg := c.Message()
+	if msg.Content != "Hello" || msg.ReasoningContent != "think" || len(msg.ToolCalls) != 2 || msg.ToolCalls[0].Function.Arguments != `{"a":1}` {
+		t.Errorf("accumulated message = %+v", msg)
+	}
+}
+
+func TestStreamConverterIncompleteAndFailed(t *testing.T) {
+	length := "length"
+	c := NewStreamConverter(NewResponse(&models.ResponsesRequest{Model: "m"}))
+	c.Convert(&models.ChatCompletionChunk{Choices: []models.ChunkChoice{{Delta: models.ChunkDelta{Content: "cut"}, FinishReason: &length}}})
+	events := c.Finish()
+	last := events[len(events)-1]
+	if last.Type != "response.incomplete" || last.Response.IncompleteDetails == nil || last.Response.IncompleteDetails.Reason != "max_output_tokens" {
+		t.Errorf("last event = %s %+v, want response.incomplete with max_output_tokens", last.Type, last.Response)
+	}
+
+	c = NewStreamConverter(NewResponse(&models.ResponsesRequest{Model: "m"}))
+	started := c.Convert(&models.ChatCompletionChunk{Choices: []models.ChunkChoice{{Delta: models.ChunkDelta{Content: "x"}}}})
+	failed := c.Fail("server_error", "boom")
+	if len(failed) != 1 || failed[0].Type != "response.failed" || failed[0].Response.Error.Message != "boom" {
+		t.Errorf("failed = %+v", failed)
+	}
+	if failed[0].SequenceNumber != len(started) {
+		t.Errorf("response.failed sequence_number = %d, want %d", failed[0].SequenceNumber, len(started))
+	}
+}
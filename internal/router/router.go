@@ -2,9 +2,11 @@ package router
 
 import (
 	"net/http"
+	"openbridge/internal/blob"
 	"openbridge/internal/config"
 	"openbridge/internal/handler"
 	"openbridge/internal/provider"
+	"openbridge/internal/responses"
 	"openbridge/internal/service"
 	"openbridge/internal/user"
 
@@ -49,12 +51,27 @@ func Setup(cfg *config.Config, registry *provider.Registry, keyManagers *service
 	r.GET("/providers", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"providers": registry.ListProviders(),
+			"types":     provider.SupportedTypes(),
 		})
 	})
 
 	// Initialize handlers
 	chatHandler := handler.NewChatHandler(cfg, registry, keyManagers)
 	modelsHandler := handler.NewModelsHandler(cfg, registry, keyManagers)
+	completionsHandler := handler.NewCompletionsHandler(chatHandler)
+	embeddingsHandler := handler.NewEmbeddingsHandler(chatHandler)
+	tokensHandler := handler.NewTokensHandler(chatHandler)
+	imagesHandler := handler.NewImagesHandler(chatHandler, blob.New(blob.Options{
+		MaxBytes: cfg.Images.BlobMaxBytes,
+		TTL:      cfg.Images.BlobTTL,
+	}), cfg.Images.PublicURL)
+	responsesHandler := handler.NewResponsesHandler(chatHandler, responses.NewStore(responses.StoreOptions{
+		MaxEntries: cfg.Responses.StoreSize,
+		TTL:        cfg.Responses.StoreTTL,
+	}))
+
+	// 生成图片的临时 URL（no auth required，ID 不可猜测）
+	r.GET("/blobs/:id", imagesHandler.GetBlob)
 
 	// OpenAI compatible endpoints (with auth)
 	v1 := r.Group("/v1")
@@ -62,6 +79,21 @@ func Setup(cfg *config.Config, registry *provider.Registry, keyManagers *service
 	{
 		// Chat completions
 		v1.POST("/chat/completions", chatHandler.CreateChatCompletion)
+		v1.POST("/chat/completions/count_tokens", tokensHandler.CountTokens)
+
+		// Legacy completions
+		v1.POST("/completions", completionsHandler.CreateCompletion)
+
+		// Responses
+		v1.POST("/responses", responsesHandler.CreateResponse)
+		v1.GET("/responses/:id", responsesHandler.GetResponse)
+		v1.DELETE("/responses/:id", responsesHandler.DeleteResponse)
+
+		// Embeddings
+		v1.POST("/embeddings", embeddingsHandler.CreateEmbeddings)
+
+		// Images
+		v1.POST("/images/generations", imagesHandler.CreateImage)
 
 		// Models
 		v1.GET("/models", modelsHandler.ListModels)
@@ -0,0 +1,342 @@
+// Package schema 提供一个轻量的 JSON Schema 校验器，用于检查结构化输出是否符合 response_format
+//
+// 支持常用关键字: type, properties, required, additionalProperties, items, enum, const,
+// anyOf, oneOf, allOf, minLength, maxLength, minimum, maximum, minItems, maxItems 以及本地 $ref
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidationError 校验失败，Path 为出错位置（如 $.items[0].name）
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// ValidateJSON 解析 JSON 文本并按 schema 校验
+func ValidateJSON(schema map[string]any, data string) error {
+	var value interface{}
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		return &ValidationError{Path: "$", Message: "invalid JSON: " + err.Error()}
+	}
+	return Validate(schema, value)
+}
+
+// Validate 按 schema 校验已解析的 JSON 值
+func Validate(schema map[string]any, value interface{}) error {
+	v := &validator{root: schema}
+	return v.validate(schema, value, "$", 0)
+}
+
+// maxDepth 防止递归 $ref 导致无限循环
+const maxDepth = 64
+
+type validator struct {
+	root map[string]any
+}
+
+func (v *validator) validate(schema map[string]any, value interface{}, path string, depth int) error {
+	if depth > maxDepth {
+		return nil
+	}
+
+	if ref, ok := schema["$ref"].(string); ok {
+		resolved, err := v.resolve(ref)
+		if err != nil {
+			return &ValidationError{Path: path, Message: err.Error()}
+		}
+		return v.validate(resolved, value, path, depth+1)
+	}
+
+	if t, ok := schema["type"]; ok {
+		if err := checkType(t, value, path); err != nil {
+			return err
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if equal(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("value %s is not one of the allowed values", describe(value))}
+		}
+	}
+
+	if c, ok := schema["const"]; ok && !equal(c, value) {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("value %s does not equal const %s", describe(value), describe(c))}
+	}
+
+	if err := v.validateCombinators(schema, value, path, depth); err != nil {
+		return err
+	}
+
+	switch val := value.(type) {
+	case map[string]interface{}:
+		return v.validateObject(schema, val, path, depth)
+	case []interface{}:
+		return v.validateArray(schema, val, path, depth)
+	case string:
+		length := utf8.RuneCountInString(val)
+		if min, ok := number(schema["minLength"]); ok && float64(length) < min {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("string is shorter than minLength %v", min)}
+		}
+		if max, ok := number(schema["maxLength"]); ok && float64(length) > max {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("string is longer than maxLength %v", max)}
+		}
+	case float64:
+		if min, ok := number(schema["minimum"]); ok && val < min {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("%v is less than minimum %v", val, min)}
+		}
+		if max, ok := number(schema["maximum"]); ok && val > max {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("%v is greater than maximum %v", val, max)}
+		}
+	}
+
+	return nil
+}
+
+func (v *validator) validateCombinators(schema map[string]any, value interface{}, path string, depth int) error {
+	if all, ok := schema["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			if subSchema, ok := sub.(map[string]any); ok {
+				if err := v.validate(subSchema, value, path, depth+1); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	for _, key := range []string{"anyOf", "oneOf"} {
+		variants, ok := schema[key].([]interface{})
+		if !ok {
+			continue
+		}
+		matched := 0
+		var firstErr error
+		for _, sub := range variants {
+			subSchema, ok := sub.(map[string]any)
+			if !ok {
+				continue
+			}
+			if err := v.validate(subSchema, value, path, depth+1); err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			matched++
+		}
+		if matched == 0 {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("value does not match any schema in %s (first error: %v)", key, firstErr)}
+		}
+		if key == "oneOf" && matched > 1 {
+			return &ValidationError{Path: path, Message: "value matches more than one schema in oneOf"}
+		}
+	}
+
+	return nil
+}
+
+func (v *validator) validateObject(schema map[string]any, obj map[string]interface{}, path string, depth int) error {
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, r := range required {
+			if name, ok := r.(string); ok {
+				if _, exists := obj[name]; !exists {
+					return &ValidationError{Path: path, Message: fmt.Sprintf("missing required property %q", name)}
+				}
+			}
+		}
+	}
+
+	props, _ := schema["properties"].(map[string]any)
+
+	// 按属性名排序，保证错误信息稳定
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		childPath := path + "." + k
+		if propSchema, ok := props[k].(map[string]any); ok {
+			if err := v.validate(propSchema, obj[k], childPath, depth+1); err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				return &ValidationError{Path: path, Message: fmt.Sprintf("unexpected property %q", k)}
+			}
+		case map[string]any:
+			if err := v.validate(additional, obj[k], childPath, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (v *validator) validateArray(schema map[string]any, arr []interface{}, path string, depth int) error {
+	if min, ok := number(schema["minItems"]); ok && float64(len(arr)) < min {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("array has fewer than minItems %v", min)}
+	}
+	if max, ok := number(schema["maxItems"]); ok && float64(len(arr)) > max {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("array has more than maxItems %v", max)}
+	}
+
+	if items, ok := schema["items"].(map[string]any); ok {
+		for i, item := range arr {
+			if err := v.validate(items, item, fmt.Sprintf("%s[%d]", path, i), depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolve 解析本地引用，如 "#/$defs/Address"、"#/definitions/Address" 或 "#"
+func (v *validator) resolve(ref string) (map[string]any, error) {
+	if ref == "#" {
+		return v.root, nil
+	}
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("unsupported $ref %q", ref)
+	}
+
+	var node interface{} = v.root
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		m, ok := node.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+		node = m[part]
+	}
+
+	resolved, ok := node.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("unresolvable $ref %q", ref)
+	}
+	return resolved, nil
+}
+
+// checkType 校验 type 关键字，支持字符串或字符串数组
+func checkType(t interface{}, value interface{}, path string) error {
+	var types []string
+	switch tv := t.(type) {
+	case string:
+		types = []string{tv}
+	case []interface{}:
+		for _, item := range tv {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+	default:
+		return nil
+	}
+
+	for _, expected := range types {
+		if matchesType(expected, value) {
+			return nil
+		}
+	}
+	return &ValidationError{
+		Path:    path,
+		Message: fmt.Sprintf("expected %s, got %s", strings.Join(types, " or "), typeName(value)),
+	}
+}
+
+func matchesType(expected string, value interface{}) bool {
+	switch expected {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return true
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// equal 通过 JSON 序列化比较两个值
+func equal(a, b interface{}) bool {
+	aj, err1 := json.Marshal(a)
+	bj, err2 := json.Marshal(b)
+	return err1 == nil && err2 == nil && string(aj) == string(bj)
+}
+
+func describe(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	if len(data) > 64 {
+		return string(data[:61]) + "..."
+	}
+	return string(data)
+}
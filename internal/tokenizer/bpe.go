@@ -0,0 +1,234 @@
+// Package tokenizer 本地 BPE 分词器，按 tiktoken 的词表和预分词规则计算 OpenAI 模型的 token 数
+package tokenizer
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// whitespace 与 tiktoken 的 \s 一致的 Unicode 空白字符（RE2 的 \s 只包含 ASCII 空白）
+const whitespace = `\t\n\v\f\r\x{85}\p{Z}`
+
+// patterns 各编码的预分词正则
+// tiktoken 原始正则中的 \s+(?!\S) 在 RE2 中无法表达，这里只保留 \s+，由 split 补上前瞻的效果
+var patterns = map[string]string{
+	"cl100k_base": `(?i:'s|'t|'re|'ve|'m|'ll|'d)` +
+		`|[^\r\n\p{L}\p{N}]?\p{L}+` +
+		`|\p{N}{1,3}` +
+		`| ?[^` + whitespace + `\p{L}\p{N}]+[\r\n]*` +
+		`|[` + whitespace + `]*[\r\n]+` +
+		`|[` + whitespace + `]+`,
+	"o200k_base": `[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?` +
+		`|[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?` +
+		`|\p{N}{1,3}` +
+		`| ?[^` + whitespace + `\p{L}\p{N}]+[\r\n/]*` +
+		`|[` + whitespace + `]*[\r\n]+` +
+		`|[` + whitespace + `]+`,
+}
+
+// Encoding 一个 BPE 编码（如 o200k_base），词表为 token 字节串 -> rank
+type Encoding struct {
+	name    string
+	ranks   map[string]int
+	pattern *regexp.Regexp
+}
+
+// Load 读取 .tiktoken 格式的词表（每行为 base64 编码的 token 和 rank）
+func Load(name string, r io.Reader) (*Encoding, error) {
+	pattern, ok := patterns[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown encoding %q", name)
+	}
+
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		token, rank, found := strings.Cut(text, " ")
+		if !found {
+			return nil, fmt.Errorf("%s: invalid vocab line %d", name, line)
+		}
+		data, err := base64.StdEncoding.DecodeString(token)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid token on line %d: %w", name, line, err)
+		}
+		n, err := strconv.Atoi(rank)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid rank on line %d: %w", name, line, err)
+		}
+		ranks[string(data)] = n
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: failed to read vocab: %w", name, err)
+	}
+
+	// 所有单字节都必须在词表中，否则合并结果无法覆盖任意输入
+	for b := 0; b < 256; b++ {
+		if _, ok := ranks[string([]byte{byte(b)})]; !ok {
+			return nil, fmt.Errorf("%s: vocab is missing byte %#x", name, b)
+		}
+	}
+
+	return &Encoding{
+		name:    name,
+		ranks:   ranks,
+		pattern: regexp.MustCompile(pattern),
+	}, nil
+}
+
+// Name 返回编码名称
+func (e *Encoding) Name() string {
+	return e.name
+}
+
+// Count 返回文本的 token 数（特殊 token 按普通文本计算）
+func (e *Encoding) Count(text string) int {
+	count := 0
+	for _, piece := range e.split(text) {
+		count += e.countPiece([]byte(piece))
+	}
+	return count
+}
+
+// split 按预分词正则切分文本
+// 模拟 \s+(?!\S)：后面紧跟非空白字符的多个空白（不含换行）留下最后一个，与后面的单词合并
+func (e *Encoding) split(text string) []string {
+	var pieces []string
+	for len(text) > 0 {
+		loc := e.pattern.FindStringIndex(text)
+		if loc == nil {
+			pieces = append(pieces, text)
+			break
+		}
+		if loc[0] > 0 {
+			pieces = append(pieces, text[:loc[0]])
+		}
+		end := loc[1]
+		if match := text[loc[0]:end]; end < len(text) && isSpaces(match) && utf8.RuneCountInString(match) > 1 {
+			_, size := utf8.DecodeLastRuneInString(match)
+			end -= size
+		}
+		pieces = append(pieces, text[loc[0]:end])
+		text = text[end:]
+	}
+	return pieces
+}
+
+// isSpaces 是否为不含换行的空白
+func isSpaces(s string) bool {
+	for _, r := range s {
+		if r == '\r' || r == '\n' || !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// countPiece 对一个片段做字节级 BPE 合并，返回 token 数
+// 每次合并 rank 最小（相同时最靠左）的相邻 token，直到没有可合并的组合，结果与 tiktoken 相同
+// 候选合并放在最小堆中，超长片段（如压缩后的代码）也不会退化为平方复杂度
+func (e *Encoding) countPiece(piece []byte) int {
+	if len(piece) == 0 {
+		return 0
+	}
+	if _, ok := e.ranks[string(piece)]; ok {
+		return 1
+	}
+
+	// parts[i] 为从第 i 个字节开始的 token，初始每个字节一个 token
+	parts := make([]part, len(piece))
+	for i := range parts {
+		parts[i] = part{end: i + 1, prev: i - 1}
+	}
+	merges := &mergeHeap{}
+	for i := range parts {
+		e.pushMerge(merges, piece, parts, i)
+	}
+
+	count := len(piece)
+	for merges.Len() > 0 {
+		m := heap.Pop(merges).(merge)
+		p := &parts[m.start]
+		if p.removed || p.version != m.version {
+			continue // 相邻 token 已变化，候选失效
+		}
+
+		// 与后一个 token 合并，之后当前 token 和前一个 token 的候选都需要重新计算
+		next := p.end
+		p.end = parts[next].end
+		parts[next].removed = true
+		if p.end < len(piece) {
+			parts[p.end].prev = m.start
+		}
+		count--
+
+		p.version++
+		e.pushMerge(merges, piece, parts, m.start)
+		if p.prev >= 0 {
+			parts[p.prev].version++
+			e.pushMerge(merges, piece, parts, p.prev)
+		}
+	}
+	return count
+}
+
+// pushMerge 如果 start 处的 token 能与后一个 token 合并，加入候选
+func (e *Encoding) pushMerge(merges *mergeHeap, piece []byte, parts []part, start int) {
+	p := &parts[start]
+	if p.end >= len(piece) {
+		return
+	}
+	if rank, ok := e.ranks[string(piece[start:parts[p.end].end])]; ok {
+		heap.Push(merges, merge{rank: rank, start: start, version: p.version})
+	}
+}
+
+// part 合并过程中的一个 token
+type part struct {
+	end     int  // 结束位置（不含）
+	prev    int  // 前一个 token 的起始位置，-1 表示没有
+	version int  // 每次相邻关系变化时递增，用于识别失效的候选
+	removed bool // 已并入前一个 token
+}
+
+// merge 候选合并：start 处的 token 与后一个 token
+type merge struct {
+	rank    int
+	start   int
+	version int
+}
+
+// mergeHeap 按 rank、位置排序的候选合并
+type mergeHeap []merge
+
+func (h mergeHeap) Len() int { return len(h) }
+func (h mergeHeap) Less(i, j int) bool {
+	if h[i].rank != h[j].rank {
+		return h[i].rank < h[j].rank
+	}
+	return h[i].start < h[j].start
+}
+func (h mergeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x any)   { *h = append(*h, x.(merge)) }
+func (h *mergeHeap) Pop() any {
+	old := *h
+	m := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return m
+}
+
+// Estimate 没有编码可用时按约 4 字节一个 token 估算
+func Estimate(text string) int {
+	return (len(text) + 3) / 4
+}
@@ -0,0 +1,225 @@
+package tokenizer
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"openbridge/internal/models"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Chat Completions 的格式开销（参考 OpenAI cookbook 的计算方式）
+const (
+	tokensPerMessage = 3 // 每条消息的角色和分隔符
+	tokensPerName    = 1 // 消息带 name 时额外的 token
+	tokensPerReply   = 3 // 每个回复以 <|start|>assistant<|message|> 开头
+	tokensPerTool    = 8 // 每个工具定义的格式开销（近似值）
+
+	lowDetailImageTokens  = 85  // detail: low 的图片
+	highDetailImageTokens = 765 // 其他图片按 1024x1024 的 high detail 计算（不下载图片，无法得知实际尺寸）
+)
+
+// encodingPrefixes 模型名前缀 -> 编码，按顺序匹配（gpt-4o 需要在 gpt-4 之前）
+var encodingPrefixes = []struct {
+	prefix   string
+	encoding string
+}{
+	{"gpt-4o", "o200k_base"},
+	{"gpt-4.1", "o200k_base"},
+	{"gpt-4.5", "o200k_base"},
+	{"gpt-5", "o200k_base"},
+	{"chatgpt-4o", "o200k_base"},
+	{"o1", "o200k_base"},
+	{"o3", "o200k_base"},
+	{"o4", "o200k_base"},
+	{"gpt-4", "cl100k_base"},
+	{"gpt-3.5", "cl100k_base"},
+	{"gpt-35", "cl100k_base"},
+	{"text-embedding-3", "cl100k_base"},
+	{"text-embedding-ada-002", "cl100k_base"},
+}
+
+// defaultEncoding 未知模型（如其他 OpenAI 兼容上游的模型）使用的编码，结果视为估算
+const defaultEncoding = "o200k_base"
+
+// EncodingForModel 返回模型使用的编码名称，known 表示是已知的 OpenAI 模型
+func EncodingForModel(model string) (name string, known bool) {
+	for _, p := range encodingPrefixes {
+		if strings.HasPrefix(model, p.prefix) {
+			return p.encoding, true
+		}
+	}
+	return defaultEncoding, false
+}
+
+// bundledVocab 内置的 tiktoken 词表（来自 openaipublic.blob.core.windows.net/encodings，sha256 与 tiktoken 校验值一致）
+//
+//go:embed vocab/*.tiktoken
+var bundledVocab embed.FS
+
+// Options 本地分词器配置
+type Options struct {
+	VocabDir string // 覆盖内置词表的目录，其中的 {encoding}.tiktoken（如 o200k_base.tiktoken）优先于内置词表
+}
+
+// Tokenizer 按模型选择编码计算 token 数
+type Tokenizer struct {
+	encodings map[string]*Encoding
+}
+
+// New 加载所有已知编码，VocabDir 中存在的词表替换内置词表
+func New(opts Options) (*Tokenizer, error) {
+	t := &Tokenizer{encodings: make(map[string]*Encoding)}
+	for name := range patterns {
+		f, err := openVocab(opts.VocabDir, name)
+		if err != nil {
+			return nil, err
+		}
+		enc, err := Load(name, f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		t.encodings[name] = enc
+	}
+	return t, nil
+}
+
+// openVocab 打开词表，优先使用 dir 中的文件
+func openVocab(dir, name string) (io.ReadCloser, error) {
+	filename := name + ".tiktoken"
+	if dir != "" {
+		f, err := os.Open(filepath.Join(dir, filename))
+		if err == nil {
+			return f, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to open vocab: %w", err)
+		}
+	}
+	return bundledVocab.Open("vocab/" + filename)
+}
+
+// Encodings 返回已加载的编码名称
+func (t *Tokenizer) Encodings() []string {
+	names := make([]string, 0, len(t.encodings))
+	for name := range t.encodings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Count 计算文本的 token 数
+// estimated 表示结果不精确：未知模型借用默认编码计算，编码不可用时按长度估算
+func (t *Tokenizer) Count(model, text string) (count int, estimated bool) {
+	name, known := EncodingForModel(model)
+	enc, ok := t.encodings[name]
+	if !ok {
+		return Estimate(text), true
+	}
+	return enc.Count(text), !known
+}
+
+// CountChat 计算 Chat Completions 请求的输入 token 数
+// 消息按 OpenAI 的格式开销计算；图片按 detail 取固定值，工具定义按 JSON 文本计算，均为近似值
+func (t *Tokenizer) CountChat(req *models.ChatCompletionRequest) (count int, estimated bool) {
+	add := func(text string) {
+		n, est := t.Count(req.Model, text)
+		count += n
+		estimated = estimated || est
+	}
+
+	for _, msg := range req.Messages {
+		count += tokensPerMessage
+		add(msg.Role)
+		if msg.Name != "" {
+			add(msg.Name)
+			count += tokensPerName
+		}
+
+		switch content := msg.Content.(type) {
+		case string:
+			add(content)
+		case []interface{}:
+			for _, item := range content {
+				part, ok := item.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				switch part["type"] {
+				case "text":
+					text, _ := part["text"].(string)
+					add(text)
+				case "image_url":
+					image, _ := part["image_url"].(map[string]interface{})
+					if detail, _ := image["detail"].(string); detail == "low" {
+						count += lowDetailImageTokens
+					} else {
+						count += highDetailImageTokens
+					}
+				}
+			}
+		}
+
+		for _, call := range msg.ToolCalls {
+			add(call.Function.Name)
+			add(call.Function.Arguments)
+		}
+	}
+	count += tokensPerReply
+
+	for _, tool := range req.Tools {
+		data, err := json.Marshal(tool.Function)
+		if err != nil {
+			continue
+		}
+		add(string(data))
+		count += tokensPerTool
+	}
+
+	return count, estimated
+}
+
+var (
+	defaultMu        sync.RWMutex
+	defaultTokenizer *Tokenizer
+
+	bundledOnce      sync.Once
+	bundledTokenizer *Tokenizer
+)
+
+// SetDefault 替换全局默认 Tokenizer（启动时根据配置调用）
+func SetDefault(t *Tokenizer) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultTokenizer = t
+}
+
+// Default 返回全局默认 Tokenizer，未调用 SetDefault 时在第一次使用时加载内置词表
+func Default() *Tokenizer {
+	defaultMu.RLock()
+	t := defaultTokenizer
+	defaultMu.RUnlock()
+	if t != nil {
+		return t
+	}
+
+	bundledOnce.Do(func() {
+		var err error
+		bundledTokenizer, err = New(Options{})
+		if err != nil {
+			log.Printf("❌ Failed to load bundled tokenizer vocab: %v", err)
+			bundledTokenizer = &Tokenizer{encodings: make(map[string]*Encoding)}
+		}
+	})
+	return bundledTokenizer
+}
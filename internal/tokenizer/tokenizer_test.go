@@ -0,0 +1,65 @@
+package tokenizer
+
+import (
+	"strings"
+	"testing"
+
+	"openbridge/internal/models"
+)
+
+// 期望值来自 tiktoken 对同一文本的编码结果
+func TestCountMatchesTiktoken(t *testing.T) {
+	tok, err := New(Options{})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	tests := []struct {
+		model string
+		text  string
+		want  int
+	}{
+		{"gpt-4", "hello world", 2},
+		{"gpt-4", "tiktoken is great!", 6},
+		{"gpt-4", "hello   world\n\n  next", 6},
+		{"gpt-4", "It's 12345 DON'T", 7},
+		{"gpt-4", "中文测试，你好世界！", 10},
+		{"gpt-4", "func main() {\n\tfmt.Println(\"hi\")\n}\n", 10},
+		{"gpt-4", strings.Repeat("abcdefghij", 1000), 2000},
+		{"gpt-4o", "hello world", 2},
+		{"gpt-4o", "tiktoken is great!", 6},
+		{"gpt-4o", "hello   world\n\n  next", 6},
+		{"gpt-4o", "It's 12345 DON'T", 5},
+		{"gpt-4o", "中文测试，你好世界！", 6},
+		{"gpt-4o", "func main() {\n\tfmt.Println(\"hi\")\n}\n", 10},
+		{"gpt-4o", strings.Repeat("abcdefghij", 1000), 2000},
+	}
+	for _, tt := range tests {
+		got, estimated := tok.Count(tt.model, tt.text)
+		if got != tt.want || estimated {
+			t.Errorf("Count(%s, %.20q) = %d (estimated %v), want %d", tt.model, tt.text, got, estimated, tt.want)
+		}
+	}
+}
+
+func TestCountChat(t *testing.T) {
+	tok, err := New(Options{})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	req := &models.ChatCompletionRequest{
+		Model:    "gpt-4o",
+		Messages: []models.Message{{Role: "user", Content: "hello world"}},
+	}
+	// 3（消息开销）+ 1（user）+ 2（hello world）+ 3（回复开头）
+	if got, estimated := tok.CountChat(req); got != 9 || estimated {
+		t.Errorf("CountChat = %d (estimated %v), want 9", got, estimated)
+	}
+
+	// 未知模型借用 o200k_base 计算，标记为估算
+	req.Model = "deepseek-chat"
+	if got, estimated := tok.CountChat(req); got != 9 || !estimated {
+		t.Errorf("CountChat(deepseek-chat) = %d (estimated %v), want 9 estimated", got, estimated)
+	}
+}
@@ -4,13 +4,21 @@ import (
 	"log"
 	"openbridge/internal/admin"
 	"openbridge/internal/config"
+	"openbridge/internal/media"
 	"openbridge/internal/provider"
-	"openbridge/internal/provider/anthropic"
-	"openbridge/internal/provider/google"
-	"openbridge/internal/provider/openai"
 	"openbridge/internal/router"
 	"openbridge/internal/service"
+	"openbridge/internal/tokenizer"
 	"openbridge/internal/user"
+	"strings"
+
+	// 各 Provider 包在 init() 中注册自己的构造函数
+	_ "openbridge/internal/provider/anthropic"
+	_ "openbridge/internal/provider/cohere"
+	_ "openbridge/internal/provider/google"
+	_ "openbridge/internal/provider/mock"
+	_ "openbridge/internal/provider/openai"
+	_ "openbridge/internal/provider/plugin"
 )
 
 func main() {
@@ -28,27 +36,37 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// 远程图片等媒体的下载限制
+	media.SetDefault(media.New(media.Options{
+		MaxBytes:     cfg.Media.MaxBytes,
+		Timeout:      cfg.Media.Timeout,
+		AllowedHosts: cfg.Media.AllowedHosts,
+		AllowPrivate: cfg.Media.AllowPrivate,
+		CacheSize:    cfg.Media.CacheSize,
+		CacheTTL:     cfg.Media.CacheTTL,
+	}))
+
+	// 本地分词器（OpenAI 模型的 token 计数），vocab_dir 中的词表覆盖内置词表
+	tok, err := tokenizer.New(tokenizer.Options{VocabDir: cfg.Tokenizer.VocabDir})
+	if err != nil {
+		log.Fatalf("Failed to load tokenizer: %v", err)
+	}
+	tokenizer.SetDefault(tok)
+	log.Printf("🔤 Loaded tokenizer encodings: %s", strings.Join(tok.Encodings(), ", "))
+
 	// Initialize provider registry
 	registry := provider.NewRegistry()
 
 	// Initialize API key managers
 	keyManagers := service.NewProviderKeyManagers()
 
+	log.Printf("🧩 Supported provider types: %s", strings.Join(provider.SupportedTypes(), ", "))
+
 	// Register providers from config
 	for name, providerCfg := range cfg.Providers {
-		var p provider.Provider
-
-		switch providerCfg.Type {
-		case "openai":
-			p = openai.New(name, providerCfg.BaseURL)
-		case "anthropic", "claude":
-			p = anthropic.New(name, providerCfg.BaseURL)
-		case "google", "gemini":
-			p = google.New(name, providerCfg.BaseURL)
-		default:
-			// 默认使用 OpenAI 格式
-			log.Printf("⚠️  Unknown provider type '%s', using OpenAI format", providerCfg.Type)
-			p = openai.New(name, providerCfg.BaseURL)
+		p, err := provider.NewFromConfig(name, providerCfg)
+		if err != nil {
+			log.Fatalf("Failed to register provider: %v", err)
 		}
 
 		registry.Register(name, p)